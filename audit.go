@@ -0,0 +1,279 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/gnodux/sqlxx/meta"
+	"reflect"
+	"time"
+)
+
+const (
+	// DefaultAuditTable 默认的审计表名称
+	DefaultAuditTable = "sqlxx_audit"
+	// AuditUpdate 审计操作：更新
+	AuditUpdate = "update"
+	// AuditDelete 审计操作：删除(逻辑删除)
+	AuditDelete = "delete"
+	// AuditErase 审计操作：擦除(物理删除)
+	AuditErase = "erase"
+)
+
+// AuditConfig 变更审计配置
+//
+// 审计表需要包含 AuditLog 中的列，例如(MySQL):
+//
+//	CREATE TABLE sqlxx_audit (
+//	    id           BIGINT PRIMARY KEY AUTO_INCREMENT,
+//	    entity_table VARCHAR(128) NOT NULL,
+//	    operation    VARCHAR(16)  NOT NULL,
+//	    entity_id    VARCHAR(64)  NOT NULL,
+//	    tenant_id    VARCHAR(64),
+//	    actor        VARCHAR(128),
+//	    diff         TEXT         NOT NULL,
+//	    created_at   DATETIME     NOT NULL
+//	);
+type AuditConfig struct {
+	//Table 审计表名称,为空时使用 DefaultAuditTable
+	Table string
+	//Actor 获取操作人,为空时使用 ActorFrom
+	Actor func(ctx context.Context) string
+}
+
+// WithAudit 设置变更审计配置，未设置时使用默认配置
+//
+// 只有开启了审计的实体(参考 meta.Auditable)才会记录审计日志
+func WithAudit(cfg AuditConfig) Option {
+	return func(d *DB) {
+		d.audit = &cfg
+	}
+}
+
+// AuditLog 审计记录
+type AuditLog struct {
+	ID          int64
+	EntityTable string
+	Operation   string
+	EntityId    string
+	TenantId    string
+	Actor       string
+	//Diff 列差异(JSON),格式为 {"列名":{"before":变更前的值,"after":变更后的值}}
+	Diff      string
+	CreatedAt time.Time
+}
+
+func (a *AuditLog) TableName() string {
+	return DefaultAuditTable
+}
+
+// AuditChange 列变更前后的值
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type actorKey struct{}
+
+// WithActor 在上下文中记录操作人,审计日志默认从上下文中获取操作人
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 获取上下文中的操作人
+func ActorFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+func (d *DB) auditTable() string {
+	if d.audit != nil && d.audit.Table != "" {
+		return d.audit.Table
+	}
+	return DefaultAuditTable
+}
+
+func (d *DB) auditActor(ctx context.Context) string {
+	if d.audit != nil && d.audit.Actor != nil {
+		return d.audit.Actor(ctx)
+	}
+	return ActorFrom(ctx)
+}
+
+// auditTrail 一次写操作的审计,在写操作前后查询记录并比较差异
+type auditTrail[T any] struct {
+	mapper *BaseMapper[T]
+	tx     *Tx
+	op     string
+	ids    []any
+	before []T
+}
+
+// audit 查询写操作前的记录(不使用租户条件，不执行钩子)
+//
+// 实体未开启审计或没有主键时返回nil
+func (b *BaseMapper[T]) audit(tx *Tx, op string, where expr.Expr) (*auditTrail[T], error) {
+	if !b.meta.Audit || b.meta.PrimaryKey == nil {
+		return nil, nil
+	}
+	a := &auditTrail[T]{mapper: b, tx: tx, op: op}
+	if err := tx.SelectExpr(&a.before, expr.Select(b.meta.ColumnExprs()...).From(b.meta).Where(where)); err != nil {
+		return nil, err
+	}
+	for _, row := range a.before {
+		a.ids = append(a.ids, columnValue(reflect.ValueOf(row), b.meta.PrimaryKey))
+	}
+	return a, nil
+}
+
+// auditByIds 按照主键查询写操作前的记录
+func (b *BaseMapper[T]) auditByIds(tx *Tx, op string, ids []any) (*auditTrail[T], error) {
+	if !b.meta.Audit || b.meta.PrimaryKey == nil || len(ids) == 0 {
+		return nil, nil
+	}
+	return b.audit(tx, op, expr.In(b.meta.PrimaryKey, "audit_id", ids...))
+}
+
+// auditEntities 按照实体的主键查询写操作前的记录
+func (b *BaseMapper[T]) auditEntities(tx *Tx, op string, entities []T) (*auditTrail[T], error) {
+	if !b.meta.Audit || b.meta.PrimaryKey == nil {
+		return nil, nil
+	}
+	var ids []any
+	for _, entity := range entities {
+		ids = append(ids, columnValue(reflect.ValueOf(entity), b.meta.PrimaryKey))
+	}
+	return b.auditByIds(tx, op, ids)
+}
+
+// write 查询写操作后的记录，将存在差异的记录写入审计表(与写操作在同一事务中)
+func (a *auditTrail[T]) write() error {
+	if a == nil || len(a.ids) == 0 {
+		return nil
+	}
+	m := a.mapper.meta
+	var after []T
+	if err := a.tx.SelectExpr(&after, expr.Select(m.ColumnExprs()...).From(m).Where(expr.In(m.PrimaryKey, "audit_id", a.ids...))); err != nil {
+		return err
+	}
+	afterRows := map[string]reflect.Value{}
+	for _, row := range after {
+		rv := reflect.ValueOf(row)
+		afterRows[fmt.Sprint(columnValue(rv, m.PrimaryKey))] = rv
+	}
+	ctx := a.tx.Context()
+	actor := a.mapper.auditActor(ctx)
+	table := expr.Name(a.mapper.auditTable())
+	now := time.Now()
+	for idx, row := range a.before {
+		rv := reflect.ValueOf(row)
+		id := fmt.Sprint(a.ids[idx])
+		diff, err := auditDiff(m, rv, afterRows[id])
+		if err != nil {
+			return err
+		}
+		if diff == nil {
+			continue
+		}
+		var tenantId string
+		if m.TenantKey != nil {
+			tenantId = fmt.Sprint(columnValue(rv, m.TenantKey))
+		}
+		if _, err = a.tx.ExecExpr(expr.InsertInto(table).
+			SetExpr(expr.Name("entity_table"), expr.Var("entity_table", m.TableName)).
+			SetExpr(expr.Name("operation"), expr.Var("operation", a.op)).
+			SetExpr(expr.Name("entity_id"), expr.Var("entity_id", id)).
+			SetExpr(expr.Name("tenant_id"), expr.Var("tenant_id", tenantId)).
+			SetExpr(expr.Name("actor"), expr.Var("actor", actor)).
+			SetExpr(expr.Name("diff"), expr.Var("diff", string(diff))).
+			SetExpr(expr.Name("created_at"), expr.Var("created_at", now))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditDiff 比较记录前后的列差异(JSON),after无效时表示记录已被删除,没有差异时返回nil
+//
+// 敏感列(sensitive)的值会被脱敏
+func auditDiff(m *meta.Entity, before, after reflect.Value) ([]byte, error) {
+	changes := map[string]AuditChange{}
+	for _, col := range m.Columns {
+		if col.Ignore {
+			continue
+		}
+		change := AuditChange{Before: auditValue(before, col)}
+		if after.IsValid() {
+			change.After = auditValue(after, col)
+		}
+		b, err := json.Marshal(change.Before)
+		if err != nil {
+			return nil, err
+		}
+		a, err := json.Marshal(change.After)
+		if err != nil {
+			return nil, err
+		}
+		if after.IsValid() && bytes.Equal(a, b) {
+			continue
+		}
+		if col.Sensitive {
+			change = AuditChange{Before: redactValue(change.Before), After: redactValue(change.After)}
+		}
+		changes[col.ColumnName] = change
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changes)
+}
+
+// auditValue 获取列的值(空指针为nil,driver.Valuer使用Value()的结果)
+func auditValue(row reflect.Value, col *meta.Column) any {
+	v := columnValue(row, col)
+	if valuer, ok := v.(driver.Valuer); ok {
+		if val, err := valuer.Value(); err == nil {
+			return val
+		}
+	}
+	return v
+}
+
+func redactValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	return Redacted
+}
+
+// columnValue 获取实体中列对应的字段值,空指针返回nil
+func columnValue(ev reflect.Value, col *meta.Column) any {
+	for ev.Kind() == reflect.Pointer || ev.Kind() == reflect.Interface {
+		if ev.IsNil() {
+			return nil
+		}
+		ev = ev.Elem()
+	}
+	f := col.FieldValue(ev, false)
+	for f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			return nil
+		}
+		f = f.Elem()
+	}
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	return f.Interface()
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// auditedUser 开启变更审计的用户
+type auditedUser struct {
+	ID        int64
+	TenantID  int64
+	Name      string
+	Password  string `dbx:"sensitive"`
+	Birthday  time.Time
+	Address   string
+	Role      string
+	IsDeleted bool
+}
+
+func (u *auditedUser) TableName() string {
+	return "user"
+}
+
+func (u *auditedUser) Audit() bool {
+	return true
+}
+
+func TestBaseMapper_Audit(t *testing.T) {
+	mapper, err := NewMapper[BaseMapper[*auditedUser]](DefaultName)
+	assert.NoError(t, err)
+	assert.True(t, mapper.Meta().Audit)
+	user := &auditedUser{TenantID: 1, Name: "audited", Password: "secret", Birthday: time.Now().Truncate(time.Second), Address: "somewhere", Role: "admin"}
+	assert.NoError(t, mapper.Create(user))
+	ctx := WithActor(context.Background(), "alice")
+
+	logs := func() (changes []map[string]AuditChange, entries []AuditLog) {
+		assert.NoError(t, mapper.DB.Select(&entries, "SELECT * FROM sqlxx_audit WHERE entity_table = ? AND entity_id = ? ORDER BY id", "user", strconv.FormatInt(user.ID, 10)))
+		for _, entry := range entries {
+			var change map[string]AuditChange
+			assert.NoError(t, json.Unmarshal([]byte(entry.Diff), &change))
+			changes = append(changes, change)
+		}
+		return
+	}
+
+	user.Name, user.Password = "audited_1", "changed"
+	assert.NoError(t, mapper.UpdateContext(ctx, false, user))
+	user.Address = "elsewhere"
+	assert.NoError(t, mapper.PartialUpdateContext(ctx, false, []string{"Address"}, user))
+	_, err = mapper.UpdateByExampleContext(ctx, &auditedUser{Role: "auditor"}, &auditedUser{Name: "audited_1"})
+	assert.NoError(t, err)
+	errFailed := errors.New("failed")
+	assert.ErrorIs(t, mapper.Batch(ctx, nil, func(tx *Tx) error {
+		user.Name = "rollback"
+		if err := mapper.UpdateContext(tx.Context(), false, user); err != nil {
+			return err
+		}
+		return errFailed
+	}), errFailed)
+	assert.NoError(t, mapper.DeleteByIdContext(ctx, user.TenantID, user.ID))
+	assert.NoError(t, mapper.EraseByIdContext(ctx, user.TenantID, user.ID))
+
+	changes, entries := logs()
+	if !assert.Len(t, entries, 5) {
+		return
+	}
+	for idx, op := range []string{AuditUpdate, AuditUpdate, AuditUpdate, AuditDelete, AuditErase} {
+		assert.Equal(t, op, entries[idx].Operation)
+		assert.Equal(t, "alice", entries[idx].Actor)
+		assert.Equal(t, "1", entries[idx].TenantId)
+	}
+	assert.Equal(t, map[string]AuditChange{
+		"name":     {Before: "audited", After: "audited_1"},
+		"password": {Before: Redacted, After: Redacted},
+	}, changes[0])
+	assert.Equal(t, map[string]AuditChange{"address": {Before: "somewhere", After: "elsewhere"}}, changes[1])
+	assert.Equal(t, map[string]AuditChange{"role": {Before: "admin", After: "auditor"}}, changes[2])
+	assert.Equal(t, map[string]AuditChange{"is_deleted": {Before: false, After: true}}, changes[3])
+	assert.Equal(t, AuditChange{Before: "audited_1"}, changes[4]["name"])
+	assert.Len(t, changes[4], len(mapper.Meta().Columns))
+}
@@ -6,6 +6,7 @@
 package sqlxx
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/cookieY/sqlx"
@@ -13,6 +14,7 @@ import (
 	. "github.com/gnodux/sqlxx/meta"
 	. "github.com/gnodux/sqlxx/utils"
 	"reflect"
+	"strings"
 	"sync"
 )
 
@@ -21,8 +23,14 @@ import (
 // 2. 表达式查询
 // 3. 模板查询
 // 4. 事务操作
+// 5. 实体钩子(参考 hooks.go),写操作的钩子在同一事务中执行,查询后执行 AfterFind
+// 6. 变更审计(参考 audit.go),开启审计的实体在更新、删除时将列差异写入审计表
+// 7. 类型安全的查询构造器(参考 Query)
+//
+// XxxContext 方法会复用上下文中的事务(参考 WithTx/Tx.Context)，写操作按照PropagationRequired加入该事务
 type BaseMapper[T any] struct {
 	*DB
+	listeners
 	once            sync.Once
 	meta            *Entity
 	CreateTx        TxFunc `sql:"builtin/create.sql" readonly:"false" tx:"Default"`
@@ -31,12 +39,20 @@ type BaseMapper[T any] struct {
 	PartialUpdateTx TxFunc `sql:"builtin/partial_update_by_id_tenant_id.sql" readonly:"false" tx:"Default"`
 	DeleteTx        TxFunc `sql:"builtin/delete_by_id.sql" readonly:"false" tx:"Default"`
 	EraseTx         TxFunc `sql:"builtin/erase_by_id.sql" readonly:"false" tx:"Default"`
+	//XxxTxContext 带上下文的 XxxTx,按照PropagationRequired复用上下文中的事务
+	CreateTxContext        TxContextFunc `sql:"builtin/create.sql" readonly:"false" tx:"Default"`
+	UpdateTxContext        TxContextFunc `sql:"builtin/update_by_id_tenant_id.sql" readonly:"false" tx:"Default"`
+	UpdateByIdTxContext    TxContextFunc `sql:"builtin/update_by_id.sql" readonly:"false" tx:"Default"`
+	PartialUpdateTxContext TxContextFunc `sql:"builtin/partial_update_by_id_tenant_id.sql" readonly:"false" tx:"Default"`
+	DeleteTxContext        TxContextFunc `sql:"builtin/delete_by_id.sql" readonly:"false" tx:"Default"`
+	EraseTxContext         TxContextFunc `sql:"builtin/erase_by_id.sql" readonly:"false" tx:"Default"`
 }
 
 func (b *BaseMapper[T]) init() {
 	b.once.Do(func() {
 		var t T
 		b.meta = NewEntity(t)
+		RegisterSensitive(b.meta.Type)
 	})
 }
 
@@ -54,16 +70,21 @@ func (b *BaseMapper[T]) Column(name string) *Column {
 
 // ListById 通过ID列表查询
 func (b *BaseMapper[T]) ListById(tenantId any, ids ...any) (entities []T, err error) {
+	return b.ListByIdContext(context.Background(), tenantId, ids...)
+}
+
+// ListByIdContext 带上下文的 ListById
+func (b *BaseMapper[T]) ListByIdContext(ctx context.Context, tenantId any, ids ...any) (entities []T, err error) {
 	b.init()
 	if len(ids) == 0 {
 		return nil, sql.ErrNoRows
 	}
+	const tpl = "builtin/list_by_id.sql"
 	var (
 		query   string
 		argList []any
-		stmt    *sqlx.Stmt
 	)
-	if query, err = b.ParseSQL("builtin/list_by_id.sql", b.meta); err != nil {
+	if query, err = b.ParseSQL(tpl, b.meta); err != nil {
 		return
 	}
 	if b.meta.TenantKey != nil {
@@ -74,31 +95,63 @@ func (b *BaseMapper[T]) ListById(tenantId any, ids ...any) (entities []T, err er
 	if err != nil {
 		return
 	}
-	if stmt, err = b.Preparex(query); err != nil {
+	if err = b.doSelect(ctx, nil, &entities, tpl, query, argList); err != nil {
 		return
 	}
-	defer func() {
-		if stErr := stmt.Close(); stErr != nil {
-			err = stErr
-		}
-	}()
-	err = stmt.Select(&entities, argList...)
+	err = runAfterHooks(ctx, b.activeTx(ctx, nil), HookFind, entities, b.hookChain()...)
 	return entities, err
 }
 
+// LockById 在事务中查询并锁定(FOR UPDATE)指定ID的记录,记录在事务结束前不能被其他事务修改
+//
+// 包含租户ID时使用租户ID作为查询条件,记录不存在时返回 sql.ErrNoRows
+func (b *BaseMapper[T]) LockById(tx *Tx, tenantId any, id any) (entity T, err error) {
+	b.init()
+	if tx == nil {
+		return entity, ErrNilTx
+	}
+	cond := []expr.Expr{expr.Eq(b.meta.PrimaryKey, expr.Var("id", id))}
+	if b.meta.TenantKey != nil {
+		cond = append(cond, expr.Eq(b.meta.TenantKey, expr.Var("tenant_id", tenantId)))
+	}
+	var entities []T
+	if err = tx.SelectExpr(&entities, expr.Select(b.meta.ColumnExprs()...).From(b.meta).Where(expr.And(cond...)).ForUpdate()); err != nil {
+		return
+	}
+	if len(entities) == 0 {
+		return entity, sql.ErrNoRows
+	}
+	if err = runAfterHooks(tx.Context(), tx, HookFind, entities, b.hookChain()...); err != nil {
+		return
+	}
+	return entities[0], nil
+}
+
 // Update 更新所有列.(如果包含租户ID,则会自动添加租户ID作为更新条件)
 //
 // useTenantId 是否使用租户ID作为更新条件
 //
 // 如果需要更新部分列,请使用PartialUpdate
 func (b *BaseMapper[T]) Update(useTenantId bool, entities ...T) error {
+	return b.UpdateContext(context.Background(), useTenantId, entities...)
+}
+
+// UpdateContext 带上下文的 Update
+func (b *BaseMapper[T]) UpdateContext(ctx context.Context, useTenantId bool, entities ...T) error {
 	b.init()
 	if len(entities) == 0 {
 		return sql.ErrNoRows
 	}
 
-	return b.UpdateTx(func(tx *Tx) (err error) {
-		return tx.RunCurrentPrepareNamed(map[string]any{
+	return b.UpdateTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookUpdate, entities, b.hookChain()...); err != nil {
+			return
+		}
+		var trail *auditTrail[T]
+		if trail, err = b.auditEntities(tx, AuditUpdate, entities); err != nil {
+			return
+		}
+		if err = tx.RunCurrentPrepareNamed(map[string]any{
 			"Meta":         b.meta,
 			"UserTenantId": useTenantId,
 		}, func(stmt *sqlx.NamedStmt) error {
@@ -108,7 +161,13 @@ func (b *BaseMapper[T]) Update(useTenantId bool, entities ...T) error {
 				}
 			}
 			return nil
-		})
+		}); err != nil {
+			return
+		}
+		if err = trail.write(); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookUpdate, entities, b.hookChain()...)
 	})
 }
 
@@ -120,10 +179,12 @@ func (b *BaseMapper[T]) Update(useTenantId bool, entities ...T) error {
 //
 // entities 实体列表
 func (b *BaseMapper[T]) PartialUpdate(useTenantId bool, specifiedField []string, entities ...T) error {
+	return b.PartialUpdateContext(context.Background(), useTenantId, specifiedField, entities...)
+}
+
+// PartialUpdateContext 带上下文的 PartialUpdate
+func (b *BaseMapper[T]) PartialUpdateContext(ctx context.Context, useTenantId bool, specifiedField []string, entities ...T) error {
 	b.init()
-	if hookErr := EvalBeforeHooks(entities...); hookErr != nil {
-		return hookErr
-	}
 	if len(entities) == 0 {
 		return sql.ErrNoRows
 	}
@@ -138,11 +199,19 @@ func (b *BaseMapper[T]) PartialUpdate(useTenantId bool, specifiedField []string,
 	if len(specifiedField) > 0 {
 		metaCols = Search(b.meta.Columns, func(col *Column) bool {
 			return Contains(specifiedField, func(s string) bool {
-				return col.Name == s
+				//指定展开的结构体字段时更新其所有列
+				return col.Name == s || strings.HasPrefix(col.Name, s+".")
 			})
 		})
 	}
-	err := b.PartialUpdateTx(func(tx *Tx) (err error) {
+	return b.PartialUpdateTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookUpdate, entities, b.hookChain()...); err != nil {
+			return
+		}
+		var trail *auditTrail[T]
+		if trail, err = b.auditEntities(tx, AuditUpdate, entities); err != nil {
+			return
+		}
 		for _, entity := range entities {
 			if specifiedField == nil {
 				data := ToMap(entity, excludes...)
@@ -162,31 +231,48 @@ func (b *BaseMapper[T]) PartialUpdate(useTenantId bool, specifiedField []string,
 				return err
 			}
 		}
-		return nil
+		if err = trail.write(); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookUpdate, entities, b.hookChain()...)
 	})
-
-	if hookErr := EvalAfterHooks(entities...); hookErr != nil {
-		return hookErr
-	}
-	return err
 }
 
 // AutoPartialUpdate 更新指定列.(不会自动添加租户ID作为更新条件)
 //
 // useTenantId 是否使用租户ID作为更新条件
 func (b *BaseMapper[T]) AutoPartialUpdate(useTenantId bool, entities ...T) error {
-	return b.PartialUpdate(useTenantId, nil, entities...)
+	return b.AutoPartialUpdateContext(context.Background(), useTenantId, entities...)
+}
+
+// AutoPartialUpdateContext 带上下文的 AutoPartialUpdate
+func (b *BaseMapper[T]) AutoPartialUpdateContext(ctx context.Context, useTenantId bool, entities ...T) error {
+	return b.PartialUpdateContext(ctx, useTenantId, nil, entities...)
 }
 
 // DeleteById 根据租户ID和ID删除记录
 //
 // 删除使用的SQL模版是builtin/delete_by_id.sql
 func (b *BaseMapper[T]) DeleteById(tenantId any, ids ...any) error {
+	return b.DeleteByIdContext(context.Background(), tenantId, ids...)
+}
+
+// DeleteByIdContext 带上下文的 DeleteById
+func (b *BaseMapper[T]) DeleteByIdContext(ctx context.Context, tenantId any, ids ...any) error {
 	if len(ids) == 0 {
 		return sql.ErrNoRows
 	}
-	return b.DeleteTx(func(tx *Tx) (err error) {
-		return tx.RunCurrentPrepareNamed(b.meta, func(stmt *sqlx.NamedStmt) error {
+	b.init()
+	entities := b.keyEntities(tenantId, ids)
+	return b.DeleteTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookDelete, entities, b.hookChain()...); err != nil {
+			return
+		}
+		var trail *auditTrail[T]
+		if trail, err = b.auditByIds(tx, AuditDelete, ids); err != nil {
+			return
+		}
+		if err = tx.RunCurrentPrepareNamed(b.meta, func(stmt *sqlx.NamedStmt) error {
 			for _, id := range ids {
 				if _, err = stmt.Exec(map[string]any{
 					"tenant_id": tenantId,
@@ -196,7 +282,13 @@ func (b *BaseMapper[T]) DeleteById(tenantId any, ids ...any) error {
 				}
 			}
 			return nil
-		})
+		}); err != nil {
+			return
+		}
+		if err = trail.write(); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookDelete, entities, b.hookChain()...)
 	})
 }
 
@@ -204,12 +296,25 @@ func (b *BaseMapper[T]) DeleteById(tenantId any, ids ...any) error {
 //
 // 擦除使用的SQL模版是builtin/erase_by_id.sql,该操作将完整删除记录
 func (b *BaseMapper[T]) EraseById(tenantId any, ids ...any) error {
+	return b.EraseByIdContext(context.Background(), tenantId, ids...)
+}
 
+// EraseByIdContext 带上下文的 EraseById
+func (b *BaseMapper[T]) EraseByIdContext(ctx context.Context, tenantId any, ids ...any) error {
 	if ids == nil {
 		return sql.ErrNoRows
 	}
-	return b.EraseTx(func(tx *Tx) (err error) {
-		return tx.RunCurrentPrepareNamed(b.meta, func(stmt *sqlx.NamedStmt) error {
+	b.init()
+	entities := b.keyEntities(tenantId, ids)
+	return b.EraseTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookDelete, entities, b.hookChain()...); err != nil {
+			return
+		}
+		var trail *auditTrail[T]
+		if trail, err = b.auditByIds(tx, AuditErase, ids); err != nil {
+			return
+		}
+		if err = tx.RunCurrentPrepareNamed(b.meta, func(stmt *sqlx.NamedStmt) error {
 			for _, id := range ids {
 				if _, err = stmt.Exec(map[string]any{
 					"tenant_id": tenantId,
@@ -219,17 +324,31 @@ func (b *BaseMapper[T]) EraseById(tenantId any, ids ...any) error {
 				}
 			}
 			return nil
-		})
+		}); err != nil {
+			return
+		}
+		if err = trail.write(); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookDelete, entities, b.hookChain()...)
 	})
 }
 
 func (b *BaseMapper[T]) Create(entities ...T) error {
+	return b.CreateContext(context.Background(), entities...)
+}
 
+// CreateContext 带上下文的 Create
+func (b *BaseMapper[T]) CreateContext(ctx context.Context, entities ...T) error {
+	b.init()
 	if len(entities) == 0 {
 		return sql.ErrNoRows
 	}
-	return b.CreateTx(func(tx *Tx) (err error) {
-		return tx.RunCurrentPrepareNamed(b.meta, func(stmt *sqlx.NamedStmt) error {
+	return b.CreateTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookInsert, entities, b.hookChain()...); err != nil {
+			return
+		}
+		if err = tx.RunCurrentPrepareNamed(b.meta, func(stmt *sqlx.NamedStmt) error {
 			var result sql.Result
 			for idx, _ := range entities {
 				if result, err = stmt.Exec(entities[idx]); err != nil {
@@ -239,36 +358,52 @@ func (b *BaseMapper[T]) Create(entities ...T) error {
 				}
 			}
 			return nil
-		})
+		}); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookInsert, entities, b.hookChain()...)
 	})
 }
 
 // Select 使用SelectExprBuilder构建查询
 // 默认限制100条,如果需要更多,请使用builder中的Limit方法
 func (b *BaseMapper[T]) Select(builders ...expr.FilterFn) (result []T, total int64, err error) {
+	return b.SelectContext(context.Background(), builders...)
+}
 
+// SelectContext 带上下文的 Select
+func (b *BaseMapper[T]) SelectContext(ctx context.Context, builders ...expr.FilterFn) (result []T, total int64, err error) {
+	b.init()
 	//默认Limit 100
 	queryExpr := expr.Select(b.meta.ColumnExprs()...).From(b.meta).Limit(100)
 	for _, fn := range builders {
 		fn(queryExpr)
 	}
-	err = b.SelectExpr(&result, queryExpr)
+	err = b.SelectExprContext(ctx, &result, queryExpr)
 	if err != nil {
 		return
 	}
+	if err = runAfterHooks(ctx, b.activeTx(ctx, nil), HookFind, result, b.hookChain()...); err != nil {
+		return
+	}
 	if queryExpr.UseCount() {
 		countExpr := queryExpr.BuildCountExpr()
-		err = b.GetExpr(&total, countExpr)
+		err = b.GetExprContext(ctx, &total, countExpr)
 	}
 	return
 }
 
 func (b *BaseMapper[T]) InsertExpr(builders ...expr.InsertFilterFn) error {
+	return b.InsertExprContext(context.Background(), builders...)
+}
+
+// InsertExprContext 带上下文的 InsertExpr
+func (b *BaseMapper[T]) InsertExprContext(ctx context.Context, builders ...expr.InsertFilterFn) error {
 	insertExpr := expr.InsertInto(b.meta)
 	for _, fn := range builders {
 		fn(insertExpr)
 	}
-	_, err := b.ExecExpr(insertExpr)
+	_, err := b.ExecExprContext(ctx, insertExpr)
 	return err
 }
 
@@ -276,7 +411,16 @@ func (b *BaseMapper[T]) InsertExpr(builders ...expr.InsertFilterFn) error {
 //
 // 和Create不一样的是，Insert会忽略空值，仅插入有值的字段
 func (b *BaseMapper[T]) Insert(entities ...T) error {
-	return b.CreateTx(func(tx *Tx) error {
+	return b.InsertContext(context.Background(), entities...)
+}
+
+// InsertContext 带上下文的 Insert
+func (b *BaseMapper[T]) InsertContext(ctx context.Context, entities ...T) error {
+	b.init()
+	return b.CreateTxContext(ctx, func(tx *Tx) error {
+		if err := runBeforeHooks(tx.Context(), tx, HookInsert, entities, b.hookChain()...); err != nil {
+			return err
+		}
 		for idx, _ := range entities {
 			insertExpr := expr.InsertInto(b.meta)
 			values := ToMap(entities[idx])
@@ -295,11 +439,16 @@ func (b *BaseMapper[T]) Insert(entities ...T) error {
 				}
 			}
 		}
-		return nil
+		return runAfterHooks(tx.Context(), tx, HookInsert, entities, b.hookChain()...)
 	})
 }
 
 func (b *BaseMapper[T]) CountBy(where map[string]any, fns ...expr.FilterFn) (total int64, err error) {
+	return b.CountByContext(context.Background(), where, fns...)
+}
+
+// CountByContext 带上下文的 CountBy
+func (b *BaseMapper[T]) CountByContext(ctx context.Context, where map[string]any, fns ...expr.FilterFn) (total int64, err error) {
 	queryExpr := expr.Select(expr.Count).From(b.meta)
 	var whereColumns []expr.Expr
 	for name, val := range where {
@@ -316,14 +465,24 @@ func (b *BaseMapper[T]) CountBy(where map[string]any, fns ...expr.FilterFn) (tot
 		fn(queryExpr)
 	}
 	queryExpr = queryExpr.BuildCountExpr()
-	err = b.GetExpr(&total, queryExpr)
+	err = b.GetExprContext(ctx, &total, queryExpr)
 	return
 }
 func (b *BaseMapper[T]) CountByExample(entity T, filters ...expr.FilterFn) (total int64, err error) {
-	return b.CountBy(ToMap(entity), filters...)
+	return b.CountByExampleContext(context.Background(), entity, filters...)
+}
+
+// CountByExampleContext 带上下文的 CountByExample
+func (b *BaseMapper[T]) CountByExampleContext(ctx context.Context, entity T, filters ...expr.FilterFn) (total int64, err error) {
+	return b.CountByContext(ctx, ToMap(entity), filters...)
 }
 
 func (b *BaseMapper[T]) SelectByExample(entity T, builders ...expr.FilterFn) ([]T, int64, error) {
+	return b.SelectByExampleContext(context.Background(), entity, builders...)
+}
+
+// SelectByExampleContext 带上下文的 SelectByExample
+func (b *BaseMapper[T]) SelectByExampleContext(ctx context.Context, entity T, builders ...expr.FilterFn) ([]T, int64, error) {
 	valMap := ToMap(entity)
 	var whereColumns []expr.Expr
 	for name, val := range valMap {
@@ -335,16 +494,21 @@ func (b *BaseMapper[T]) SelectByExample(entity T, builders ...expr.FilterFn) ([]
 	if len(whereColumns) > 0 {
 		builders = append([]expr.FilterFn{expr.UseCondition(expr.And(whereColumns...))}, builders...)
 	}
-	return b.Select(builders...)
+	return b.SelectContext(ctx, builders...)
 }
 
 func (b *BaseMapper[T]) UpdateBy(builders ...expr.FilterFn) (effect int64, err error) {
+	return b.UpdateByContext(context.Background(), builders...)
+}
+
+// UpdateByContext 带上下文的 UpdateBy
+func (b *BaseMapper[T]) UpdateByContext(ctx context.Context, builders ...expr.FilterFn) (effect int64, err error) {
 	updateExpr := expr.Update(b.meta)
 	for _, fn := range builders {
 		fn(updateExpr)
 	}
 	var result sql.Result
-	result, err = b.ExecExpr(updateExpr)
+	result, err = b.ExecExprContext(ctx, updateExpr)
 	if err != nil {
 		return 0, err
 	}
@@ -352,9 +516,39 @@ func (b *BaseMapper[T]) UpdateBy(builders ...expr.FilterFn) (effect int64, err e
 	return
 }
 func (b *BaseMapper[T]) UpdateByExample(newValue T, example T, builders ...expr.FilterFn) (effect int64, err error) {
-	if err = EvalBeforeHook(newValue); err != nil {
-		return 0, err
-	}
+	return b.UpdateByExampleContext(context.Background(), newValue, example, builders...)
+}
+
+// UpdateByExampleContext 带上下文的 UpdateByExample
+func (b *BaseMapper[T]) UpdateByExampleContext(ctx context.Context, newValue T, example T, builders ...expr.FilterFn) (effect int64, err error) {
+	b.init()
+	values := []T{newValue}
+	err = b.UpdateTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookUpdate, values, b.hookChain()...); err != nil {
+			return
+		}
+		updateExpr := b.updateByExampleExpr(values[0], example, builders...)
+		var trail *auditTrail[T]
+		if trail, err = b.audit(tx, AuditUpdate, updateExpr.WhereExpr); err != nil {
+			return
+		}
+		var result sql.Result
+		if result, err = tx.ExecExpr(updateExpr); err != nil {
+			return
+		}
+		if effect, err = result.RowsAffected(); err != nil {
+			return
+		}
+		if err = trail.write(); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookUpdate, values, b.hookChain()...)
+	})
+	return
+}
+
+// updateByExampleExpr 构建按照示例更新的表达式
+func (b *BaseMapper[T]) updateByExampleExpr(newValue T, example T, builders ...expr.FilterFn) *expr.UpdateExpr {
 	valMap := ToMap(example)
 	var whereColumns []expr.Expr
 	for name, val := range valMap {
@@ -375,13 +569,18 @@ func (b *BaseMapper[T]) UpdateByExample(newValue T, example T, builders ...expr.
 	if len(whereColumns) > 0 {
 		builders = append([]expr.FilterFn{expr.UseCondition(expr.And(whereColumns...))}, builders...)
 	}
-	effect, err = b.UpdateBy(builders...)
-	if hookErr := EvalAfterHook(newValue); hookErr != nil {
-		err = hookErr
+	updateExpr := expr.Update(b.meta)
+	for _, fn := range builders {
+		fn(updateExpr)
 	}
-	return
+	return updateExpr
 }
 func (b *BaseMapper[T]) DeleteBy(builders ...expr.DeleteExprFn) (rowAffected int64, err error) {
+	return b.DeleteByContext(context.Background(), builders...)
+}
+
+// DeleteByContext 带上下文的 DeleteBy
+func (b *BaseMapper[T]) DeleteByContext(ctx context.Context, builders ...expr.DeleteExprFn) (rowAffected int64, err error) {
 	if len(builders) == 0 {
 		return 0, errors.New("delete by must have one builder")
 	}
@@ -390,7 +589,7 @@ func (b *BaseMapper[T]) DeleteBy(builders ...expr.DeleteExprFn) (rowAffected int
 		fn(deleteExpr)
 	}
 	var result sql.Result
-	result, err = b.ExecExpr(deleteExpr)
+	result, err = b.ExecExprContext(ctx, deleteExpr)
 	if err != nil {
 		return 0, err
 	}
@@ -398,6 +597,25 @@ func (b *BaseMapper[T]) DeleteBy(builders ...expr.DeleteExprFn) (rowAffected int
 	return
 }
 func (b *BaseMapper[T]) DeleteByExample(example T, builders ...expr.DeleteExprFn) (effect int64, err error) {
+	return b.DeleteByExampleContext(context.Background(), example, builders...)
+}
+
+// DeleteByExampleContext 带上下文的 DeleteByExample
+func (b *BaseMapper[T]) DeleteByExampleContext(ctx context.Context, example T, builders ...expr.DeleteExprFn) (effect int64, err error) {
+	examples := []T{example}
+	err = b.DeleteTxContext(ctx, func(tx *Tx) (err error) {
+		if err = runBeforeHooks(tx.Context(), tx, HookDelete, examples, b.hookChain()...); err != nil {
+			return
+		}
+		if effect, err = b.deleteByExample(tx.Context(), examples[0], builders...); err != nil {
+			return
+		}
+		return runAfterHooks(tx.Context(), tx, HookDelete, examples, b.hookChain()...)
+	})
+	return
+}
+
+func (b *BaseMapper[T]) deleteByExample(ctx context.Context, example T, builders ...expr.DeleteExprFn) (effect int64, err error) {
 	valMap := ToMap(example)
 	var whereColumns []expr.Expr
 	for name, val := range valMap {
@@ -409,7 +627,7 @@ func (b *BaseMapper[T]) DeleteByExample(example T, builders ...expr.DeleteExprFn
 	if len(whereColumns) > 0 {
 		builders = append([]expr.DeleteExprFn{expr.UseDeleteCondition(expr.And(whereColumns...))}, builders...)
 	}
-	return b.DeleteBy(builders...)
+	return b.DeleteByContext(ctx, builders...)
 }
 func setPrimaryKey(entity any, meta *Entity, result sql.Result) error {
 	if meta.PrimaryKey == nil {
@@ -433,3 +651,44 @@ func setPrimaryKey(entity any, meta *Entity, result sql.Result) error {
 	}
 	return nil
 }
+
+// hookChain 监听器链(Factory、BaseMapper)
+func (b *BaseMapper[T]) hookChain() []*listeners {
+	return []*listeners{b.factoryListeners(), &b.listeners}
+}
+
+// keyEntities 创建只包含主键和租户ID的实体,用于执行删除钩子和监听器(都不存在时返回nil)
+func (b *BaseMapper[T]) keyEntities(tenantId any, ids []any) []T {
+	if !hasHooks[T](HookDelete) && !hasListeners(HookDelete, b.hookChain()...) {
+		return nil
+	}
+	entities := make([]T, len(ids))
+	for idx, id := range ids {
+		ev := reflect.ValueOf(&entities[idx]).Elem()
+		if ev.Kind() == reflect.Pointer {
+			ev.Set(reflect.New(ev.Type().Elem()))
+			ev = ev.Elem()
+		}
+		setColumnValue(ev, b.meta.PrimaryKey, id)
+		setColumnValue(ev, b.meta.TenantKey, tenantId)
+	}
+	return entities
+}
+
+// setColumnValue 设置列对应的字段值,类型相同或均为整数时设置,否则忽略
+func setColumnValue(ev reflect.Value, col *Column, val any) {
+	if col == nil || val == nil {
+		return
+	}
+	f := col.FieldValue(ev, true)
+	v := reflect.ValueOf(val)
+	if !f.CanSet() {
+		return
+	}
+	switch {
+	case v.Type().AssignableTo(f.Type()):
+		f.Set(v)
+	case (v.CanInt() || v.CanUint()) && (f.CanInt() || f.CanUint()):
+		f.Set(v.Convert(f.Type()))
+	}
+}
@@ -392,3 +392,42 @@ func TestBaseMapper_Insert(t *testing.T) {
 	}
 
 }
+
+func TestBaseMapper_LockById(t *testing.T) {
+	mapper, err := NewMapper[BaseMapper[*User]](DefaultName)
+	assert.NoError(t, err)
+	users, _, err := mapper.Select(expr.UseLimit(1))
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, users) {
+		return
+	}
+	_, err = mapper.LockById(nil, users[0].TenantID, users[0].ID)
+	assert.ErrorIs(t, err, ErrNilTx)
+	assert.NoError(t, mapper.Batch(context.Background(), nil, func(tx *Tx) error {
+		locked, err := mapper.LockById(tx, users[0].TenantID, users[0].ID)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, users[0].Name, locked.Name)
+		_, err = mapper.LockById(tx, users[0].TenantID+1, users[0].ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		return nil
+	}))
+}
+
+func TestBaseMapper_TxFunc(t *testing.T) {
+	mapper, err := NewMapper[BaseMapper[*User]](DefaultName)
+	assert.NoError(t, err)
+	var tpl string
+	//XxxTx 保持 TxFunc 的签名
+	assert.NoError(t, mapper.CreateTx(func(tx *Tx) error {
+		tpl = tx.tpl
+		return nil
+	}))
+	assert.Equal(t, "builtin/create.sql", tpl)
+	assert.NoError(t, mapper.CreateTxContext(context.Background(), func(tx *Tx) error {
+		tpl = tx.tpl
+		return nil
+	}))
+	assert.Equal(t, "builtin/create.sql", tpl)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// sqlxx-gen 根据数据库表结构(或建表语句文件)生成实体和mapper代码
+//
+//	sqlxx-gen -dsn "user:pass@tcp(localhost)/db?parseTime=true" -tables user,role -pkg model -out model/entity.go
+//	sqlxx-gen -ddl schema.sql -pkg model -out model/entity.go
+//
+// 内置MySQL驱动,其他数据库需要在引入对应驱动后自行编译
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/gen"
+	_ "github.com/go-sql-driver/mysql"
+	"os"
+	"strings"
+)
+
+func main() {
+	var (
+		driver     = flag.String("driver", "mysql", "database driver name(mysql/mssql/postgres/sqlite3)")
+		dsn        = flag.String("dsn", "", "data source name, introspect tables from database")
+		ddl        = flag.String("ddl", "", "DDL file, parse CREATE TABLE statements offline")
+		tables     = flag.String("tables", "", "comma separated table names, default all tables")
+		pkg        = flag.String("pkg", gen.DefaultPackage, "package name of generated code")
+		out        = flag.String("out", "", "output file, default stdout")
+		tenantKey  = flag.String("tenant-key", gen.DefaultTenantKey, "tenant key column")
+		softDelete = flag.String("soft-delete", gen.DefaultSoftDelete, "soft delete column")
+	)
+	flag.Parse()
+	if err := run(*driver, *dsn, *ddl, *tables, *out,
+		gen.WithPackage(*pkg), gen.WithTenantKey(*tenantKey), gen.WithSoftDelete(*softDelete)); err != nil {
+		fmt.Fprintln(os.Stderr, "sqlxx-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, ddl, names, out string, opts ...gen.Option) error {
+	var filter []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			filter = append(filter, name)
+		}
+	}
+	var (
+		tables []*gen.Table
+		err    error
+	)
+	switch {
+	case ddl != "":
+		content, err := os.ReadFile(ddl)
+		if err != nil {
+			return err
+		}
+		if tables, err = gen.ParseDDL(string(content)); err != nil {
+			return err
+		}
+		tables = selectTables(tables, filter)
+	case dsn != "":
+		f := sqlxx.NewFactory("sqlxx-gen")
+		defer f.Shutdown()
+		db, err := f.Open(sqlxx.DefaultName, driver, dsn)
+		if err != nil {
+			return err
+		}
+		if tables, err = gen.FromDB(context.Background(), db, filter...); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("-dsn or -ddl is required")
+	}
+	code, err := gen.New(opts...).Generate(tables...)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(out, code, 0644)
+}
+
+// selectTables 按表名过滤,没有指定表名时返回所有表
+func selectTables(tables []*gen.Table, names []string) []*gen.Table {
+	if len(names) == 0 {
+		return tables
+	}
+	var selected []*gen.Table
+	for _, table := range tables {
+		for _, name := range names {
+			if strings.EqualFold(table.Name, name) {
+				selected = append(selected, table)
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// sqlxx-mapper 为mapper结构体生成静态实现(New<Mapper>函数),在生成时检查模板、命名参数和查询列
+//
+//	//go:generate go run github.com/gnodux/sqlxx/cmd/sqlxx-mapper -type UserMapper -templates sql
+//
+// 模板目录中的.sql文件以相对路径命名,运行时需要使用相同的目录注册模板(参考 sqlxx.Factory.SetTemplateFS)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/gen"
+	"github.com/gnodux/sqlxx/utils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma separated mapper struct names, required")
+		dir       = flag.String("dir", ".", "package directory")
+		templates = flag.String("templates", "", "comma separated template directories, relative to package directory")
+		driver    = flag.String("dialect", sqlxx.DefaultDriver.Name, "dialect used to parse templates(mysql/mssql/postgres/sqlite3)")
+		out       = flag.String("out", "", "output file, default <type>_gen.go in package directory")
+	)
+	flag.Parse()
+	if err := run(*typeNames, *dir, *templates, *driver, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "sqlxx-mapper:", err)
+		os.Exit(1)
+	}
+}
+
+func run(typeNames, dir, templates, driver, out string) error {
+	if typeNames == "" {
+		return fmt.Errorf("-type is required")
+	}
+	d, ok := sqlxx.Drivers[driver]
+	if !ok {
+		return fmt.Errorf("unknown dialect %s", driver)
+	}
+	opts := []gen.MapperOption{gen.WithDialect(d)}
+	if templates != "" {
+		for _, tplDir := range strings.Split(templates, ",") {
+			opts = append(opts, gen.WithTemplateFS(os.DirFS(filepath.Join(dir, strings.TrimSpace(tplDir)))))
+		}
+	}
+	g, err := gen.NewMapperGenerator(dir, opts...)
+	if err != nil {
+		return err
+	}
+	names := strings.Split(typeNames, ",")
+	code, err := g.Generate(names...)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = filepath.Join(dir, utils.LowerCase(names[0])+"_gen.go")
+	}
+	return os.WriteFile(out, code, 0644)
+}
@@ -13,7 +13,9 @@ import (
 	"github.com/gnodux/sqlxx/builtin"
 	"github.com/gnodux/sqlxx/dialect"
 	"github.com/gnodux/sqlxx/expr"
+	"github.com/gnodux/sqlxx/meta"
 	"io/fs"
+	"sort"
 	"strings"
 	"sync"
 	"text/template"
@@ -22,6 +24,14 @@ import (
 var (
 	ErrNilDriver = errors.New("driver is nil")
 	ErrNilDB     = errors.New("DB is nil")
+	//ErrNilTx 需要在事务中执行的操作没有传入事务
+	ErrNilTx = errors.New("tx is nil")
+	//ErrNoEnqueuer 数据库没有注册发件箱(参考 outbox.New)
+	ErrNoEnqueuer = errors.New("enqueuer is not registered")
+	//ErrRollbackOnly 加入事务的函数返回了错误,外层函数虽然处理了该错误,事务(或保存点)仍然回滚
+	ErrRollbackOnly = errors.New("transaction is marked as rollback-only")
+	//ErrTxPanic 事务函数panic导致回滚(回滚回调收到的错误)
+	ErrTxPanic = errors.New("transaction panic")
 )
 
 // DB 数据库连接
@@ -29,10 +39,19 @@ var (
 // 1. 通过模板文件生成SQL语句并执行，每个连接实例都可以拥有自己的模版系统
 // 2. 通过expr包提供的表达式语法生成SQL语句并执行
 type DB struct {
-	m        *Factory
-	template *template.Template
-	lock     sync.Mutex
-	driver   *dialect.Driver
+	m            *Factory
+	name         string
+	template     *template.Template
+	lock         sync.Mutex
+	driver       *dialect.Driver
+	interceptors []Interceptor
+	retry        *RetryPolicy
+	audit        *AuditConfig
+	enqueuer     Enqueuer
+	lockMu       sync.Mutex
+	locks        map[string]*heldLock
+	lockTable    bool
+	entities     sync.Map
 	*sqlx.DB
 }
 
@@ -40,6 +59,42 @@ func (d *DB) SetManager(m *Factory) {
 	d.m = m
 }
 
+// Name 获取数据源名称(在Factory中注册的名称)
+func (d *DB) Name() string {
+	return d.name
+}
+
+// Logger 获取日志,使用所属Factory的日志
+func (d *DB) Logger() Logger {
+	if d.m == nil {
+		return log
+	}
+	return d.m.Logger()
+}
+
+// Driver 获取数据库方言驱动
+func (d *DB) Driver() *dialect.Driver {
+	return d.driver
+}
+
+// RegisterEntity 登记实体元数据(BoostMapper会自动登记BaseMapper的实体),用于结构检查等
+func (d *DB) RegisterEntity(entity *meta.Entity) {
+	d.entities.LoadOrStore(entity.Type, entity)
+}
+
+// Entities 获取已登记的实体元数据(按表名排序)
+func (d *DB) Entities() []*meta.Entity {
+	var entities []*meta.Entity
+	d.entities.Range(func(_, value any) bool {
+		entities = append(entities, value.(*meta.Entity))
+		return true
+	})
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].TableName < entities[j].TableName
+	})
+	return entities
+}
+
 func (d *DB) Preparexx(sqlOrTpl string, args any) (*sqlx.Stmt, error) {
 	if d == nil {
 		return nil, ErrNilDB
@@ -48,70 +103,49 @@ func (d *DB) Preparexx(sqlOrTpl string, args any) (*sqlx.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return d.Preparex(query)
+	return d.doPrepare(context.Background(), nil, sqlOrTpl, query, args)
 }
 
 func (d *DB) RunPrepared(sqlOrTpl string, arg any, fn func(*sqlx.Stmt) error) (err error) {
-	var stmt *sqlx.Stmt
-	if strings.HasSuffix(sqlOrTpl, ".sql") {
-		if stmt, err = d.Preparexx(sqlOrTpl, arg); err != nil {
-			return
-		}
-	} else {
-		if stmt, err = d.Preparex(sqlOrTpl); err != nil {
-			return
-		}
+	if d == nil {
+		return ErrNilDB
 	}
-	defer func() {
-		stErr := stmt.Close()
-		if stErr != nil {
-			err = stErr
-		}
-	}()
-	return fn(stmt)
+	var query string
+	if query, err = d.ParseSQL(sqlOrTpl, arg); err != nil {
+		return
+	}
+	return d.runPrepared(context.Background(), nil, sqlOrTpl, query, arg, fn)
 }
 
 func (d *DB) PrepareNamedxx(tplName string, args any) (*sqlx.NamedStmt, error) {
 	if d == nil {
 		return nil, ErrNilDB
 	}
-	var (
-		query string
-		err   error
-	)
-	if strings.HasSuffix(tplName, ".sql") {
-		query, err = d.ParseSQL(tplName, args)
-	} else {
-		query = tplName
-	}
+	query, err := d.ParseSQL(tplName, args)
 	if err != nil {
 		return nil, err
 	}
-	return d.PrepareNamed(query)
+	return d.doPrepareNamed(context.Background(), nil, tplName, query, args)
 }
 
 // RunPrepareNamed run prepared statement with named args
 // arg 如果是模版，是模版渲染参数，如果是动态SQL，则不需要(根据传入名称是否以.sql结尾判断)
 func (d *DB) RunPrepareNamed(sqlOrTpl string, arg any, fn func(*sqlx.NamedStmt) error) (err error) {
-	var stmt *sqlx.NamedStmt
-	if strings.HasSuffix(sqlOrTpl, ".sql") {
-		if stmt, err = d.PrepareNamedxx(sqlOrTpl, arg); err != nil {
-			return
-		}
-	} else {
-		if stmt, err = d.PrepareNamed(sqlOrTpl); err != nil {
-			return
-		}
+	if d == nil {
+		return ErrNilDB
 	}
-	defer func() {
-		stErr := stmt.Close()
-		if stErr != nil {
-			err = stErr
-		}
-	}()
-	return fn(stmt)
+	var query string
+	if query, err = d.ParseSQL(sqlOrTpl, arg); err != nil {
+		return
+	}
+	return d.runPrepareNamed(context.Background(), nil, sqlOrTpl, query, arg, fn)
 }
 func (d *DB) Selectxx(dest interface{}, sqlOrTpl string, args ...any) error {
+	return d.SelectxxContext(context.Background(), dest, sqlOrTpl, args...)
+}
+
+// SelectxxContext 使用上下文查询,上下文中存在事务时在事务中执行(参考 WithTx)
+func (d *DB) SelectxxContext(ctx context.Context, dest interface{}, sqlOrTpl string, args ...any) error {
 	if d == nil {
 		return ErrNilDB
 	}
@@ -119,45 +153,72 @@ func (d *DB) Selectxx(dest interface{}, sqlOrTpl string, args ...any) error {
 	if err != nil {
 		return err
 	}
-	log.Debug("select:", query, args)
-	return d.DB.Select(dest, query, args...)
+	return d.doSelect(ctx, nil, dest, sqlOrTpl, query, args)
 }
 func (d *DB) NamedSelectxx(dest interface{}, sqlOrTpl string, args interface{}) (err error) {
+	return d.NamedSelectxxContext(context.Background(), dest, sqlOrTpl, args)
+}
+
+// NamedSelectxxContext 使用上下文和命名参数查询
+func (d *DB) NamedSelectxxContext(ctx context.Context, dest interface{}, sqlOrTpl string, args interface{}) (err error) {
 	if d == nil {
 		return ErrNilDB
 	}
-	var named *sqlx.NamedStmt
-	named, err = d.PrepareNamedxx(sqlOrTpl, args)
+	query, err := d.ParseSQL(sqlOrTpl, args)
 	if err != nil {
 		return err
 	}
-	defer func(named *sqlx.NamedStmt) {
-		err = named.Close()
-
-	}(named)
 	if args == nil {
 		args = map[string]any{}
 	}
-	log.Debug("named select tpl:", named.QueryString, args)
-	return named.Select(dest, args)
+	return d.doNamedSelect(ctx, nil, dest, sqlOrTpl, query, args)
 }
-func (d *DB) NamedSelect(dest interface{}, sql string, arg any) (err error) {
+
+// Getxx 查询单条记录
+func (d *DB) Getxx(dest interface{}, sqlOrTpl string, args ...any) error {
+	return d.GetxxContext(context.Background(), dest, sqlOrTpl, args...)
+}
+
+// GetxxContext 使用上下文查询单条记录
+func (d *DB) GetxxContext(ctx context.Context, dest interface{}, sqlOrTpl string, args ...any) error {
 	if d == nil {
 		return ErrNilDB
 	}
-	var named *sqlx.NamedStmt
-	named, err = d.PrepareNamed(sql)
+	query, err := d.ParseSQL(sqlOrTpl, args)
 	if err != nil {
 		return err
 	}
-	defer func(named *sqlx.NamedStmt) {
-		err = named.Close()
+	return d.doGet(ctx, nil, dest, sqlOrTpl, query, args)
+}
 
-	}(named)
-	log.Debug("named select:", named.QueryString, arg)
-	return named.Select(dest, arg)
+// NamedGetxx 使用命名参数查询单条记录
+func (d *DB) NamedGetxx(dest interface{}, sqlOrTpl string, arg interface{}) error {
+	return d.NamedGetxxContext(context.Background(), dest, sqlOrTpl, arg)
+}
+
+// NamedGetxxContext 使用上下文和命名参数查询单条记录
+func (d *DB) NamedGetxxContext(ctx context.Context, dest interface{}, sqlOrTpl string, arg interface{}) error {
+	if d == nil {
+		return ErrNilDB
+	}
+	query, err := d.ParseSQL(sqlOrTpl, arg)
+	if err != nil {
+		return err
+	}
+	return d.doNamedGet(ctx, nil, dest, sqlOrTpl, query, arg)
+}
+func (d *DB) NamedSelect(dest interface{}, sql string, arg any) (err error) {
+	if d == nil {
+		return ErrNilDB
+	}
+	return d.doNamedSelect(context.Background(), nil, dest, "", sql, arg)
 }
 func (d *DB) NamedExecxx(sqlOrTpl string, arg interface{}) (sql.Result, error) {
+	return d.NamedExecxxContext(context.Background(), sqlOrTpl, arg)
+}
+
+// NamedExecxxContext 使用上下文和命名参数执行
+func (d *DB) NamedExecxxContext(ctx context.Context, sqlOrTpl string, arg interface{}) (sql.Result, error) {
 	if d == nil {
 		return nil, ErrNilDB
 	}
@@ -165,11 +226,15 @@ func (d *DB) NamedExecxx(sqlOrTpl string, arg interface{}) (sql.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Debug("named exec:", query, arg)
-	return d.NamedExec(query, arg)
+	return d.doNamedExec(ctx, nil, sqlOrTpl, query, arg)
 }
 
 func (d *DB) Execxx(sqlOrTpl string, args ...interface{}) (sql.Result, error) {
+	return d.ExecxxContext(context.Background(), sqlOrTpl, args...)
+}
+
+// ExecxxContext 使用上下文执行
+func (d *DB) ExecxxContext(ctx context.Context, sqlOrTpl string, args ...interface{}) (sql.Result, error) {
 	if d == nil {
 		return nil, ErrNilDB
 	}
@@ -177,10 +242,14 @@ func (d *DB) Execxx(sqlOrTpl string, args ...interface{}) (sql.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Debug("exec:", query, args)
-	return d.Exec(query, args...)
+	return d.doExec(ctx, nil, sqlOrTpl, query, args)
 }
 func (d *DB) NamedQueryxx(sqlOrTpl string, arg interface{}) (*sqlx.Rows, error) {
+	return d.NamedQueryxxContext(context.Background(), sqlOrTpl, arg)
+}
+
+// NamedQueryxxContext 使用上下文和命名参数查询,返回游标
+func (d *DB) NamedQueryxxContext(ctx context.Context, sqlOrTpl string, arg interface{}) (*sqlx.Rows, error) {
 	if d == nil {
 		return nil, ErrNilDB
 	}
@@ -188,111 +257,63 @@ func (d *DB) NamedQueryxx(sqlOrTpl string, arg interface{}) (*sqlx.Rows, error)
 	if err != nil {
 		return nil, err
 	}
-	log.Debug("named query:", query, arg)
-	return d.NamedQuery(query, arg)
+	return d.doNamedQuery(ctx, nil, sqlOrTpl, query, arg)
 }
 func (d *DB) Batch(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) (err error) {
 	return d.Batchxx(ctx, opts, "", fn)
 }
 
+// Batchxx 在事务中执行fn,上下文中已存在事务时加入该事务(PropagationRequired,参考 BatchWith)
 func (d *DB) Batchxx(ctx context.Context, opts *sql.TxOptions, tpl string, fn func(tx *Tx) error) (err error) {
-	if d == nil {
-		return ErrNilDB
-	}
-	var tx *sqlx.Tx
-	tx, err = d.BeginTxx(ctx, opts)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if tx != nil {
-			if err != nil {
-				_ = tx.Rollback()
-			} else {
-				err = tx.Commit()
-			}
-		}
-	}()
-	if err = fn(NewTxWith(tx, d, tpl)); err != nil {
-		return
-	}
-	return
+	return d.BatchWith(ctx, TxDefinition{Options: opts}, tpl, fn)
 }
 
 // SelectExpr 使用表达式进行查询
 func (d *DB) SelectExpr(dest interface{}, exp expr.Expr) error {
+	return d.SelectExprContext(context.Background(), dest, exp)
+}
+
+// SelectExprContext 使用上下文和表达式进行查询
+func (d *DB) SelectExprContext(ctx context.Context, dest interface{}, exp expr.Expr) error {
 	if d == nil {
 		return ErrNilDB
 	}
-	buff := expr.NewTracedBuffer(d.driver)
-	if d.driver.SupportNamed {
-		query, namedArgs, err := buff.BuildNamed(exp)
-		if err != nil {
-			return err
-		}
-		return d.NamedSelect(dest, query, namedArgs)
-	} else {
-		query, args, err := buff.Build(exp)
-		if err != nil {
-			return err
-		}
-		return d.Select(dest, query, args...)
-	}
+	return d.selectExpr(ctx, nil, dest, exp)
 }
 
 // ExecExpr 使用表达式进行执行
 func (d *DB) ExecExpr(exp expr.Expr) (sql.Result, error) {
+	return d.ExecExprContext(context.Background(), exp)
+}
+
+// ExecExprContext 使用上下文和表达式进行执行
+func (d *DB) ExecExprContext(ctx context.Context, exp expr.Expr) (sql.Result, error) {
 	if d == nil {
 		return nil, ErrNilDB
 	}
-	buff := expr.NewTracedBuffer(d.driver)
-	if d.driver.SupportNamed {
-		query, namedArgs, err := buff.BuildNamed(exp)
-		if err != nil {
-			return nil, err
-		}
-		return d.NamedExec(query, namedArgs)
-	} else {
-		query, args, err := buff.Build(exp)
-		if err != nil {
-			return nil, err
-		}
-		return d.Exec(query, args...)
-	}
+	return d.execExpr(ctx, nil, exp)
 }
 
 func (d *DB) GetExpr(dest interface{}, exp expr.Expr, filters ...expr.FilterFn) error {
+	return d.GetExprContext(context.Background(), dest, exp, filters...)
+}
+
+// GetExprContext 使用上下文和表达式查询单条记录
+func (d *DB) GetExprContext(ctx context.Context, dest interface{}, exp expr.Expr, filters ...expr.FilterFn) error {
 	if d == nil {
 		return ErrNilDB
 	}
 	for _, filter := range filters {
 		filter(exp)
 	}
-	buff := expr.NewTracedBuffer(d.driver)
-	if d.driver.SupportNamed {
-		query, namedArgs, err := buff.BuildNamed(exp)
-		if err != nil {
-			return err
-		}
-		return d.NamedGet(dest, query, namedArgs)
-	} else {
-		query, args, err := buff.Build(exp)
-		if err != nil {
-			return err
-		}
-		return d.Get(dest, query, args...)
-	}
+	return d.getExpr(ctx, nil, dest, exp)
 }
 
 func (d *DB) NamedGet(dest interface{}, query string, arg interface{}) error {
-	stmt, err := d.PrepareNamed(query)
-	if err != nil {
-		return err
+	if d == nil {
+		return ErrNilDB
 	}
-	defer func() {
-		_ = stmt.Close()
-	}()
-	return stmt.Get(dest, arg)
+	return d.doNamedGet(context.Background(), nil, dest, "", query, arg)
 }
 
 // SetTemplate set template
@@ -308,7 +329,8 @@ func (d *DB) Template() *template.Template {
 // ParseTemplateFS parse template from filesystem。
 // 为了保留目录结构，没有直接使用template的ParseFS(template中的ParseFS方法不会保留路径名称)
 func (d *DB) ParseTemplateFS(f fs.FS, patterns ...string) error {
-	log.Info("parse template from filesystem: ", f, " with patterns:", patterns)
+	logger := d.Logger()
+	logger.Info("parse template from filesystem: ", f, " with patterns:", patterns)
 	for _, pattern := range patterns {
 		matches, err := fs.Glob(f, pattern)
 		if err != nil {
@@ -319,7 +341,7 @@ func (d *DB) ParseTemplateFS(f fs.FS, patterns ...string) error {
 			if err != nil {
 				return err
 			}
-			log.Info("parse sql:", mf)
+			logger.Info("parse sql:", mf)
 			if _, err = d.template.New(strings.ReplaceAll(mf, "\\", "/")).Parse(string(buf)); err != nil {
 				return err
 			}
@@ -349,18 +371,19 @@ func (d *DB) ParseSQL(sqlOrTpl string, args any) (query string, err error) {
 		query = sb.String()
 	}
 	//}
-	log.Trace("parse sql:", sqlOrTpl, "=>", query, " with args:", args)
+	d.Logger().Trace("parse sql:", sqlOrTpl, "=>", query, " with args:", args)
 	return
 }
 
 // Open database with default factory(StdFactory)
-func Open(driverName, datasource string) (*DB, error) {
+func Open(driverName, datasource string, opts ...Option) (*DB, error) {
 	d, _ := Drivers[driverName]
-	return OpenWith(StdFactory, d, datasource)
+	return OpenWith(StdFactory, d, datasource, opts...)
 }
 
 // OpenWith open database with factory
-func OpenWith(f *Factory, driver *dialect.Driver, datasource string) (*DB, error) {
+// opts: 连接池等可选配置,参考 WithMaxOpenConns/WithMaxIdleConns/WithConnMaxLifetime/WithConnMaxIdleTime
+func OpenWith(f *Factory, driver *dialect.Driver, datasource string, opts ...Option) (*DB, error) {
 	if driver == nil {
 		driver = f.driver
 	}
@@ -374,6 +397,9 @@ func OpenWith(f *Factory, driver *dialect.Driver, datasource string) (*DB, error
 	db.MapperFunc(NameFunc)
 
 	newDb := &DB{DB: db, m: f, driver: driver, template: template.New("sql").Funcs(MakeFuncMap(driver))}
+	for _, opt := range opts {
+		opt(newDb)
+	}
 	err = newDb.ParseTemplateFS(builtin.Builtin, "builtin/*.sql")
 	if err != nil {
 		return nil, err
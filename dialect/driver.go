@@ -5,6 +5,8 @@
 
 package dialect
 
+import "fmt"
+
 type Driver struct {
 	//驱动名称（mysql/mssql）等
 	Name string
@@ -22,6 +24,34 @@ type Driver struct {
 	DateFormat string
 	//Keywords 关键字映射
 	Keywords map[string]string
+	//Explain 查看执行计划的语句前缀(为空表示不支持)
+	Explain string
+	//Savepoint 创建保存点的语句(fmt格式,参数为保存点名称)
+	Savepoint string
+	//RollbackTo 回滚到保存点的语句
+	RollbackTo string
+	//ReleaseSavepoint 释放保存点的语句(为空表示不支持)
+	ReleaseSavepoint string
+	//Retryable 判断错误是否可以通过重试事务解决(死锁、锁等待超时、序列化失败等)
+	Retryable func(err error) bool
+	//ForUpdate 排他锁语句(为空表示不支持行锁)
+	ForUpdate string
+	//ForShare 共享锁语句
+	ForShare string
+	//SkipLocked 跳过已锁定记录的语句(为空表示不支持)
+	SkipLocked string
+	//NoWait 不等待锁的语句(为空表示不支持)
+	NoWait string
+	//LockHints 使用表提示加锁(SQL Server: FROM t WITH (UPDLOCK, ROWLOCK)),否则在语句末尾追加锁定子句
+	LockHints bool
+	//AdvisoryLock 获取咨询锁(会话级)的语句,返回1表示成功,为空表示不支持(使用锁表)
+	//
+	//命名参数 :name 锁名称, :timeout 超时秒数, :timeout_ms 超时毫秒数(不限时为-1)
+	AdvisoryLock string
+	//AdvisoryUnlock 释放咨询锁的语句,命名参数 :name 锁名称,返回1表示成功
+	AdvisoryUnlock string
+	//TransactionalDDL DDL语句是否可以在事务中执行并回滚(MySQL的DDL会隐式提交事务)
+	TransactionalDDL bool
 }
 
 func (d *Driver) Keyword(name string) string {
@@ -33,6 +63,30 @@ func (d *Driver) Keyword(name string) string {
 	}
 	return name
 }
+
+// IsRetryable 判断错误是否可以通过重试事务解决
+func (d *Driver) IsRetryable(err error) bool {
+	return err != nil && d.Retryable != nil && d.Retryable(err)
+}
+
+// SavepointSQL 创建保存点的SQL
+func (d *Driver) SavepointSQL(name string) string {
+	return fmt.Sprintf(d.Savepoint, name)
+}
+
+// RollbackToSQL 回滚到保存点的SQL
+func (d *Driver) RollbackToSQL(name string) string {
+	return fmt.Sprintf(d.RollbackTo, name)
+}
+
+// ReleaseSavepointSQL 释放保存点的SQL,不支持时返回空字符串
+func (d *Driver) ReleaseSavepointSQL(name string) string {
+	if d.ReleaseSavepoint == "" {
+		return ""
+	}
+	return fmt.Sprintf(d.ReleaseSavepoint, name)
+}
+
 func (d *Driver) KeywordWith(prefix string, kw string, suffix string) string {
 	return prefix + d.Keyword(kw) + suffix
 }
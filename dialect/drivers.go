@@ -8,18 +8,32 @@ package dialect
 import (
 	"fmt"
 	"github.com/gnodux/sqlxx/utils"
+	"strings"
 )
 
 var (
 	//MySQL MySQL驱动
 	MySQL = &Driver{
-		Name:         "mysql",
-		SupportNamed: true,
-		NamedPrefix:  ":",
-		DateFormat:   "'2006-01-02 15:04:05'",
-		SQLNameFunc:  MakeNameFunc("`", "`"),
-		NameFunc:     utils.LowerCase,
-		PlaceHolder:  "?",
+		Name:             "mysql",
+		SupportNamed:     true,
+		NamedPrefix:      ":",
+		DateFormat:       "'2006-01-02 15:04:05'",
+		SQLNameFunc:      MakeNameFunc("`", "`"),
+		NameFunc:         utils.LowerCase,
+		PlaceHolder:      "?",
+		Explain:          "EXPLAIN ",
+		Savepoint:        "SAVEPOINT %s",
+		RollbackTo:       "ROLLBACK TO SAVEPOINT %s",
+		ReleaseSavepoint: "RELEASE SAVEPOINT %s",
+		//1213:死锁 1205:锁等待超时
+		Retryable:  RetryableNumbers(1213, 1205),
+		ForUpdate:  "FOR UPDATE",
+		ForShare:   "FOR SHARE",
+		SkipLocked: "SKIP LOCKED",
+		NoWait:     "NOWAIT",
+		//GET_LOCK:1 成功,0 超时
+		AdvisoryLock:   "SELECT GET_LOCK(:name, :timeout)",
+		AdvisoryUnlock: "SELECT RELEASE_LOCK(:name)",
 	}
 
 	//SQLServer SQLServer驱动
@@ -31,6 +45,62 @@ var (
 		DateFormat:   "'2006-01-02 15:04:05'",
 		SQLNameFunc:  MakeNameFunc("[", "]"),
 		NameFunc:     utils.LowerCase,
+		Savepoint:    "SAVE TRANSACTION %s",
+		RollbackTo:   "ROLLBACK TRANSACTION %s",
+		//1205:死锁
+		Retryable:  RetryableNumbers(1205),
+		ForUpdate:  "UPDLOCK, ROWLOCK",
+		ForShare:   "HOLDLOCK, ROWLOCK",
+		SkipLocked: "READPAST",
+		NoWait:     "NOWAIT",
+		LockHints:  true,
+		//sp_getapplock:>=0 成功,<0 超时、死锁或错误
+		AdvisoryLock: "DECLARE @result INT; EXEC @result = sp_getapplock @Resource = :name, @LockMode = 'Exclusive', " +
+			"@LockOwner = 'Session', @LockTimeout = :timeout_ms; SELECT CASE WHEN @result >= 0 THEN 1 ELSE 0 END",
+		AdvisoryUnlock: "DECLARE @result INT; EXEC @result = sp_releaseapplock @Resource = :name, @LockOwner = 'Session'; " +
+			"SELECT CASE WHEN @result >= 0 THEN 1 ELSE 0 END",
+		TransactionalDDL: true,
+	}
+
+	//Postgres PostgreSQL驱动(lib/pq)
+	Postgres = &Driver{
+		Name:             "postgres",
+		SupportNamed:     true,
+		NamedPrefix:      ":",
+		PlaceHolder:      "?",
+		DateFormat:       "'2006-01-02 15:04:05'",
+		SQLNameFunc:      MakeNameFunc(`"`, `"`),
+		NameFunc:         utils.LowerCase,
+		Explain:          "EXPLAIN ",
+		Savepoint:        "SAVEPOINT %s",
+		RollbackTo:       "ROLLBACK TO SAVEPOINT %s",
+		ReleaseSavepoint: "RELEASE SAVEPOINT %s",
+		//40001:序列化失败 40P01:死锁
+		Retryable:  RetryableStates("40001", "40P01"),
+		ForUpdate:  "FOR UPDATE",
+		ForShare:   "FOR SHARE",
+		SkipLocked: "SKIP LOCKED",
+		NoWait:     "NOWAIT",
+		//pg_advisory_lock 没有超时参数,超时由上下文取消
+		AdvisoryLock:     "SELECT 1 FROM pg_advisory_lock(hashtext(:name))",
+		AdvisoryUnlock:   "SELECT CASE WHEN pg_advisory_unlock(hashtext(:name)) THEN 1 ELSE 0 END",
+		TransactionalDDL: true,
+	}
+
+	//SQLite SQLite驱动(mattn/go-sqlite3)
+	SQLite = &Driver{
+		Name:             "sqlite3",
+		SupportNamed:     true,
+		NamedPrefix:      ":",
+		PlaceHolder:      "?",
+		DateFormat:       "'2006-01-02 15:04:05'",
+		SQLNameFunc:      MakeNameFunc(`"`, `"`),
+		NameFunc:         utils.LowerCase,
+		Explain:          "EXPLAIN QUERY PLAN ",
+		Savepoint:        "SAVEPOINT %s",
+		RollbackTo:       "ROLLBACK TO SAVEPOINT %s",
+		ReleaseSavepoint: "RELEASE SAVEPOINT %s",
+		TransactionalDDL: true,
 	}
 )
 
@@ -40,6 +110,11 @@ func MakeNameFunc(prefix, suffix string) func(any) string {
 	}
 }
 
+// QuotedString 字符串字面量,单引号转义为两个单引号
+func QuotedString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
 func QuotedName(name any, prefix, suffix string) string {
 	col := ""
 	switch n := name.(type) {
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package dialect
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrorNumber 获取驱动错误的错误号(MySQL/SQL Server)
+//
+// 方言包不依赖具体的数据库驱动：优先使用 SQLErrorNumber() 方法(mssql),否则读取错误结构体的Number字段(mysql.MySQLError)
+func ErrorNumber(err error) (int, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if e, ok := err.(interface{ SQLErrorNumber() int32 }); ok {
+			return int(e.SQLErrorNumber()), true
+		}
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		f := v.FieldByName("Number")
+		switch {
+		case f.CanInt():
+			return int(f.Int()), true
+		case f.CanUint():
+			return int(f.Uint()), true
+		}
+	}
+	return 0, false
+}
+
+// ErrorState 获取驱动错误的SQLSTATE(Postgres: lib/pq、pgx)
+func ErrorState(err error) (string, bool) {
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		return e.SQLState(), true
+	}
+	return "", false
+}
+
+// RetryableNumbers 根据错误号判断错误是否可重试
+func RetryableNumbers(numbers ...int) func(error) bool {
+	return func(err error) bool {
+		n, ok := ErrorNumber(err)
+		if !ok {
+			return false
+		}
+		for _, number := range numbers {
+			if n == number {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryableStates 根据SQLSTATE判断错误是否可重试
+func RetryableStates(states ...string) func(error) bool {
+	return func(err error) bool {
+		s, ok := ErrorState(err)
+		if !ok {
+			return false
+		}
+		for _, state := range states {
+			if s == state {
+				return true
+			}
+		}
+		return false
+	}
+}
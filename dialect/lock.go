@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package dialect
+
+import "strings"
+
+// LockMode 行锁模式
+type LockMode int
+
+const (
+	// LockNone 不加锁
+	LockNone LockMode = iota
+	// LockForUpdate 排他锁(FOR UPDATE)
+	LockForUpdate
+	// LockForShare 共享锁(FOR SHARE)
+	LockForShare
+)
+
+// LockWait 遇到已锁定记录时的等待策略
+type LockWait int
+
+const (
+	// LockWaitDefault 等待锁释放(默认)
+	LockWaitDefault LockWait = iota
+	// LockSkipLocked 跳过已锁定的记录(SKIP LOCKED)
+	LockSkipLocked
+	// LockNoWait 不等待,直接返回错误(NOWAIT)
+	LockNoWait
+)
+
+// LockSQL 行锁语句,不支持行锁或不加锁时返回空字符串
+//
+// LockHints 为true时返回表提示的内容(如 UPDLOCK, ROWLOCK, READPAST),否则返回追加在语句末尾的子句(如 FOR UPDATE SKIP LOCKED)
+func (d *Driver) LockSQL(mode LockMode, wait LockWait) string {
+	var parts []string
+	switch mode {
+	case LockForUpdate:
+		parts = append(parts, d.ForUpdate)
+	case LockForShare:
+		parts = append(parts, d.ForShare)
+	}
+	if len(parts) == 0 || parts[0] == "" {
+		return ""
+	}
+	switch {
+	case wait == LockSkipLocked && d.SkipLocked != "":
+		parts = append(parts, d.SkipLocked)
+	case wait == LockNoWait && d.NoWait != "":
+		parts = append(parts, d.NoWait)
+	}
+	sep := " "
+	if d.LockHints {
+		sep = ", "
+	}
+	return strings.Join(parts, sep)
+}
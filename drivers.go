@@ -11,8 +11,12 @@ var (
 	DefaultDriver = dialect.MySQL
 	MySQL         = dialect.MySQL
 	SQLServer     = dialect.SQLServer
+	Postgres      = dialect.Postgres
+	SQLite        = dialect.SQLite
 	Drivers       = map[string]*dialect.Driver{
-		"mysql": MySQL,
-		"mssql": SQLServer,
+		"mysql":    MySQL,
+		"mssql":    SQLServer,
+		"postgres": Postgres,
+		"sqlite3":  SQLite,
 	}
 )
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/gnodux/sqlxx/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type EmbeddedAddress struct {
+	City   string
+	Street string
+}
+
+type EmbeddedCustomer struct {
+	Id       int64
+	TenantId int64
+	Name     string
+	Home     EmbeddedAddress  `dbx:"prefix:home_"`
+	Work     *EmbeddedAddress `dbx:"prefix:work_"`
+}
+
+var embeddedCustomerCols = meta.MustCols[EmbeddedCustomer, struct {
+	Id       meta.Col[int64]
+	HomeCity meta.Col[string]
+	WorkCity meta.Col[string]
+}]()
+
+func TestEmbedded(t *testing.T) {
+	ctx := context.Background()
+	_, err := MustGet(DefaultName).Exec("DROP TABLE IF EXISTS `embedded_customer`")
+	assert.NoError(t, err)
+	_, err = MustGet(DefaultName).Exec("CREATE TABLE `embedded_customer` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `tenant_id` BIGINT NOT NULL, `name` VARCHAR(64) NOT NULL, " +
+		"`home_city` VARCHAR(64) NOT NULL DEFAULT '', `home_street` VARCHAR(64) NOT NULL DEFAULT '', `work_city` VARCHAR(64) NOT NULL DEFAULT '', `work_street` VARCHAR(64) NOT NULL DEFAULT '')")
+	assert.NoError(t, err)
+	mapper, err := NewMapper[BaseMapper[*EmbeddedCustomer]](DefaultName)
+	assert.NoError(t, err)
+	tenant := time.Now().UnixNano()
+
+	customer := &EmbeddedCustomer{TenantId: tenant, Name: "alice",
+		Home: EmbeddedAddress{City: "Hangzhou", Street: "West Lake"},
+		Work: &EmbeddedAddress{City: "Shanghai", Street: "Nanjing Road"}}
+	assert.Equal(t, map[string]any{"TenantId": tenant, "Name": "alice", "Home.City": "Hangzhou", "Home.Street": "West Lake",
+		"Work.City": "Shanghai", "Work.Street": "Nanjing Road"}, utils.ToMap(customer))
+	assert.NoError(t, mapper.Create(customer))
+	assert.NoError(t, mapper.Insert(&EmbeddedCustomer{TenantId: tenant, Name: "bob", Home: EmbeddedAddress{City: "Beijing"}}))
+
+	list, err := mapper.ListById(tenant, customer.Id)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, customer.Home, list[0].Home)
+		assert.Equal(t, customer.Work, list[0].Work)
+	}
+
+	customer.Home.Street = "Lingyin Road"
+	customer.Work.City = "Suzhou"
+	assert.NoError(t, mapper.PartialUpdate(true, []string{"Home"}, customer))
+	found, err := mapper.Query().Tenant(tenant).Where(embeddedCustomerCols.HomeCity.Eq("Hangzhou")).First(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lingyin Road", found.Home.Street)
+	assert.Equal(t, "Shanghai", found.Work.City)
+
+	assert.NoError(t, mapper.Update(true, customer))
+	found, err = mapper.Query().Tenant(tenant).Where(embeddedCustomerCols.WorkCity.Eq("Suzhou")).First(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", found.Name)
+
+	result, _, err := mapper.SelectByExample(&EmbeddedCustomer{TenantId: tenant, Home: EmbeddedAddress{City: "Beijing"}})
+	assert.NoError(t, err)
+	if assert.Len(t, result, 1) {
+		assert.Equal(t, "bob", result[0].Name)
+	}
+	_, err = mapper.Query().Tenant(tenant).Where(embeddedCustomerCols.Id.Gt(0)).Erase(ctx)
+	assert.NoError(t, err)
+}
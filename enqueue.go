@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+// Enqueuer 在事务中写入待发布的消息(事务性发件箱,参考 outbox 包)
+type Enqueuer func(tx *Tx, topic string, payload any) error
+
+// WithEnqueuer 打开数据库时注册发件箱
+func WithEnqueuer(fn Enqueuer) Option {
+	return func(d *DB) {
+		d.enqueuer = fn
+	}
+}
+
+// SetEnqueuer 注册发件箱,outbox.New 会自动注册
+func (d *DB) SetEnqueuer(fn Enqueuer) {
+	d.enqueuer = fn
+}
+
+// Enqueue 在当前事务中写入待发布的消息,消息与事务中的其他修改一起提交或回滚
+//
+// 数据库没有注册发件箱时返回 ErrNoEnqueuer
+func (t *Tx) Enqueue(topic string, payload any) error {
+	if t == nil || t.db == nil {
+		return ErrNilDB
+	}
+	if t.db.enqueuer == nil {
+		return ErrNoEnqueuer
+	}
+	return t.db.enqueuer(t, topic, payload)
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"github.com/cookieY/sqlx"
+	"github.com/gnodux/sqlxx/expr"
+)
+
+// executor DB与Tx共有的执行接口(*sqlx.DB/*sqlx.Tx)
+type executor interface {
+	sqlx.ExtContext
+	PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error)
+	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+}
+
+// executor 获取执行器,tx不为空时在事务中执行
+func (d *DB) executor(tx *Tx) executor {
+	if tx != nil {
+		return tx.Tx
+	}
+	return d.DB
+}
+
+// 以下方法是DB与Tx执行语句的统一入口，所有语句都经过拦截器链
+
+// newStatement 创建语句信息,并确定执行语句的事务(参考 activeTx)
+func (d *DB) newStatement(ctx context.Context, tx *Tx, op, sqlOrTpl, query string, args any) *Statement {
+	stmt := newStatement(op, sqlOrTpl, query, args)
+	stmt.Tx = d.activeTx(ctx, tx)
+	return stmt
+}
+
+func (d *DB) doSelect(ctx context.Context, tx *Tx, dest any, sqlOrTpl, query string, args []any) error {
+	stmt := d.newStatement(ctx, tx, OpSelect, sqlOrTpl, query, args)
+	return d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) error {
+		if err := sqlx.SelectContext(ctx, d.executor(stmt.Tx), dest, stmt.Query, argList(stmt.Args)...); err != nil {
+			return err
+		}
+		stmt.RowsAffected = sizeOf(dest)
+		return nil
+	})
+}
+
+func (d *DB) doNamedSelect(ctx context.Context, tx *Tx, dest any, sqlOrTpl, query string, arg any) error {
+	stmt := d.newStatement(ctx, tx, OpSelect, sqlOrTpl, query, arg)
+	return d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		var named *sqlx.NamedStmt
+		if named, err = d.executor(stmt.Tx).PrepareNamedContext(ctx, stmt.Query); err != nil {
+			return
+		}
+		defer func() {
+			if stErr := named.Close(); stErr != nil {
+				err = stErr
+			}
+		}()
+		if err = named.SelectContext(ctx, dest, stmt.Args); err == nil {
+			stmt.RowsAffected = sizeOf(dest)
+		}
+		return
+	})
+}
+
+func (d *DB) doGet(ctx context.Context, tx *Tx, dest any, sqlOrTpl, query string, args []any) error {
+	stmt := d.newStatement(ctx, tx, OpGet, sqlOrTpl, query, args)
+	return d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) error {
+		if err := sqlx.GetContext(ctx, d.executor(stmt.Tx), dest, stmt.Query, argList(stmt.Args)...); err != nil {
+			return err
+		}
+		stmt.RowsAffected = 1
+		return nil
+	})
+}
+
+func (d *DB) doNamedGet(ctx context.Context, tx *Tx, dest any, sqlOrTpl, query string, arg any) error {
+	stmt := d.newStatement(ctx, tx, OpGet, sqlOrTpl, query, arg)
+	return d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		var named *sqlx.NamedStmt
+		if named, err = d.executor(stmt.Tx).PrepareNamedContext(ctx, stmt.Query); err != nil {
+			return
+		}
+		defer func() {
+			_ = named.Close()
+		}()
+		if err = named.GetContext(ctx, dest, stmt.Args); err == nil {
+			stmt.RowsAffected = 1
+		}
+		return
+	})
+}
+
+func (d *DB) doExec(ctx context.Context, tx *Tx, sqlOrTpl, query string, args []any) (result sql.Result, err error) {
+	stmt := d.newStatement(ctx, tx, OpExec, sqlOrTpl, query, args)
+	err = d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		if result, err = d.executor(stmt.Tx).ExecContext(ctx, stmt.Query, argList(stmt.Args)...); err == nil {
+			stmt.RowsAffected, _ = result.RowsAffected()
+		}
+		return
+	})
+	return
+}
+
+func (d *DB) doNamedExec(ctx context.Context, tx *Tx, sqlOrTpl, query string, arg any) (result sql.Result, err error) {
+	stmt := d.newStatement(ctx, tx, OpExec, sqlOrTpl, query, arg)
+	err = d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		if result, err = sqlx.NamedExecContext(ctx, d.executor(stmt.Tx), stmt.Query, stmt.Args); err == nil {
+			stmt.RowsAffected, _ = result.RowsAffected()
+		}
+		return
+	})
+	return
+}
+
+func (d *DB) doNamedQuery(ctx context.Context, tx *Tx, sqlOrTpl, query string, arg any) (rows *sqlx.Rows, err error) {
+	stmt := d.newStatement(ctx, tx, OpQuery, sqlOrTpl, query, arg)
+	err = d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		rows, err = sqlx.NamedQueryContext(ctx, d.executor(stmt.Tx), stmt.Query, stmt.Args)
+		return
+	})
+	return
+}
+
+func (d *DB) doPrepare(ctx context.Context, tx *Tx, sqlOrTpl, query string, arg any) (prepared *sqlx.Stmt, err error) {
+	stmt := d.newStatement(ctx, tx, OpPrepare, sqlOrTpl, query, arg)
+	err = d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		prepared, err = d.executor(stmt.Tx).PreparexContext(ctx, stmt.Query)
+		return
+	})
+	return
+}
+
+func (d *DB) doPrepareNamed(ctx context.Context, tx *Tx, sqlOrTpl, query string, arg any) (prepared *sqlx.NamedStmt, err error) {
+	stmt := d.newStatement(ctx, tx, OpPrepare, sqlOrTpl, query, arg)
+	err = d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		prepared, err = d.executor(stmt.Tx).PrepareNamedContext(ctx, stmt.Query)
+		return
+	})
+	return
+}
+
+// runPrepared 预编译语句并执行fn,拦截器记录的耗时包含fn的执行过程
+func (d *DB) runPrepared(ctx context.Context, tx *Tx, sqlOrTpl, query string, arg any, fn func(*sqlx.Stmt) error) error {
+	stmt := d.newStatement(ctx, tx, OpPrepare, sqlOrTpl, query, arg)
+	return d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		var prepared *sqlx.Stmt
+		if prepared, err = d.executor(stmt.Tx).PreparexContext(ctx, stmt.Query); err != nil {
+			return
+		}
+		defer func() {
+			if stErr := prepared.Close(); stErr != nil {
+				err = stErr
+			}
+		}()
+		return fn(prepared)
+	})
+}
+
+// runPrepareNamed 预编译命名参数语句并执行fn,拦截器记录的耗时包含fn的执行过程
+func (d *DB) runPrepareNamed(ctx context.Context, tx *Tx, sqlOrTpl, query string, arg any, fn func(*sqlx.NamedStmt) error) error {
+	stmt := d.newStatement(ctx, tx, OpPrepare, sqlOrTpl, query, arg)
+	return d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) (err error) {
+		var prepared *sqlx.NamedStmt
+		if prepared, err = d.executor(stmt.Tx).PrepareNamedContext(ctx, stmt.Query); err != nil {
+			return
+		}
+		defer func() {
+			if stErr := prepared.Close(); stErr != nil {
+				err = stErr
+			}
+		}()
+		return fn(prepared)
+	})
+}
+
+func (d *DB) selectExpr(ctx context.Context, tx *Tx, dest any, exp expr.Expr) error {
+	buff := expr.NewTracedBuffer(d.driver)
+	if d.driver.SupportNamed {
+		query, namedArgs, err := buff.BuildNamed(exp)
+		if err != nil {
+			return err
+		}
+		return d.doNamedSelect(ctx, tx, dest, "", query, namedArgs)
+	} else {
+		query, args, err := buff.Build(exp)
+		if err != nil {
+			return err
+		}
+		return d.doSelect(ctx, tx, dest, "", query, args)
+	}
+}
+
+func (d *DB) execExpr(ctx context.Context, tx *Tx, exp expr.Expr) (sql.Result, error) {
+	buff := expr.NewTracedBuffer(d.driver)
+	if d.driver.SupportNamed {
+		query, namedArgs, err := buff.BuildNamed(exp)
+		if err != nil {
+			return nil, err
+		}
+		return d.doNamedExec(ctx, tx, "", query, namedArgs)
+	} else {
+		query, args, err := buff.Build(exp)
+		if err != nil {
+			return nil, err
+		}
+		return d.doExec(ctx, tx, "", query, args)
+	}
+}
+
+func (d *DB) getExpr(ctx context.Context, tx *Tx, dest any, exp expr.Expr) error {
+	buff := expr.NewTracedBuffer(d.driver)
+	if d.driver.SupportNamed {
+		query, namedArgs, err := buff.BuildNamed(exp)
+		if err != nil {
+			return err
+		}
+		return d.doNamedGet(ctx, tx, dest, "", query, namedArgs)
+	} else {
+		query, args, err := buff.Build(exp)
+		if err != nil {
+			return err
+		}
+		return d.doGet(ctx, tx, dest, "", query, args)
+	}
+}
+
+// argList 将参数转换为位置参数列表
+func argList(args any) []any {
+	switch a := args.(type) {
+	case nil:
+		return nil
+	case []any:
+		return a
+	default:
+		return []any{a}
+	}
+}
@@ -149,10 +149,10 @@ func (n *NameExpr) Between(min, max any) *BetweenExpr {
 	minExp, minOk := min.(Expr)
 	maxExp, maxOk := max.(Expr)
 	if !minOk {
-		minExp = Var(autoParam(), min)
+		minExp = AutoVar(min)
 	}
 	if !maxOk {
-		maxExp = Var(autoParam(), max)
+		maxExp = AutoVar(max)
 	}
 	return Between(n, minExp, maxExp)
 }
@@ -325,6 +325,11 @@ func Var(name string, value any) *ValueExpr {
 	return &ValueExpr{Name: name, Value: value}
 }
 
+// AutoVar 自动命名的参数,例如：AutoVar(1)会被格式化为：:param_1
+func AutoVar(value any) *ValueExpr {
+	return Var(autoParam(), value)
+}
+
 // Const 常量, 例如：Const(1), Const("a")，和Raw不同的是，Const会自动将值转换为SQL语句中的常量，例如：Const(1)会被格式化为：1，Const("a")会被格式化为：'a'
 func Const(value any) *ConstantExpr {
 	return &ConstantExpr{Value: value}
@@ -353,6 +358,14 @@ func Binary(left Expr, op string, right any) *BinaryExpr {
 		return &BinaryExpr{Left: left, Space: " ", Operator: op, Right: Const(right)}
 	}
 }
+
+// Assign 赋值表达式(UPDATE的SET),总是使用 = ,非表达式的值(包括nil)绑定为参数,例如:Assign(Name("address"), nil)会被格式化为:`address` = :param_1
+func Assign(left Expr, value any) *BinaryExpr {
+	if r, ok := value.(Expr); ok {
+		return &BinaryExpr{Left: left, Space: " ", Operator: keywords.Equal, Right: r}
+	}
+	return &BinaryExpr{Left: left, Space: " ", Operator: keywords.Equal, Right: AutoVar(value)}
+}
 func Eq(left Expr, right any) *BinaryExpr {
 	return Binary(left, keywords.Equal, right)
 }
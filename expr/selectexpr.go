@@ -6,6 +6,7 @@
 package expr
 
 import (
+	"github.com/gnodux/sqlxx/dialect"
 	"github.com/gnodux/sqlxx/expr/keywords"
 )
 
@@ -24,6 +25,8 @@ type SelectExpr struct {
 	limit       int
 	offset      int
 	withCount   bool
+	lockMode    dialect.LockMode
+	lockWait    dialect.LockWait
 }
 
 func (s *SelectExpr) UseCount() bool {
@@ -51,6 +54,31 @@ func (s *SelectExpr) Offset(offset int) *SelectExpr {
 	s.offset = offset
 	return s
 }
+
+// ForUpdate 对查询的记录加排他锁(需要在事务中执行)
+func (s *SelectExpr) ForUpdate() *SelectExpr {
+	s.lockMode = dialect.LockForUpdate
+	return s
+}
+
+// ForShare 对查询的记录加共享锁(需要在事务中执行)
+func (s *SelectExpr) ForShare() *SelectExpr {
+	s.lockMode = dialect.LockForShare
+	return s
+}
+
+// SkipLocked 加锁时跳过已被锁定的记录,需要和 ForUpdate/ForShare 一起使用
+func (s *SelectExpr) SkipLocked() *SelectExpr {
+	s.lockWait = dialect.LockSkipLocked
+	return s
+}
+
+// NoWait 加锁时不等待已被锁定的记录(直接返回错误),需要和 ForUpdate/ForShare 一起使用
+func (s *SelectExpr) NoWait() *SelectExpr {
+	s.lockWait = dialect.LockNoWait
+	return s
+}
+
 func (s *SelectExpr) Select(columns ...Expr) *SelectExpr {
 	s.Columns = List(",", columns...)
 	return s
@@ -86,6 +114,10 @@ func (s *SelectExpr) Format(buffer *TracedBuffer) {
 	}
 	buffer.AppendString(buffer.KeywordWithSpace(keywords.From))
 	s.FromExpr.Format(buffer)
+	lock := buffer.LockSQL(s.lockMode, s.lockWait)
+	if lock != "" && buffer.LockHints {
+		buffer.AppendString(" WITH (").AppendString(lock).AppendString(")")
+	}
 	if s.WhereExpr != nil {
 		buffer.AppendString(buffer.KeywordWithSpace(keywords.Where))
 		s.WhereExpr.Format(buffer)
@@ -113,6 +145,13 @@ func (s *SelectExpr) Format(buffer *TracedBuffer) {
 		Var("offset", s.offset).Format(buffer)
 		buffer.AppendString(keywords.Space)
 	}
+	if lock != "" && !buffer.LockHints {
+		//limit 子句以空格结尾
+		if s.limit == 0 {
+			buffer.AppendString(keywords.Space)
+		}
+		buffer.AppendString(lock)
+	}
 }
 
 func Select(columns ...Expr) *SelectExpr {
@@ -87,3 +87,36 @@ func TestSelect(t *testing.T) {
 		})
 	}
 }
+
+func TestSelect_Lock(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver *dialect.Driver
+		expr   *SelectExpr
+		want   string
+	}{
+		{"mysql for update", dialect.MySQL, Select(All).From(N("t")).Where(Eq(N("id"), R(1))).ForUpdate(),
+			"SELECT * FROM `t` WHERE `id` = 1 FOR UPDATE"},
+		{"mysql skip locked", dialect.MySQL, Select(All).From(N("t")).ForUpdate().SkipLocked(),
+			"SELECT * FROM `t` FOR UPDATE SKIP LOCKED"},
+		{"mysql limit", dialect.MySQL, Select(All).From(N("t")).Limit(10).ForUpdate().NoWait(),
+			"SELECT * FROM `t` LIMIT  :limit  OFFSET  :offset FOR UPDATE NOWAIT"},
+		{"postgres for share", dialect.Postgres, Select(All).From(N("t")).ForShare().NoWait(),
+			`SELECT * FROM "t" FOR SHARE NOWAIT`},
+		{"sqlserver hints", dialect.SQLServer, Select(All).From(N("t")).Where(Eq(N("id"), R(1))).ForUpdate().SkipLocked(),
+			"SELECT * FROM [t] WITH (UPDLOCK, ROWLOCK, READPAST) WHERE [id] = 1"},
+		{"sqlserver for share", dialect.SQLServer, Select(All).From(N("t")).ForShare(),
+			"SELECT * FROM [t] WITH (HOLDLOCK, ROWLOCK)"},
+		{"sqlite unsupported", dialect.SQLite, Select(All).From(N("t")).ForUpdate().SkipLocked(),
+			`SELECT * FROM "t"`},
+		{"skip locked only", dialect.MySQL, Select(All).From(N("t")).SkipLocked(),
+			"SELECT * FROM `t`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := NewTracedBuffer(tt.driver)
+			tt.expr.Format(buffer)
+			assert.Equal(t, tt.want, buffer.String())
+		})
+	}
+}
@@ -32,6 +32,12 @@ func TestUpdate(t *testing.T) {
 				expr: Update(N("table")).Set(N("name").Eq(V("name", "gnodux")), N("age").Eq(V("age", 18))).Where(N("id").Eq(V("id", 1))),
 			},
 			want: "UPDATE `table` SET `name` = :name, `age` = :age WHERE `id` = :id",
+		}, {
+			name: "assign null",
+			args: args{
+				expr: Update(N("table")).Set(Assign(N("address"), V("address", nil)), Assign(N("age"), Raw("age + 1"))).Where(N("id").Eq(V("id", 1))),
+			},
+			want: "UPDATE `table` SET `address` = :address, `age` = age + 1 WHERE `id` = :id",
 		},
 	}
 	for _, tt := range tests {
@@ -6,6 +6,7 @@
 package sqlxx
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/gnodux/sqlxx/dialect"
 	"github.com/gnodux/sqlxx/utils"
@@ -63,6 +64,13 @@ type Factory struct {
 	constructors map[string]DBConstructor
 	lock         *sync.RWMutex
 	templateFS   []*TplFS
+	interceptors []Interceptor
+	//optionLock 保护logger/redactor(constructor在持有lock时创建连接并输出日志,因此不能使用lock)
+	optionLock sync.RWMutex
+	logger     Logger
+	redactor   Redactor
+	bindings   map[bindingKey]*binding
+	listeners
 }
 
 func NewFactoryWithDriver(name string, driver *dialect.Driver) *Factory {
@@ -115,13 +123,13 @@ func (m *Factory) Get(name string) (*DB, error) {
 				}()
 				var err error
 				conn, err = loader()
-				conn.SetManager(m)
-				conn.MapperFunc(NameFunc)
 				if err != nil {
 					return err
-				} else {
-					m.dbs[name] = conn
 				}
+				conn.SetManager(m)
+				conn.MapperFunc(NameFunc)
+				conn.name = name
+				m.dbs[name] = conn
 				return nil
 			}()
 			if err != nil {
@@ -150,8 +158,9 @@ func (m *Factory) CreateAndSet(name string, fn DBConstructor) (*DB, error) {
 }
 
 // Open 打开一个数据库连接
-func (m *Factory) Open(name, driverName, dsn string) (*DB, error) {
-	db, err := OpenWith(m, Drivers[driverName], dsn)
+// opts: 连接池等可选配置
+func (m *Factory) Open(name, driverName, dsn string, opts ...Option) (*DB, error) {
+	db, err := OpenWith(m, Drivers[driverName], dsn, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -164,9 +173,44 @@ func (m *Factory) Set(name string, db *DB) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	db.m = m
+	db.name = name
 	m.dbs[name] = db
 }
 
+// SetLogger 设置Factory级别的日志,未设置时使用全局日志(参考 SetLogger)
+func (m *Factory) SetLogger(l Logger) {
+	m.optionLock.Lock()
+	defer m.optionLock.Unlock()
+	m.logger = l
+}
+
+// Logger 获取Factory的日志
+func (m *Factory) Logger() Logger {
+	m.optionLock.RLock()
+	defer m.optionLock.RUnlock()
+	if m.logger == nil {
+		return log
+	}
+	return m.logger
+}
+
+// SetRedactor 设置语句日志的参数脱敏方式,默认为 RedactSensitive
+func (m *Factory) SetRedactor(r Redactor) {
+	m.optionLock.Lock()
+	defer m.optionLock.Unlock()
+	m.redactor = r
+}
+
+// Redactor 获取语句日志的参数脱敏方式
+func (m *Factory) Redactor() Redactor {
+	m.optionLock.RLock()
+	defer m.optionLock.RUnlock()
+	if m.redactor == nil {
+		return RedactSensitive
+	}
+	return m.redactor
+}
+
 // SetConstructor set a database constructor(Lazy create DB)
 func (m *Factory) SetConstructor(name string, loadFunc DBConstructor) {
 	m.lock.Lock()
@@ -174,15 +218,32 @@ func (m *Factory) SetConstructor(name string, loadFunc DBConstructor) {
 	m.constructors[name] = loadFunc
 }
 
-func (m *Factory) BoostMapper(dest any, dataSource string) error {
-	return BoostMapper(dest, m, dataSource)
+func (m *Factory) BoostMapper(dest any, dataSource string, opts ...BoostOption) error {
+	return BoostMapper(dest, m, dataSource, opts...)
 }
+
+// Stats 获取所有已初始化数据库的连接池统计信息,key为数据库名称
+//
+// 尚未初始化(仅设置了Constructor)的数据库不会被统计
+func (m *Factory) Stats() map[string]sql.DBStats {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stats := make(map[string]sql.DBStats, len(m.dbs))
+	for name, db := range m.dbs {
+		stats[name] = db.Stats()
+	}
+	return stats
+}
+
 func (m *Factory) Shutdown() error {
 	for _, v := range m.dbs {
 		if err := v.Close(); err != nil {
 			return err
 		}
 	}
+	m.lock.Lock()
+	m.bindings = nil
+	m.lock.Unlock()
 	return nil
 }
 func (m *Factory) String() string {
@@ -18,6 +18,9 @@ import (
 	"time"
 )
 
+// testDSN 测试数据库(MySQL),其他测试使用独立的 Factory 连接同一个数据库
+const testDSN = "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true"
+
 var (
 	encoder = json.NewEncoder(os.Stdout)
 )
@@ -26,7 +29,7 @@ func TestMain(m *testing.M) {
 	logrus.SetLevel(logrus.TraceLevel)
 	encoder.SetIndent("", "  ")
 	SetConstructor(DefaultName, func() (*DB, error) {
-		return Open("mysql", "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true&multiStatements=true")
+		return Open("mysql", testDSN+"&multiStatements=true")
 	})
 	SetTemplateFS(os.DirFS("./testdata"), "examples/*.sql", "initialize/*.sql", "my_mapper/*.sql")
 	initData()
@@ -121,3 +124,24 @@ func TestNilDB(t *testing.T) {
 func TestMustGet(t *testing.T) {
 	utils.Must(Get(DefaultName))
 }
+
+func TestFactory_Stats(t *testing.T) {
+	f := NewFactory("stats")
+	_, err := f.Open(DefaultName, "mysql", testDSN,
+		WithMaxOpenConns(5), WithMaxIdleConns(2), WithConnMaxLifetime(time.Minute), WithConnMaxIdleTime(time.Second*30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	var count int
+	if err = f.MustGet(DefaultName).Get(&count, "SELECT COUNT(1) FROM tenant"); err != nil {
+		t.Fatal(err)
+	}
+	stats := f.Stats()
+	if s, ok := stats[DefaultName]; !ok {
+		t.Fatal("stats not found")
+	} else if s.MaxOpenConnections != 5 {
+		t.Errorf("max open connections want 5, got %d", s.MaxOpenConnections)
+	}
+	encoder.Encode(stats)
+}
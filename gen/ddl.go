@@ -0,0 +1,245 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package gen
+
+import (
+	"fmt"
+	"github.com/gnodux/sqlxx/migrate"
+	"strings"
+)
+
+// constraintWords 列定义中类型之后的约束关键字
+var constraintWords = map[string]bool{
+	"NOT": true, "NULL": true, "PRIMARY": true, "DEFAULT": true, "AUTO_INCREMENT": true, "AUTOINCREMENT": true,
+	"UNIQUE": true, "REFERENCES": true, "CHECK": true, "COMMENT": true, "IDENTITY": true, "GENERATED": true,
+	"COLLATE": true, "CONSTRAINT": true, "KEY": true, "ON": true,
+}
+
+// ParseDDL 离线解析建表语句(CREATE TABLE),忽略其他语句
+//
+// 只解析列名、类型、是否允许为空和主键,索引、外键等约束被忽略
+func ParseDDL(script string) ([]*Table, error) {
+	var tables []*Table
+	for _, stmt := range migrate.Split(script) {
+		tokens := tokenize(stripComments(stmt))
+		if len(tokens) < 3 || !strings.EqualFold(tokens[0], "CREATE") {
+			continue
+		}
+		idx := 1
+		for idx < len(tokens) && isWord(tokens[idx], "TEMPORARY", "TEMP", "GLOBAL", "LOCAL", "UNLOGGED") {
+			idx++
+		}
+		if idx >= len(tokens) || !strings.EqualFold(tokens[idx], "TABLE") {
+			continue
+		}
+		idx++
+		if idx+2 < len(tokens) && isWord(tokens[idx], "IF") {
+			idx += 3
+		}
+		if idx+1 >= len(tokens) || !strings.HasPrefix(tokens[idx+1], "(") {
+			return nil, fmt.Errorf("gen: invalid create table statement: %s", stmt)
+		}
+		table := &Table{Name: unquote(tokens[idx])}
+		body := tokens[idx+1]
+		if err := parseDefinitions(table, body[1:len(body)-1]); err != nil {
+			return nil, fmt.Errorf("gen: table %s: %w", table.Name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// parseDefinitions 解析列定义和表约束
+func parseDefinitions(table *Table, body string) error {
+	for _, def := range splitTopLevel(body) {
+		tokens := tokenize(def)
+		if len(tokens) == 0 {
+			continue
+		}
+		if isWord(tokens[0], "CONSTRAINT") && len(tokens) > 2 {
+			tokens = tokens[2:]
+		}
+		switch {
+		case isWord(tokens[0], "PRIMARY") && len(tokens) > 2:
+			for _, name := range splitTopLevel(strings.Trim(tokens[2], "()")) {
+				name = unquote(strings.Fields(name)[0])
+				for _, col := range table.Columns {
+					if strings.EqualFold(col.Name, name) {
+						col.PrimaryKey, col.Nullable = true, false
+					}
+				}
+			}
+			continue
+		case isWord(tokens[0], "UNIQUE", "KEY", "INDEX", "FOREIGN", "CHECK", "FULLTEXT", "SPATIAL", "EXCLUDE"):
+			continue
+		}
+		if len(tokens) < 2 {
+			return fmt.Errorf("invalid column definition: %s", def)
+		}
+		col := &Column{Name: unquote(tokens[0]), Nullable: true}
+		idx := 1
+		var typ []string
+		for ; idx < len(tokens); idx++ {
+			word := strings.ToUpper(tokens[idx])
+			if constraintWords[word] || word == "CHARACTER" && idx+1 < len(tokens) && isWord(tokens[idx+1], "SET") {
+				break
+			}
+			if strings.HasPrefix(word, "(") && len(typ) > 0 {
+				typ[len(typ)-1] += tokens[idx]
+				continue
+			}
+			typ = append(typ, tokens[idx])
+		}
+		col.DataType = strings.Join(typ, " ")
+		for ; idx < len(tokens); idx++ {
+			switch {
+			case isWord(tokens[idx], "NOT") && idx+1 < len(tokens) && isWord(tokens[idx+1], "NULL"):
+				col.Nullable = false
+			case isWord(tokens[idx], "PRIMARY"):
+				col.PrimaryKey, col.Nullable = true, false
+			}
+		}
+		table.Columns = append(table.Columns, col)
+	}
+	return nil
+}
+
+// tokenize 将语句拆分为单词、引用名称、字符串和括号分组
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(':
+			depth, j := 0, i
+			for ; j < len(s); j++ {
+				if s[j] == '(' {
+					depth++
+				} else if s[j] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				} else if s[j] == '\'' {
+					j += strings.IndexByte(s[j+1:], '\'') + 1
+				}
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		case c == ',':
+			tokens = append(tokens, ",")
+			i++
+		default:
+			// 单词可以包含引用部分,例如 [dbo].[user]、'a b'
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r(,", rune(s[j])) {
+				if closing := closingQuote(s[j]); closing != 0 {
+					end := strings.IndexByte(s[j+1:], closing)
+					if end < 0 {
+						j = len(s)
+						break
+					}
+					j += end + 1
+				}
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// closingQuote 引号对应的结束符号,不是引号时返回0
+func closingQuote(c byte) byte {
+	switch c {
+	case '\'', '"', '`':
+		return c
+	case '[':
+		return ']'
+	}
+	return 0
+}
+
+// splitTopLevel 按不在括号和字符串中的逗号拆分
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+		quote byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// stripComments 删除行注释和块注释(不处理字符串中的注释标记)
+func stripComments(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "--"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return sb.String()
+			}
+			i += end - 1
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return sb.String()
+			}
+			i += end + 3
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// unquote 去掉名称的引号和schema前缀
+func unquote(name string) string {
+	if idx := strings.LastIndexByte(name, '.'); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.Trim(name, "`\"[]")
+}
+
+func isWord(token string, words ...string) bool {
+	for _, w := range words {
+		if strings.EqualFold(token, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// Package gen 根据数据库表结构生成实体和mapper代码(参考 cmd/sqlxx-gen)
+//
+// 表结构可以从数据库读取(FromDB),也可以离线解析建表语句(ParseDDL)
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/gnodux/sqlxx/utils"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultPackage 默认的包名
+	DefaultPackage = "model"
+	// DefaultTenantKey 默认的租户列名
+	DefaultTenantKey = "tenant_id"
+	// DefaultSoftDelete 默认的逻辑删除列名
+	DefaultSoftDelete = "is_deleted"
+)
+
+// Table 表结构
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+// Column 列结构
+type Column struct {
+	Name string
+	//DataType 数据库类型,例如 VARCHAR(64)
+	DataType   string
+	Nullable   bool
+	PrimaryKey bool
+}
+
+// Option 生成配置
+type Option func(g *Generator)
+
+// WithPackage 设置生成代码的包名,默认为 DefaultPackage
+func WithPackage(pkg string) Option {
+	return func(g *Generator) {
+		g.pkg = pkg
+	}
+}
+
+// WithTenantKey 设置租户列名,默认为 DefaultTenantKey
+func WithTenantKey(column string) Option {
+	return func(g *Generator) {
+		g.tenantKey = column
+	}
+}
+
+// WithSoftDelete 设置逻辑删除列名,默认为 DefaultSoftDelete
+func WithSoftDelete(column string) Option {
+	return func(g *Generator) {
+		g.softDelete = column
+	}
+}
+
+// Generator 代码生成器
+type Generator struct {
+	pkg        string
+	tenantKey  string
+	softDelete string
+}
+
+// New 创建代码生成器
+func New(opts ...Option) *Generator {
+	g := &Generator{
+		pkg:        DefaultPackage,
+		tenantKey:  DefaultTenantKey,
+		softDelete: DefaultSoftDelete,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// entity 模板中的实体
+type entity struct {
+	Name      string
+	Table     string
+	TableName bool
+	Fields    []*field
+}
+
+// field 模板中的字段
+type field struct {
+	Name    string
+	Type    string
+	Tag     string
+	Comment string
+}
+
+var codeTemplate = template.Must(template.New("gen").Parse(`// Code generated by sqlxx-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{range .Entities}}
+// {{.Name}} 表 {{.Table}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}}{{if .Tag}} ` + "`{{.Tag}}`" + `{{end}}{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+{{if .TableName}}
+func ({{.Name}}) TableName() string {
+	return "{{.Table}}"
+}
+{{end}}
+// {{.Name}}Mapper {{.Name}}的mapper
+type {{.Name}}Mapper struct {
+	sqlxx.BaseMapper[{{.Name}}]
+}
+
+// {{.Name}}Columns {{.Name}}的列
+type {{.Name}}Columns struct {
+{{- range .Fields}}
+	{{.Name}} meta.Col[{{.Type}}]
+{{- end}}
+}
+
+// {{.Name}}Cols {{.Name}}的列,用于构造类型安全的条件,例如 {{.Name}}Cols.{{(index .Fields 0).Name}}.Eq(v)
+var {{.Name}}Cols = meta.MustCols[{{.Name}}, {{.Name}}Columns]()
+{{end}}`))
+
+// Generate 生成实体和mapper代码(已格式化)
+func (g *Generator) Generate(tables ...*Table) ([]byte, error) {
+	imports := map[string]bool{"github.com/gnodux/sqlxx": true, "github.com/gnodux/sqlxx/meta": true}
+	var entities []*entity
+	for _, table := range tables {
+		e := &entity{Name: utils.BigCamelCase(table.Name), Table: table.Name}
+		e.TableName = utils.LowerCase(e.Name) != table.Name
+		for _, col := range table.Columns {
+			f := g.field(col)
+			if f.Type == "time.Time" || f.Type == "*time.Time" {
+				imports["time"] = true
+			}
+			e.Fields = append(e.Fields, f)
+		}
+		entities = append(entities, e)
+	}
+	var importList []string
+	for _, imp := range []string{"time", "github.com/gnodux/sqlxx", "github.com/gnodux/sqlxx/meta"} {
+		if imports[imp] {
+			importList = append(importList, imp)
+		}
+	}
+	var buf bytes.Buffer
+	if err := codeTemplate.Execute(&buf, map[string]any{
+		"Package":  g.pkg,
+		"Imports":  importList,
+		"Entities": entities,
+	}); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: format source: %w", err)
+	}
+	return code, nil
+}
+
+// field 列对应的字段,字段名按照 utils.LowerCase 的规则映射为列名,无法映射时添加db标签
+func (g *Generator) field(col *Column) *field {
+	f := &field{Name: utils.BigCamelCase(strings.ToLower(col.Name))}
+	typ, size := goType(col.DataType)
+	var dbx []string
+	switch {
+	case col.PrimaryKey:
+		dbx = append(dbx, meta.MarkPK)
+	case strings.EqualFold(col.Name, g.tenantKey):
+		dbx = append(dbx, meta.MarkTenantKey)
+	case strings.EqualFold(col.Name, g.softDelete):
+		dbx = append(dbx, meta.MarkIsDeleted)
+	}
+	if size > 0 && typ == "string" {
+		dbx = append(dbx, meta.MarkSize+":"+strconv.Itoa(size))
+	}
+	if col.Nullable && !col.PrimaryKey && typ != "[]byte" {
+		typ = "*" + typ
+	}
+	f.Type = typ
+	var tags []string
+	if len(dbx) > 0 {
+		tags = append(tags, fmt.Sprintf(`%s:"%s"`, meta.TagField, strings.Join(dbx, ",")))
+	}
+	if utils.LowerCase(f.Name) != col.Name {
+		tags = append(tags, fmt.Sprintf(`db:"%s"`, col.Name))
+		f.Comment = "列名与字段名不匹配,BaseMapper的内置语句无法使用该列"
+	}
+	f.Tag = strings.Join(tags, " ")
+	return f
+}
+
+var sizeRegexp = regexp.MustCompile(`\((\d+)`)
+
+// goType 数据库类型对应的Go类型和长度
+func goType(dataType string) (string, int) {
+	t := strings.ToUpper(dataType)
+	size := 0
+	if m := sizeRegexp.FindStringSubmatch(t); m != nil {
+		size, _ = strconv.Atoi(m[1])
+	}
+	switch {
+	case strings.HasPrefix(t, "BOOL") || strings.HasPrefix(t, "BIT") || strings.HasPrefix(t, "TINYINT(1)"):
+		return "bool", size
+	case strings.Contains(t, "BIGINT") || strings.Contains(t, "BIGSERIAL") || strings.Contains(t, "INT") && strings.Contains(t, "UNSIGNED"):
+		return "int64", size
+	case strings.Contains(t, "SMALLINT") || strings.Contains(t, "TINYINT") || strings.Contains(t, "SMALLSERIAL"):
+		return "int16", size
+	case strings.Contains(t, "INT") || strings.Contains(t, "SERIAL"):
+		return "int32", size
+	case strings.HasPrefix(t, "FLOAT4") || t == "REAL" || t == "FLOAT":
+		return "float32", size
+	case strings.Contains(t, "DOUB") || strings.Contains(t, "FLOA") || strings.Contains(t, "REAL") ||
+		strings.Contains(t, "DEC") || strings.Contains(t, "NUMERIC") || strings.Contains(t, "MONEY"):
+		return "float64", size
+	case strings.Contains(t, "BLOB") || strings.Contains(t, "BINARY") || strings.Contains(t, "BYTEA") || strings.Contains(t, "IMAGE"):
+		return "[]byte", size
+	case strings.Contains(t, "DATE") || strings.Contains(t, "TIME"):
+		return "time.Time", size
+	}
+	return "string", size
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package gen
+
+import (
+	"context"
+	"github.com/gnodux/sqlxx"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDSN 测试数据库(MySQL)
+const testDSN = "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true"
+
+func TestParseDDL(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want []*Table
+	}{
+		{"mysql", "-- users\nCREATE TABLE IF NOT EXISTS `sys_user` (\n" +
+			"  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT 'id, pk',\n" +
+			"  `name` varchar(64) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',\n" +
+			"  `score` decimal(10,2),\n" +
+			"  PRIMARY KEY (`id`),\n" +
+			"  UNIQUE KEY `uk_name` (`name`)\n" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\nINSERT INTO sys_user (id) VALUES (1);",
+			[]*Table{{Name: "sys_user", Columns: []*Column{
+				{Name: "id", DataType: "bigint(20)", PrimaryKey: true},
+				{Name: "name", DataType: "varchar(64)"},
+				{Name: "score", DataType: "decimal(10,2)", Nullable: true},
+			}}}},
+		{"postgres", `CREATE TABLE public."role" (id SERIAL PRIMARY KEY, name character varying(32) NOT NULL, created timestamp without time zone)`,
+			[]*Table{{Name: "role", Columns: []*Column{
+				{Name: "id", DataType: "SERIAL", PrimaryKey: true},
+				{Name: "name", DataType: "character varying(32)"},
+				{Name: "created", DataType: "timestamp without time zone", Nullable: true},
+			}}}},
+		{"sqlserver", "CREATE TABLE [dbo].[T_Log] ([id] BIGINT IDENTITY(1,1) NOT NULL, [body] NVARCHAR(MAX) NULL, CONSTRAINT [pk_log] PRIMARY KEY ([id]))",
+			[]*Table{{Name: "T_Log", Columns: []*Column{
+				{Name: "id", DataType: "BIGINT", PrimaryKey: true},
+				{Name: "body", DataType: "NVARCHAR(MAX)", Nullable: true},
+			}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDDL(tt.ddl)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	tables := []*Table{{Name: "T_Account", Columns: []*Column{
+		{Name: "id", DataType: "bigint", PrimaryKey: true},
+		{Name: "org_id", DataType: "int"},
+		{Name: "email", DataType: "varchar(64)"},
+		{Name: "deleted", DataType: "tinyint(1)"},
+		{Name: "login_at", DataType: "datetime", Nullable: true},
+		{Name: "userID", DataType: "int", Nullable: true},
+	}}}
+	code, err := New(WithPackage("entity"), WithTenantKey("org_id"), WithSoftDelete("deleted")).Generate(tables...)
+	assert.NoError(t, err)
+	assert.Equal(t, `// Code generated by sqlxx-gen. DO NOT EDIT.
+
+package entity
+
+import (
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/meta"
+	"time"
+)
+
+// TAccount 表 T_Account
+type TAccount struct {
+	Id      int64  `+"`dbx:\"primaryKey\"`"+`
+	OrgId   int32  `+"`dbx:\"tenantKey\"`"+`
+	Email   string `+"`dbx:\"size:64\"`"+`
+	Deleted bool   `+"`dbx:\"softDelete\"`"+`
+	LoginAt *time.Time
+	Userid  *int32 `+"`db:\"userID\"`"+` // 列名与字段名不匹配,BaseMapper的内置语句无法使用该列
+}
+
+func (TAccount) TableName() string {
+	return "T_Account"
+}
+
+// TAccountMapper TAccount的mapper
+type TAccountMapper struct {
+	sqlxx.BaseMapper[TAccount]
+}
+
+// TAccountColumns TAccount的列
+type TAccountColumns struct {
+	Id      meta.Col[int64]
+	OrgId   meta.Col[int32]
+	Email   meta.Col[string]
+	Deleted meta.Col[bool]
+	LoginAt meta.Col[*time.Time]
+	Userid  meta.Col[*int32]
+}
+
+// TAccountCols TAccount的列,用于构造类型安全的条件,例如 TAccountCols.Id.Eq(v)
+var TAccountCols = meta.MustCols[TAccount, TAccountColumns]()
+`, string(code))
+}
+
+func TestFromDB(t *testing.T) {
+	f := sqlxx.NewFactory("gen")
+	defer f.Shutdown()
+	db, err := f.Open(sqlxx.DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := "gen_" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+	defer db.Execxx("DROP TABLE IF EXISTS " + table)
+	_, err = db.Execxx("CREATE TABLE " + table + " (id BIGINT PRIMARY KEY, tenant_id BIGINT NOT NULL, name VARCHAR(32) NULL)")
+	assert.NoError(t, err)
+	tables, err := FromDB(context.Background(), db, table)
+	assert.NoError(t, err)
+	if assert.Len(t, tables, 1) && assert.Len(t, tables[0].Columns, 3) {
+		assert.True(t, tables[0].Columns[0].PrimaryKey)
+		assert.False(t, tables[0].Columns[1].Nullable)
+		assert.True(t, tables[0].Columns[2].Nullable)
+	}
+	_, err = FromDB(context.Background(), db, table+"_missing")
+	assert.Error(t, err)
+}
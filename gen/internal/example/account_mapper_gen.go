@@ -0,0 +1,52 @@
+// Code generated by sqlxx-mapper. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"database/sql"
+	"github.com/gnodux/sqlxx"
+)
+
+// NewAccountMapper 创建AccountMapper,模板和结果类型在生成时检查(替代 sqlxx.BoostMapper)
+func NewAccountMapper(factory *sqlxx.Factory, ds string) (*AccountMapper, error) {
+	db, err := factory.Get(ds)
+	if err != nil {
+		return nil, err
+	}
+	m := &AccountMapper{}
+	if err = sqlxx.BoostMapper(&m.BaseMapper, factory, ds); err != nil {
+		return nil, err
+	}
+	m.ListByName = func(ctx context.Context, arg any) ([]Account, error) {
+		var result []Account
+		err := db.NamedSelectxxContext(sqlxx.WithMethod(ctx, "AccountMapper.ListByName"), &result, "account_mapper/list_by_name.sql", arg)
+		return result, err
+	}
+	ctxGetById := sqlxx.WithMethod(context.Background(), "AccountMapper.GetById")
+	m.GetById = func(args ...any) (*Account, error) {
+		result := new(Account)
+		err := db.GetxxContext(ctxGetById, result, "account/get_by_id.sql", args...)
+		return result, err
+	}
+	if _, err = db.ParseTemplate("github.com/gnodux/sqlxx/gen/internal/example/account_mapper/count_by_time.sql", "SELECT COUNT(1) FROM sqlxx_gen_account WHERE created_at > ?"); err != nil {
+		return nil, err
+	}
+	m.CountByTime = func(ctx context.Context, args ...any) (int, error) {
+		var result int
+		err := db.GetxxContext(sqlxx.WithMethod(ctx, "AccountMapper.CountByTime"), &result, "github.com/gnodux/sqlxx/gen/internal/example/account_mapper/count_by_time.sql", args...)
+		return result, err
+	}
+	m.Rename = func(ctx context.Context, arg any) (sql.Result, error) {
+		return db.NamedExecxxContext(sqlxx.WithMethod(ctx, "AccountMapper.Rename"), "account/rename.sql", arg)
+	}
+	retryBatch, err := sqlxx.ParseRetryPolicy("3")
+	if err != nil {
+		return nil, err
+	}
+	defBatch := sqlxx.TxDefinition{Propagation: sqlxx.PropagationRequiresNew, Retry: retryBatch}
+	m.Batch = func(ctx context.Context, fn func(*sqlxx.Tx) error) error {
+		return db.BatchWith(sqlxx.WithMethod(ctx, "AccountMapper.Batch"), defBatch, "batch.sql", fn)
+	}
+	return m, nil
+}
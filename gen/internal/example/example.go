@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// Package example sqlxx-mapper生成静态mapper的示例
+package example
+
+import (
+	"github.com/gnodux/sqlxx"
+	"time"
+)
+
+//go:generate go run github.com/gnodux/sqlxx/cmd/sqlxx-mapper -type AccountMapper -templates sql
+
+// Account 账户
+type Account struct {
+	ID        int64
+	TenantID  int64
+	Name      string `dbx:"size:64"`
+	CreatedAt time.Time
+	IsDeleted bool
+}
+
+func (Account) TableName() string {
+	return "sqlxx_gen_account"
+}
+
+// AccountQuery 账户查询条件
+type AccountQuery struct {
+	TenantID int64
+	Name     string
+}
+
+// AccountMapper 账户mapper
+type AccountMapper struct {
+	sqlxx.BaseMapper[Account]
+	ListByName  sqlxx.NamedSelectContextFunc[Account] `arg:"AccountQuery"`
+	GetById     sqlxx.GetFunc[*Account]               `sql:"account/get_by_id.sql"`
+	CountByTime sqlxx.GetContextFunc[int]             `sql:"SELECT COUNT(1) FROM sqlxx_gen_account WHERE created_at > ?"`
+	Rename      sqlxx.NamedExecContextFunc            `sql:"account/rename.sql" arg:"Account"`
+	Batch       sqlxx.TxContextFunc                   `propagation:"RequiresNew" retry:"3"`
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package example
+
+import (
+	"context"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/gnodux/sqlxx/schema"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDSN 测试数据库(MySQL)
+const testDSN = "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true"
+
+func TestAccountMapper(t *testing.T) {
+	f := sqlxx.NewFactory("example")
+	defer f.Shutdown()
+	f.SetTemplateFS(os.DirFS("sql"), "*/*.sql")
+	db, err := f.Open(sqlxx.DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := schema.CreateTable(db.Driver(), meta.NewEntity(Account{}), schema.IfNotExists())
+	assert.NoError(t, err)
+	for _, stmt := range stmts {
+		_, err = db.Execxx(stmt)
+		assert.NoError(t, err)
+	}
+	defer db.Execxx("DROP TABLE IF EXISTS sqlxx_gen_account")
+
+	m, err := NewAccountMapper(f, sqlxx.DefaultName)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, m.Create(Account{TenantID: 1, Name: "alice", CreatedAt: created}, Account{TenantID: 1, Name: "bob", CreatedAt: created}))
+
+	accounts, err := m.ListByName(ctx, AccountQuery{TenantID: 1, Name: "al"})
+	assert.NoError(t, err)
+	if assert.Len(t, accounts, 1) {
+		assert.Equal(t, "alice", accounts[0].Name)
+		account, err := m.GetById(accounts[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", account.Name)
+	}
+	count, err := m.CountByTime(ctx, created.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, m.Batch(ctx, func(tx *sqlxx.Tx) error {
+		_, err := m.Rename(tx.Context(), Account{ID: accounts[0].ID, TenantID: 1, Name: "carol"})
+		return err
+	}))
+	accounts, err = m.ListByName(ctx, AccountQuery{TenantID: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, accounts, 2) {
+		assert.Equal(t, "carol", accounts[0].Name)
+	}
+}
@@ -0,0 +1,3 @@
+SELECT id, tenant_id, name, created_at, is_deleted
+FROM sqlxx_gen_account
+WHERE id = ?
@@ -0,0 +1,3 @@
+UPDATE sqlxx_gen_account
+SET name = :name
+WHERE id = :id AND tenant_id = :tenant_id
@@ -0,0 +1,6 @@
+SELECT a.id, a.tenant_id, a.name AS name, a.created_at, a.is_deleted
+FROM sqlxx_gen_account a
+WHERE a.tenant_id = :tenant_id {{if .Name}}
+  AND a.name LIKE CONCAT(:name, '%')
+{{end}}
+ORDER BY a.id
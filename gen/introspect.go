@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package gen
+
+import (
+	"context"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/schema"
+)
+
+// FromDB 从数据库读取表结构,没有指定表名时读取当前数据库(schema)中的所有表
+func FromDB(ctx context.Context, db *sqlxx.DB, names ...string) ([]*Table, error) {
+	inspector := schema.NewInspector(db)
+	if len(names) == 0 {
+		var err error
+		if names, err = inspector.Tables(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var tables []*Table
+	for _, name := range names {
+		infos, err := inspector.Columns(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(infos) == 0 {
+			return nil, fmt.Errorf("gen: table %s not found", name)
+		}
+		table := &Table{Name: name}
+		for _, info := range infos {
+			table.Columns = append(table.Columns, &Column{
+				Name:       info.ColumnName,
+				DataType:   info.DataType,
+				Nullable:   info.Nullable(),
+				PrimaryKey: info.PrimaryKey,
+			})
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
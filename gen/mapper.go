@@ -0,0 +1,588 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/builtin"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/gnodux/sqlxx/utils"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// sqlxxPath sqlxx的包路径
+const sqlxxPath = "github.com/gnodux/sqlxx"
+
+// ErrMapper mapper定义或模板错误(生成失败)
+var ErrMapper = errors.New("gen: invalid mapper")
+
+// funcKinds 支持生成的mapper函数类型(是否使用上下文)
+var funcKinds = map[string]bool{
+	"SelectFunc": false, "NamedSelectFunc": false, "GetFunc": false, "NamedGetFunc": false,
+	"ExecFunc": false, "NamedExecFunc": false, "TxFunc": false,
+	"SelectContextFunc": true, "NamedSelectContextFunc": true, "GetContextFunc": true, "NamedGetContextFunc": true,
+	"ExecContextFunc": true, "NamedExecContextFunc": true, "TxContextFunc": true,
+}
+
+// MapperOption mapper生成配置
+type MapperOption func(g *MapperGenerator)
+
+// WithTemplateFS 添加模板文件系统,读取其中所有的.sql文件(名称为相对路径,与 sqlxx.Factory.SetTemplateFS 一致)
+func WithTemplateFS(fsys fs.FS) MapperOption {
+	return func(g *MapperGenerator) {
+		g.templateFS = append(g.templateFS, fsys)
+	}
+}
+
+// WithDialect 设置解析模板使用的数据库方言,默认为 sqlxx.DefaultDriver
+func WithDialect(driver *dialect.Driver) MapperOption {
+	return func(g *MapperGenerator) {
+		g.driver = driver
+	}
+}
+
+// WithPkgPath 设置mapper所在包的导入路径(用于按名称查找模板),默认根据go.mod计算
+func WithPkgPath(pkgPath string) MapperOption {
+	return func(g *MapperGenerator) {
+		g.pkgPath = pkgPath
+	}
+}
+
+// MapperGenerator mapper静态实现生成器
+//
+// 读取包中mapper结构体的函数字段(SelectFunc/GetFunc等)和对应的SQL模板,生成New<Mapper>函数,
+// 使用静态类型的闭包代替 sqlxx.BoostMapper 的反射调用。模板不存在、模板解析失败、
+// 命名参数与参数类型(arg标签)不匹配、查询列与结果结构体字段不匹配时生成失败
+type MapperGenerator struct {
+	dir        string
+	pkgPath    string
+	driver     *dialect.Driver
+	templateFS []fs.FS
+	fset       *token.FileSet
+	pkg        string
+	structs    map[string]*ast.StructType
+	methods    map[string][]string
+	imports    map[*ast.StructType]map[string]string
+	template   *template.Template
+}
+
+// NewMapperGenerator 解析目录中的Go文件(不包括测试文件)和模板
+func NewMapperGenerator(dir string, opts ...MapperOption) (*MapperGenerator, error) {
+	g := &MapperGenerator{
+		dir:     dir,
+		driver:  sqlxx.DefaultDriver,
+		fset:    token.NewFileSet(),
+		structs: map[string]*ast.StructType{},
+		methods: map[string][]string{},
+		imports: map[*ast.StructType]map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.pkgPath == "" {
+		var err error
+		if g.pkgPath, err = modulePath(dir); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.parsePackage(); err != nil {
+		return nil, err
+	}
+	if err := g.parseTemplates(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// parsePackage 解析包中的结构体和方法
+func (g *MapperGenerator) parsePackage() error {
+	pkgs, err := parser.ParseDir(g.fset, g.dir, func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("gen: %s must contain exactly one package", g.dir)
+	}
+	for name, pkg := range pkgs {
+		g.pkg = name
+		for _, file := range pkg.Files {
+			imports := map[string]string{}
+			for _, imp := range file.Imports {
+				p, _ := strconv.Unquote(imp.Path.Value)
+				name := path.Base(p)
+				if imp.Name != nil {
+					name = imp.Name.Name
+				}
+				imports[name] = p
+			}
+			for _, decl := range file.Decls {
+				switch d := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						if ts, ok := spec.(*ast.TypeSpec); ok {
+							if st, ok := ts.Type.(*ast.StructType); ok {
+								g.structs[ts.Name.Name] = st
+								g.imports[st] = imports
+							}
+						}
+					}
+				case *ast.FuncDecl:
+					if d.Recv != nil && len(d.Recv.List) == 1 {
+						recv := d.Recv.List[0].Type
+						if star, ok := recv.(*ast.StarExpr); ok {
+							recv = star.X
+						}
+						if ident, ok := recv.(*ast.Ident); ok {
+							g.methods[ident.Name] = append(g.methods[ident.Name], d.Name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseTemplates 解析内置模板和模板文件系统中的.sql文件
+func (g *MapperGenerator) parseTemplates() error {
+	g.template = template.New("sql").Funcs(sqlxx.MakeFuncMap(g.driver))
+	load := func(fsys fs.FS) error {
+		return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(name, ".sql") {
+				return err
+			}
+			buf, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			if _, err = g.template.New(name).Parse(string(buf)); err != nil {
+				return fmt.Errorf("%w: %v", ErrMapper, err)
+			}
+			return nil
+		})
+	}
+	for _, fsys := range append([]fs.FS{builtin.Builtin}, g.templateFS...) {
+		if err := load(fsys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modulePath 根据go.mod计算目录的导入路径
+func modulePath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(content), "\n") {
+				if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+					rel, err := filepath.Rel(root, abs)
+					if err != nil {
+						return "", err
+					}
+					return path.Join(fields[1], filepath.ToSlash(rel)), nil
+				}
+			}
+			return "", fmt.Errorf("gen: module path not found in %s", root)
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("gen: go.mod not found for %s", dir)
+		}
+	}
+}
+
+// mapperFunc 生成的函数字段
+type mapperFunc struct {
+	Field   string
+	Kind    string
+	Context bool
+	Method  string
+	//Result 结果类型(Go表达式)
+	Result string
+	//Elem 结果类型为指针时的元素类型
+	Elem     string
+	Template string
+	//Inline 内联SQL(需要在创建时解析为模板)
+	Inline string
+	//TxDef 事务定义(Go表达式)
+	TxDef string
+	Retry string
+}
+
+// mapperField 需要赋值的其他字段
+type mapperField struct {
+	Name string
+	//Kind DB/Factory/Struct
+	Kind string
+	Ds   string
+}
+
+type mapperModel struct {
+	Name   string
+	Fields []*mapperField
+	Funcs  []*mapperFunc
+}
+
+var mapperTemplate = template.Must(template.New("mapper").Funcs(template.FuncMap{"hasPrefix": strings.HasPrefix}).Parse(`// Code generated by sqlxx-mapper. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{range .Mappers}}{{$mapper := .}}
+// New{{.Name}} 创建{{.Name}},模板和结果类型在生成时检查(替代 {{$.Q}}BoostMapper)
+func New{{.Name}}(factory *{{$.Q}}Factory, ds string) (*{{.Name}}, error) {
+	db, err := factory.Get(ds)
+	if err != nil {
+		return nil, err
+	}
+	m := &{{.Name}}{}
+{{- range .Fields}}
+{{- if eq .Kind "DB"}}
+	m.{{.Name}} = db
+{{- else if eq .Kind "Factory"}}
+	m.{{.Name}} = factory
+{{- else}}
+	if err = {{$.Q}}BoostMapper(&m.{{.Name}}, factory, {{.Ds}}); err != nil {
+		return nil, err
+	}
+{{- end}}
+{{- end}}
+{{- range .Funcs}}
+{{- if .Inline}}
+	if _, err = db.ParseTemplate({{.Template}}, {{.Inline}}); err != nil {
+		return nil, err
+	}
+{{- end}}
+{{- if .TxDef}}
+{{- if .Retry}}
+	retry{{.Field}}, err := {{$.Q}}ParseRetryPolicy({{.Retry}})
+	if err != nil {
+		return nil, err
+	}
+{{- end}}
+	def{{.Field}} := {{.TxDef}}
+{{- end}}
+{{- if not .Context}}
+	ctx{{.Field}} := {{$.Q}}WithMethod(context.Background(), {{.Method}})
+{{- end}}
+{{- $ctx := printf "ctx%s" .Field}}{{if .Context}}{{$ctx = printf "%sWithMethod(ctx, %s)" $.Q .Method}}{{end}}
+{{- $params := "args ...any"}}{{if hasPrefix .Kind "Named"}}{{$params = "arg any"}}{{end}}{{if hasPrefix .Kind "Tx"}}{{$params = printf "fn func(*%sTx) error" $.Q}}{{end}}
+{{- if .Context}}{{$params = printf "ctx context.Context, %s" $params}}{{end}}
+{{- $args := "args..."}}{{if hasPrefix .Kind "Named"}}{{$args = "arg"}}{{end}}
+	m.{{.Field}} = func({{$params}}) {{if hasPrefix .Kind "Select"}}([]{{.Result}}, error){{else if hasPrefix .Kind "NamedSelect"}}([]{{.Result}}, error){{else if hasPrefix .Kind "Exec"}}(sql.Result, error){{else if hasPrefix .Kind "NamedExec"}}(sql.Result, error){{else if hasPrefix .Kind "Tx"}}error{{else}}({{.Result}}, error){{end}} {
+{{- if hasPrefix .Kind "Tx"}}
+		return db.BatchWith({{$ctx}}, def{{.Field}}, {{.Template}}, fn)
+{{- else if or (hasPrefix .Kind "Exec") (hasPrefix .Kind "NamedExec")}}
+		return db.{{if hasPrefix .Kind "Named"}}NamedExecxxContext{{else}}ExecxxContext{{end}}({{$ctx}}, {{.Template}}, {{$args}})
+{{- else if or (hasPrefix .Kind "Select") (hasPrefix .Kind "NamedSelect")}}
+		var result []{{.Result}}
+		err := db.{{if hasPrefix .Kind "Named"}}NamedSelectxxContext{{else}}SelectxxContext{{end}}({{$ctx}}, &result, {{.Template}}, {{$args}})
+		return result, err
+{{- else}}
+{{- if .Elem}}
+		result := new({{.Elem}})
+		err := db.{{if hasPrefix .Kind "Named"}}NamedGetxxContext{{else}}GetxxContext{{end}}({{$ctx}}, result, {{.Template}}, {{$args}})
+{{- else}}
+		var result {{.Result}}
+		err := db.{{if hasPrefix .Kind "Named"}}NamedGetxxContext{{else}}GetxxContext{{end}}({{$ctx}}, &result, {{.Template}}, {{$args}})
+{{- end}}
+		return result, err
+{{- end}}
+	}
+{{- end}}
+	return m, nil
+}
+{{end}}`))
+
+// Generate 生成mapper的静态实现(已格式化),所有检查错误合并返回
+func (g *MapperGenerator) Generate(names ...string) ([]byte, error) {
+	q := "sqlxx."
+	if g.pkgPath == sqlxxPath {
+		q = ""
+	}
+	imports := map[string]bool{}
+	var (
+		mappers []*mapperModel
+		errs    []error
+	)
+	for _, name := range names {
+		st, ok := g.structs[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: struct %s not found in %s", ErrMapper, name, g.dir))
+			continue
+		}
+		model, err := g.mapper(name, st, q, imports)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mappers = append(mappers, model)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	imports[strconv.Quote("context")] = true
+	if q != "" {
+		imports[strconv.Quote(sqlxxPath)] = true
+	}
+	var importList []string
+	for imp := range imports {
+		importList = append(importList, imp)
+	}
+	sort.Strings(importList)
+	var buf bytes.Buffer
+	if err := mapperTemplate.Execute(&buf, map[string]any{
+		"Package": g.pkg,
+		"Imports": importList,
+		"Mappers": mappers,
+		"Q":       q,
+	}); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: format source: %w\n%s", err, buf.String())
+	}
+	return code, nil
+}
+
+// mapper 解析mapper结构体的字段,检查模板
+func (g *MapperGenerator) mapper(name string, st *ast.StructType, q string, imports map[string]bool) (*mapperModel, error) {
+	model := &mapperModel{Name: name}
+	fileImports := g.imports[st]
+	var errs []error
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		fieldNames := field.Names
+		if len(fieldNames) == 0 {
+			fieldNames = []*ast.Ident{ast.NewIdent(embeddedName(field.Type))}
+		}
+		for _, ident := range fieldNames {
+			if !ident.IsExported() {
+				continue
+			}
+			kind, result := g.fieldKind(field.Type, fileImports)
+			switch kind {
+			case "":
+				continue
+			case "DB", "Factory":
+				model.Fields = append(model.Fields, &mapperField{Name: ident.Name, Kind: kind})
+				continue
+			case "Struct":
+				ds := "ds"
+				if v := tag.Get(sqlxx.TagDS); v != "" {
+					ds = strconv.Quote(v)
+				}
+				model.Fields = append(model.Fields, &mapperField{Name: ident.Name, Kind: kind, Ds: ds})
+				continue
+			}
+			fn, err := g.mapperFunc(name, ident.Name, kind, result, tag, q, fileImports, imports)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%w: %s.%s: %v", ErrMapper, name, ident.Name, err))
+				continue
+			}
+			model.Funcs = append(model.Funcs, fn)
+		}
+	}
+	return model, errors.Join(errs...)
+}
+
+// embeddedName 嵌入字段的名称
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// fieldKind 字段类型:DB、Factory、Struct(嵌套mapper)或函数类型名称,函数类型同时返回结果类型
+func (g *MapperGenerator) fieldKind(expr ast.Expr, fileImports map[string]string) (string, ast.Expr) {
+	// qualified 是否为sqlxx包中的名称
+	qualified := func(e ast.Expr) (string, bool) {
+		switch x := e.(type) {
+		case *ast.SelectorExpr:
+			pkg, ok := x.X.(*ast.Ident)
+			return x.Sel.Name, ok && fileImports[pkg.Name] == sqlxxPath
+		case *ast.Ident:
+			return x.Name, g.pkgPath == sqlxxPath
+		}
+		return "", false
+	}
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		if name, ok := qualified(e.X); ok && (name == "DB" || name == "Factory") {
+			return name, nil
+		}
+	case *ast.IndexExpr:
+		if name, ok := qualified(e.X); ok {
+			if _, known := funcKinds[name]; known {
+				return name, e.Index
+			}
+		}
+		// 泛型结构体(例如 BaseMapper[T])
+		return "Struct", nil
+	case *ast.SelectorExpr, *ast.Ident:
+		if name, ok := qualified(e); ok {
+			if _, known := funcKinds[name]; known {
+				return name, nil
+			}
+		}
+		if ident, ok := e.(*ast.Ident); ok && g.structs[ident.Name] != nil {
+			return "Struct", nil
+		}
+	}
+	return "", nil
+}
+
+// mapperFunc 解析函数字段,查找并检查模板
+func (g *MapperGenerator) mapperFunc(mapper, field, kind string, result ast.Expr, tag reflect.StructTag, q string,
+	fileImports map[string]string, imports map[string]bool) (*mapperFunc, error) {
+	method := mapper + "." + field
+	fn := &mapperFunc{Field: field, Kind: kind, Context: funcKinds[kind], Method: strconv.Quote(method)}
+	isTx := strings.HasPrefix(kind, "Tx")
+	if !isTx && !strings.Contains(kind, "Exec") {
+		if result == nil {
+			return nil, fmt.Errorf("missing result type")
+		}
+		fn.Result = types.ExprString(result)
+		if star, ok := result.(*ast.StarExpr); ok {
+			fn.Elem = types.ExprString(star.X)
+		}
+		ast.Inspect(result, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				if pkg, ok := sel.X.(*ast.Ident); ok && fileImports[pkg.Name] != "" {
+					imports[importSpec(pkg.Name, fileImports[pkg.Name])] = true
+				}
+			}
+			return true
+		})
+	}
+	if strings.Contains(kind, "Exec") {
+		imports[strconv.Quote("database/sql")] = true
+	}
+	sqlTpl := tag.Get(sqlxx.TagSQL)
+	typeDir := path.Join(utils.LowerCase(g.pkgPath), utils.LowerCase(mapper))
+	switch {
+	case sqlTpl != "" && !strings.HasSuffix(sqlTpl, ".sql"):
+		name := path.Join(typeDir, utils.LowerCase(field)+".sql")
+		if _, err := g.template.New(name).Parse(sqlTpl); err != nil {
+			return nil, err
+		}
+		fn.Template, fn.Inline = name, strconv.Quote(sqlTpl)
+	case sqlTpl != "":
+		if !isTx && g.template.Lookup(sqlTpl) == nil {
+			return nil, fmt.Errorf("template %s not found", sqlTpl)
+		}
+		fn.Template = sqlTpl
+	case isTx:
+		fn.Template = utils.LowerCase(field) + ".sql"
+	default:
+		sqlName := utils.LowerCase(field) + ".sql"
+		candidates := []string{
+			path.Join(typeDir, sqlName),
+			path.Join(path.Base(utils.LowerCase(g.pkgPath)), utils.LowerCase(mapper), sqlName),
+			path.Join(utils.LowerCase(mapper), sqlName),
+			sqlName,
+		}
+		for _, candidate := range candidates {
+			if g.template.Lookup(candidate) != nil {
+				fn.Template = candidate
+				break
+			}
+		}
+		if fn.Template == "" {
+			return nil, fmt.Errorf("template not found, candidates: %s", strings.Join(candidates, ", "))
+		}
+	}
+	if isTx {
+		def, retry, err := txDefinition(tag, q, field)
+		if err != nil {
+			return nil, err
+		}
+		fn.TxDef, fn.Retry = def, retry
+		if strings.Contains(def, "sql.") {
+			imports[strconv.Quote("database/sql")] = true
+		}
+	} else if err := g.check(g.template.Lookup(fn.Template), kind, result, tag.Get("arg")); err != nil {
+		return nil, err
+	}
+	fn.Template = strconv.Quote(fn.Template)
+	return fn, nil
+}
+
+// importSpec 导入语句(包名与路径最后一段不一致时使用别名)
+func importSpec(name, p string) string {
+	if path.Base(p) == name {
+		return strconv.Quote(p)
+	}
+	return name + " " + strconv.Quote(p)
+}
+
+// txDefinition 根据tx/readonly/propagation/retry标签生成事务定义
+func txDefinition(tag reflect.StructTag, q, field string) (string, string, error) {
+	var options []string
+	switch level := tag.Get(sqlxx.TagTx); level {
+	case sqlxx.TxReadUncommitted, sqlxx.TxReadCommitted, sqlxx.TxWriteCommitted, sqlxx.TxRepeatableRead,
+		sqlxx.TxSnapshot, sqlxx.TxSerializable, sqlxx.TxLinearizable:
+		options = append(options, "Isolation: sql.Level"+level)
+	}
+	if r := tag.Get(sqlxx.TagReadonly); r != "" && strings.ToLower(r) != "false" {
+		options = append(options, "ReadOnly: true")
+	}
+	fields := []string{"Options: &sql.TxOptions{" + strings.Join(options, ", ") + "}"}
+	if len(options) == 0 {
+		fields = nil
+	}
+	if p := sqlxx.ParsePropagation(tag.Get(sqlxx.TagPropagation)); p != sqlxx.PropagationRequired {
+		fields = append(fields, "Propagation: "+q+"Propagation"+p.String())
+	}
+	var retry string
+	if r, ok := tag.Lookup(sqlxx.TagRetry); ok {
+		if _, err := sqlxx.ParseRetryPolicy(r); err != nil {
+			return "", "", err
+		}
+		retry = strconv.Quote(r)
+		fields = append(fields, "Retry: retry"+field)
+	}
+	return q + "TxDefinition{" + strings.Join(fields, ", ") + "}", retry, nil
+}
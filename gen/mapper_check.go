@@ -0,0 +1,332 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/gnodux/sqlxx/utils"
+	"go/ast"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// actionMark 模板动作在SQL文本中的占位符
+const actionMark = "\x00"
+
+var (
+	quotedRegexp     = regexp.MustCompile(`'(?:[^']|'')*'`)
+	namedParamRegexp = regexp.MustCompile(`(^|[^:\w]):([A-Za-z_]\w*)`)
+)
+
+// check 检查模板:位置参数的模板不能引用参数字段;命名参数和模板字段必须存在于参数类型(arg标签);
+// 查询列必须存在于结果结构体
+func (g *MapperGenerator) check(tpl *template.Template, kind string, result ast.Expr, argType string) error {
+	if tpl == nil || tpl.Tree == nil {
+		return nil
+	}
+	named := strings.HasPrefix(kind, "Named")
+	var errs []error
+	text, refs := flatten(tpl.Tree.Root)
+	switch {
+	case !named && len(refs) > 0:
+		errs = append(errs, fmt.Errorf("template %s references .%s, but positional arguments are []any", tpl.Name(), refs[0]))
+	case named && argType != "":
+		st, ok := g.structs[argType]
+		if !ok {
+			return fmt.Errorf("arg type %s not found", argType)
+		}
+		columns, fields, open := g.structFields(argType, st)
+		for _, method := range g.methods[argType] {
+			fields[method] = true
+		}
+		if !open {
+			for _, ref := range refs {
+				if !fields[ref] {
+					errs = append(errs, fmt.Errorf("template %s references unknown field .%s of %s", tpl.Name(), ref, argType))
+				}
+			}
+			for _, param := range namedParams(text) {
+				if !columns[strings.ToLower(param)] {
+					errs = append(errs, fmt.Errorf("template %s uses unknown named parameter :%s of %s", tpl.Name(), param, argType))
+				}
+			}
+		}
+	}
+	if strings.Contains(kind, "Select") || strings.Contains(kind, "Get") {
+		errs = append(errs, g.checkColumns(tpl.Name(), text, result)...)
+	}
+	return errors.Join(errs...)
+}
+
+// checkColumns 检查查询列与结果结构体字段是否匹配(结果不是包中的结构体或查询列无法确定时不检查)
+func (g *MapperGenerator) checkColumns(name, text string, result ast.Expr) []error {
+	if star, ok := result.(*ast.StarExpr); ok {
+		result = star.X
+	}
+	ident, ok := result.(*ast.Ident)
+	if !ok || g.structs[ident.Name] == nil {
+		return nil
+	}
+	columns, _, open := g.structFields(ident.Name, g.structs[ident.Name])
+	selected, ok := selectColumns(text)
+	if open || !ok {
+		return nil
+	}
+	var errs []error
+	for _, col := range selected {
+		if col == "" {
+			errs = append(errs, fmt.Errorf("template %s selects an expression without alias", name))
+		} else if !columns[strings.ToLower(col)] {
+			errs = append(errs, fmt.Errorf("template %s selects column %s, but %s has no matching field", name, col, ident.Name))
+		}
+	}
+	return errs
+}
+
+// structFields 结构体的列名(按照sqlx的映射规则)和字段名,嵌入了其他包的结构体时open为true
+func (g *MapperGenerator) structFields(name string, st *ast.StructType) (columns, fields map[string]bool, open bool) {
+	columns, fields = map[string]bool{}, map[string]bool{}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			typ := field.Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			ident, ok := typ.(*ast.Ident)
+			if !ok || g.structs[ident.Name] == nil || ident.Name == name {
+				open = true
+				continue
+			}
+			c, f, o := g.structFields(ident.Name, g.structs[ident.Name])
+			for k := range c {
+				columns[k] = true
+			}
+			for k := range f {
+				fields[k] = true
+			}
+			fields[ident.Name], open = true, open || o
+			continue
+		}
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		if prefix, ok := embeddedPrefix(tag); ok {
+			typ := field.Type
+			if star, isStar := typ.(*ast.StarExpr); isStar {
+				typ = star.X
+			}
+			if ident, local := typ.(*ast.Ident); local && g.structs[ident.Name] != nil && ident.Name != name {
+				c, _, o := g.structFields(ident.Name, g.structs[ident.Name])
+				for _, fieldName := range field.Names {
+					fields[fieldName.Name] = true
+					for k := range c {
+						columns[strings.ToLower(prefix)+k] = true
+						columns[strings.ToLower(utils.LowerCase(fieldName.Name))+"."+k] = true
+					}
+				}
+				open = open || o
+			} else {
+				open = true
+			}
+			continue
+		}
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fields[ident.Name] = true
+			column := strings.Split(tag.Get("db"), ",")[0]
+			if column == "-" {
+				continue
+			}
+			if column == "" {
+				column = utils.LowerCase(ident.Name)
+			}
+			columns[strings.ToLower(column)] = true
+		}
+	}
+	return
+}
+
+// embeddedPrefix 结构体字段是否展开为多个列(dbx:"embedded"或dbx:"prefix:xxx")及列名前缀
+func embeddedPrefix(tag reflect.StructTag) (prefix string, ok bool) {
+	for _, item := range strings.Split(tag.Get(meta.TagField), ",") {
+		if item == meta.MarkEmbedded {
+			ok = true
+		} else if value, found := strings.CutPrefix(item, meta.MarkPrefix+":"); found {
+			prefix, ok = value, true
+		}
+	}
+	return
+}
+
+// flatten 模板的SQL文本(动作替换为占位符,包含所有分支)和引用的顶层参数字段
+func flatten(node parse.Node) (string, []string) {
+	var (
+		sb   strings.Builder
+		refs []string
+		seen = map[string]bool{}
+	)
+	addRefs := func(pipe *parse.PipeNode) {
+		for _, ref := range pipeFields(pipe) {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	var walk func(node parse.Node, topLevel bool)
+	walk = func(node parse.Node, topLevel bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, topLevel)
+			}
+		case *parse.TextNode:
+			sb.Write(n.Text)
+		case *parse.ActionNode:
+			sb.WriteString(actionMark)
+			if topLevel {
+				addRefs(n.Pipe)
+			}
+		case *parse.IfNode:
+			sb.WriteString(actionMark)
+			if topLevel {
+				addRefs(n.Pipe)
+			}
+			walk(n.List, topLevel)
+			walk(n.ElseList, topLevel)
+		case *parse.RangeNode:
+			sb.WriteString(actionMark)
+			if topLevel {
+				addRefs(n.Pipe)
+			}
+			walk(n.List, false)
+			walk(n.ElseList, topLevel)
+		case *parse.WithNode:
+			sb.WriteString(actionMark)
+			if topLevel {
+				addRefs(n.Pipe)
+			}
+			walk(n.List, false)
+			walk(n.ElseList, topLevel)
+		case *parse.TemplateNode:
+			sb.WriteString(actionMark)
+		}
+	}
+	walk(node, true)
+	return sb.String(), refs
+}
+
+// pipeFields 管道中引用的参数字段(.Field 或 $.Field)
+func pipeFields(pipe *parse.PipeNode) []string {
+	if pipe == nil {
+		return nil
+	}
+	var fields []string
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				fields = append(fields, a.Ident[0])
+			case *parse.VariableNode:
+				if a.Ident[0] == "$" && len(a.Ident) > 1 {
+					fields = append(fields, a.Ident[1])
+				}
+			case *parse.PipeNode:
+				fields = append(fields, pipeFields(a)...)
+			}
+		}
+	}
+	return fields
+}
+
+// namedParams SQL中的命名参数(忽略字符串中的内容和::类型转换)
+func namedParams(text string) []string {
+	text = quotedRegexp.ReplaceAllString(stripComments(text), "''")
+	var params []string
+	for _, m := range namedParamRegexp.FindAllStringSubmatch(text, -1) {
+		params = append(params, m[2])
+	}
+	return params
+}
+
+// selectColumns 查询语句的列名,无法确定时(SELECT *、包含模板动作、不是查询语句)ok为false;
+// 没有别名的表达式列名为空
+func selectColumns(text string) (columns []string, ok bool) {
+	text = strings.TrimSpace(stripComments(text))
+	if len(text) < 6 || !strings.EqualFold(text[:6], "SELECT") {
+		return nil, false
+	}
+	body := text[6:]
+	if end := topLevelKeyword(body, "FROM"); end >= 0 {
+		body = body[:end]
+	}
+	if strings.Contains(body, actionMark) {
+		return nil, false
+	}
+	for _, item := range splitTopLevel(body) {
+		tokens := strings.Fields(item)
+		if len(tokens) > 0 && isWord(tokens[0], "DISTINCT", "ALL") {
+			tokens = tokens[1:]
+		}
+		switch {
+		case len(tokens) == 0:
+			continue
+		case len(tokens) == 1 && (tokens[0] == "*" || strings.HasSuffix(tokens[0], ".*")):
+			return nil, false
+		case len(tokens) >= 3 && isWord(tokens[len(tokens)-2], "AS"):
+			columns = append(columns, unquote(tokens[len(tokens)-1]))
+		case len(tokens) == 1 && !strings.ContainsAny(tokens[0], "()+-*/'"):
+			columns = append(columns, unquote(tokens[0]))
+		case len(tokens) == 2 && !strings.ContainsAny(tokens[1], "()+-*/'"):
+			columns = append(columns, unquote(tokens[1]))
+		default:
+			columns = append(columns, "")
+		}
+	}
+	return columns, true
+}
+
+// topLevelKeyword 查找不在括号和字符串中的关键字位置
+func topLevelKeyword(s, keyword string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case closingQuote(c) != 0:
+			quote = closingQuote(c)
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && i+len(keyword) <= len(s) && strings.EqualFold(s[i:i+len(keyword)], keyword) &&
+			(i == 0 || !isIdentChar(s[i-1])) && (i+len(keyword) == len(s) || !isIdentChar(s[i+len(keyword)])):
+			return i
+		}
+	}
+	return -1
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
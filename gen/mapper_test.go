@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package gen
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMapperGenerator_Example(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	g, err := NewMapperGenerator(dir, WithTemplateFS(os.DirFS(filepath.Join(dir, "sql"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := g.Generate("AccountMapper")
+	assert.NoError(t, err)
+	want, err := os.ReadFile(filepath.Join(dir, "account_mapper_gen.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(code), "run go generate ./gen/internal/example")
+}
+
+func TestMapperGenerator_Check(t *testing.T) {
+	source := `package demo
+
+import "github.com/gnodux/sqlxx"
+
+type User struct {
+	ID   int64
+	Name string
+	Nick string ` + "`db:\"nick_name\"`" + `
+	Home *Address ` + "`dbx:\"prefix:home_\"`" + `
+}
+
+type Address struct {
+	City string
+}
+
+type Query struct {
+	Name string
+}
+
+func (Query) Pattern() string { return "" }
+
+type Mapper struct {
+	Fn sqlxx.%s
+}
+`
+	templates := fstest.MapFS{
+		"user/ok.sql":         {Data: []byte("SELECT id, name, nick_name FROM user WHERE name = :name {{if .Pattern}}AND 1 = 1{{end}}")},
+		"user/star.sql":       {Data: []byte("SELECT * FROM user WHERE id = ?")},
+		"user/unknown.sql":    {Data: []byte("SELECT id, name AS title FROM user")},
+		"user/expr.sql":       {Data: []byte("SELECT id, COUNT(1) FROM user GROUP BY id")},
+		"user/param.sql":      {Data: []byte("SELECT id FROM user WHERE name = :nick AND created_at > '12:00' AND id = :id::int")},
+		"user/field.sql":      {Data: []byte("SELECT id FROM user WHERE name = {{v .Title}}")},
+		"user/positional.sql": {Data: []byte("SELECT id FROM user WHERE name = {{v .Name}}")},
+		"user/broken.sql":     {Data: []byte("SELECT id FROM user")},
+		"demo/mapper/fn.sql":  {Data: []byte("SELECT name FROM user")},
+		"user/home.sql":       {Data: []byte("SELECT id, home_city FROM user")},
+		"user/home_bad.sql":   {Data: []byte("SELECT id, home FROM user")},
+	}
+	tests := []struct {
+		name  string
+		field string
+		want  []string
+	}{
+		{"ok", "NamedSelectFunc[User] `sql:\"user/ok.sql\" arg:\"Query\"`", nil},
+		{"select star", "GetFunc[*User] `sql:\"user/star.sql\"`", nil},
+		{"scalar", "GetFunc[int] `sql:\"user/expr.sql\"`", nil},
+		{"by name", "SelectFunc[User]", nil},
+		{"inline", "SelectContextFunc[User] `sql:\"SELECT id, name FROM user WHERE id = ?\"`", nil},
+		{"missing template", "SelectFunc[User] `sql:\"user/missing.sql\"`", []string{"template user/missing.sql not found"}},
+		{"unknown column", "SelectFunc[User] `sql:\"user/unknown.sql\"`", []string{"selects column title, but User has no matching field"}},
+		{"expression", "SelectFunc[User] `sql:\"user/expr.sql\"`", []string{"selects an expression without alias"}},
+		{"named param", "NamedSelectFunc[User] `sql:\"user/param.sql\" arg:\"Query\"`",
+			[]string{"unknown named parameter :nick", "unknown named parameter :id"}},
+		{"template field", "NamedSelectFunc[User] `sql:\"user/field.sql\" arg:\"Query\"`", []string{"unknown field .Title"}},
+		{"positional field", "SelectFunc[User] `sql:\"user/positional.sql\"`", []string{"references .Name, but positional arguments are []any"}},
+		{"arg type", "NamedExecFunc `sql:\"user/broken.sql\" arg:\"Missing\"`", []string{"arg type Missing not found"}},
+		{"embedded", "SelectFunc[User] `sql:\"user/home.sql\"`", nil},
+		{"embedded column", "SelectFunc[User] `sql:\"user/home_bad.sql\"`", []string{"selects column home, but User has no matching field"}},
+		{"inline parse", "ExecFunc `sql:\"UPDATE user SET {{.Name\"`", []string{"unclosed action"}},
+		{"retry", "TxFunc `retry:\"x\"`", []string{"Mapper.Fn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "demo.go"), []byte(fmt.Sprintf(source, tt.field)), 0644))
+			g, err := NewMapperGenerator(dir, WithPkgPath("example.com/demo"), WithTemplateFS(templates))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = g.Generate("Mapper")
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.ErrorIs(t, err, ErrMapper) {
+				for _, want := range tt.want {
+					assert.Contains(t, err.Error(), want)
+				}
+			}
+		})
+	}
+	t.Run("missing mapper", func(t *testing.T) {
+		g, err := NewMapperGenerator(filepath.Join("internal", "example"))
+		assert.NoError(t, err)
+		_, err = g.Generate("Missing")
+		assert.ErrorIs(t, err, ErrMapper)
+	})
+}
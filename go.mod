@@ -1,6 +1,6 @@
 module github.com/gnodux/sqlxx
 
-go 1.20
+go 1.21
 
 require (
 	github.com/cookieY/sqlx v1.3.0
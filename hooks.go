@@ -5,7 +5,24 @@
 
 package sqlxx
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
+
+// HookOp 触发实体钩子的操作
+type HookOp string
+
+const (
+	// HookInsert 插入(Create/Insert)
+	HookInsert HookOp = "insert"
+	// HookUpdate 更新(Update/PartialUpdate/UpdateByExample)
+	HookUpdate HookOp = "update"
+	// HookDelete 删除(DeleteById/EraseById/DeleteByExample)
+	HookDelete HookOp = "delete"
+	// HookFind 查询(Select/SelectByExample/ListById)
+	HookFind HookOp = "find"
+)
 
 type BeforeUpdate interface {
 	BeforeUpdate() error
@@ -26,62 +43,258 @@ type AfterDelete interface {
 	AfterDelete() error
 }
 
-func EvalBeforeHooks[T any](entities ...T) error {
-	for idx, _ := range entities {
-		if reflect.TypeOf(entities[idx]).Kind() == reflect.Ptr {
-			if err := EvalBeforeHook(entities[idx]); err != nil {
-				return err
-			}
-		} else {
-			if err := EvalBeforeHook(&entities[idx]); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+// BeforeInsertCtx 插入前执行,tx为执行插入的事务,可以在同一事务中查询
+type BeforeInsertCtx interface {
+	BeforeInsertCtx(ctx context.Context, tx *Tx) error
+}
+type AfterInsertCtx interface {
+	AfterInsertCtx(ctx context.Context, tx *Tx) error
+}
+type BeforeUpdateCtx interface {
+	BeforeUpdateCtx(ctx context.Context, tx *Tx) error
+}
+type AfterUpdateCtx interface {
+	AfterUpdateCtx(ctx context.Context, tx *Tx) error
 }
 
-// EvalBeforeHook eval before hook
+// BeforeDeleteCtx 删除前执行,DeleteById/EraseById 时实体只包含主键和租户ID
+type BeforeDeleteCtx interface {
+	BeforeDeleteCtx(ctx context.Context, tx *Tx) error
+}
+type AfterDeleteCtx interface {
+	AfterDeleteCtx(ctx context.Context, tx *Tx) error
+}
+
+// BeforeSave 插入和更新前执行(先于 BeforeInsert/BeforeUpdate 系列钩子)
+type BeforeSave interface {
+	BeforeSave(ctx context.Context, tx *Tx) error
+}
+
+// AfterFind 查询后对每个实体执行,非事务查询时tx为nil
+type AfterFind interface {
+	AfterFind(ctx context.Context, tx *Tx) error
+}
+
+// AfterCommitHook 实体实现该接口且返回true时，在事务中执行的After*钩子延迟到事务提交后执行(事务回滚则不执行)
+type AfterCommitHook interface {
+	AfterCommit() bool
+}
+
+// EvalBeforeHooks 对每个实体执行操作对应的前置钩子,参考 EvalBeforeHook
+func EvalBeforeHooks[T any](op HookOp, entities ...T) error {
+	return runBeforeHooks(context.Background(), nil, op, entities)
+}
+
+// EvalBeforeHook 执行操作对应的前置钩子
 // 如果entity是nil，那么不会调用任何hook
 // 如果entity是struct 而非指针，那么在调用EvalBeforeHook之前，需要先将entity转换为指针
-func EvalBeforeHook(entity any) error {
-	switch e := entity.(type) {
-	case nil:
+//
+// 实体可以同时实现多个钩子,只有与op对应的钩子会被调用(如 HookInsert 只调用 BeforeSave/BeforeInsertCtx/BeforeInsert)，
+// 上下文钩子使用 context.Background() 且tx为nil
+func EvalBeforeHook(op HookOp, entity any) error {
+	if entity == nil {
+		return nil
+	}
+	return evalBeforeHook(context.Background(), nil, op, entity)
+}
+
+// EvalAfterHooks 对每个实体执行操作对应的后置钩子,参考 EvalAfterHook
+func EvalAfterHooks[T any](op HookOp, entities ...T) error {
+	return runAfterHooks(context.Background(), nil, op, entities)
+}
+
+// EvalAfterHook 执行操作对应的后置钩子(HookFind 对应 AfterFind)
+func EvalAfterHook(op HookOp, entity any) error {
+	if entity == nil {
 		return nil
-	case BeforeUpdate:
-		return e.BeforeUpdate()
-	case BeforeInsert:
-		return e.BeforeInsert()
-	case BeforeDelete:
-		return e.BeforeDelete()
+	}
+	return evalAfterHook(context.Background(), nil, op, entity)
+}
+
+// EvalAfterHooksTx 在事务中对每个实体执行后置钩子,参考 EvalAfterHookTx
+func EvalAfterHooksTx[T any](tx *Tx, op HookOp, entities ...T) error {
+	ctx := context.Background()
+	if tx != nil {
+		ctx = tx.Context()
+	}
+	return runAfterHooks(ctx, tx, op, entities)
+}
+
+// EvalAfterHookTx 在事务中执行后置钩子
+//
+// tx不为空且实体实现了 AfterCommitHook 时，钩子延迟到事务提交后执行，钩子返回的错误只记录日志
+func EvalAfterHookTx(tx *Tx, op HookOp, entity any) error {
+	if entity == nil {
+		return nil
+	}
+	return EvalAfterHooksTx(tx, op, entity)
+}
+
+// hookTypes 各操作对应的钩子接口
+var hookTypes = map[HookOp][]reflect.Type{
+	HookInsert: {
+		reflect.TypeOf((*BeforeSave)(nil)).Elem(),
+		reflect.TypeOf((*BeforeInsertCtx)(nil)).Elem(),
+		reflect.TypeOf((*BeforeInsert)(nil)).Elem(),
+		reflect.TypeOf((*AfterInsertCtx)(nil)).Elem(),
+		reflect.TypeOf((*AfterInsert)(nil)).Elem(),
+	},
+	HookUpdate: {
+		reflect.TypeOf((*BeforeSave)(nil)).Elem(),
+		reflect.TypeOf((*BeforeUpdateCtx)(nil)).Elem(),
+		reflect.TypeOf((*BeforeUpdate)(nil)).Elem(),
+		reflect.TypeOf((*AfterUpdateCtx)(nil)).Elem(),
+		reflect.TypeOf((*AfterUpdate)(nil)).Elem(),
+	},
+	HookDelete: {
+		reflect.TypeOf((*BeforeDeleteCtx)(nil)).Elem(),
+		reflect.TypeOf((*BeforeDelete)(nil)).Elem(),
+		reflect.TypeOf((*AfterDeleteCtx)(nil)).Elem(),
+		reflect.TypeOf((*AfterDelete)(nil)).Elem(),
+	},
+	HookFind: {
+		reflect.TypeOf((*AfterFind)(nil)).Elem(),
+	},
+}
+
+// hasHooks 判断实体类型是否实现了操作对应的钩子
+func hasHooks[T any](op HookOp) bool {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Pointer {
+		t = reflect.PointerTo(t)
+	}
+	for _, it := range hookTypes[op] {
+		if t.Implements(it) {
+			return true
+		}
+	}
+	return false
+}
+
+// hookTarget 获取调用钩子的实体(非指针实体取地址,使钩子可以修改实体)
+func hookTarget[T any](entities []T, idx int) any {
+	switch reflect.TypeOf((*T)(nil)).Elem().Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return entities[idx]
+	}
+	return &entities[idx]
+}
+
+// runBeforeHooks 执行操作对应的前置监听器和钩子,顺序为 chain中的监听器、BeforeSave、XxxCtx、无参数钩子
+func runBeforeHooks[T any](ctx context.Context, tx *Tx, op HookOp, entities []T, chain ...*listeners) error {
+	for idx := range entities {
+		entity := hookTarget(entities, idx)
+		for _, l := range chain {
+			for _, fn := range l.get(false, op) {
+				if err := fn(ctx, entity); err != nil {
+					return err
+				}
+			}
+		}
+		if err := evalBeforeHook(ctx, tx, op, entity); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func EvalAfterHooks[T any](entities ...T) error {
-	for idx, _ := range entities {
-		if reflect.TypeOf(entities[idx]).Kind() == reflect.Ptr {
-			if err := EvalAfterHook(entities[idx]); err != nil {
-				return err
+func evalBeforeHook(ctx context.Context, tx *Tx, op HookOp, entity any) (err error) {
+	if h, ok := entity.(BeforeSave); ok && (op == HookInsert || op == HookUpdate) {
+		if err = h.BeforeSave(ctx, tx); err != nil {
+			return
+		}
+	}
+	switch op {
+	case HookInsert:
+		if h, ok := entity.(BeforeInsertCtx); ok {
+			if err = h.BeforeInsertCtx(ctx, tx); err != nil {
+				return
+			}
+		}
+		if h, ok := entity.(BeforeInsert); ok {
+			err = h.BeforeInsert()
+		}
+	case HookUpdate:
+		if h, ok := entity.(BeforeUpdateCtx); ok {
+			if err = h.BeforeUpdateCtx(ctx, tx); err != nil {
+				return
+			}
+		}
+		if h, ok := entity.(BeforeUpdate); ok {
+			err = h.BeforeUpdate()
+		}
+	case HookDelete:
+		if h, ok := entity.(BeforeDeleteCtx); ok {
+			if err = h.BeforeDeleteCtx(ctx, tx); err != nil {
+				return
 			}
-		} else {
-			if err := EvalAfterHook(&entities[idx]); err != nil {
-				return err
+		}
+		if h, ok := entity.(BeforeDelete); ok {
+			err = h.BeforeDelete()
+		}
+	}
+	return
+}
+
+// runAfterHooks 执行操作对应的后置钩子和监听器,顺序为 XxxCtx、无参数钩子、chain中的监听器(逆序)
+//
+// 实体实现 AfterCommitHook 时钩子延迟到事务提交后执行(此时tx为nil)，错误只记录日志；监听器不会延迟
+func runAfterHooks[T any](ctx context.Context, tx *Tx, op HookOp, entities []T, chain ...*listeners) error {
+	for idx := range entities {
+		entity := hookTarget(entities, idx)
+		if h, ok := entity.(AfterCommitHook); ok && tx != nil && h.AfterCommit() {
+			tx.OnCommit(func() {
+				if err := evalAfterHook(ctx, nil, op, entity); err != nil {
+					tx.db.Logger().Error("after commit hook failed: ", err)
+				}
+			})
+		} else if err := evalAfterHook(ctx, tx, op, entity); err != nil {
+			return err
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			for _, fn := range chain[i].get(true, op) {
+				if err := fn(ctx, entity); err != nil {
+					return err
+				}
 			}
 		}
 	}
 	return nil
 }
-func EvalAfterHook(entity any) error {
-	switch e := entity.(type) {
-	case nil:
-		return nil
-	case AfterUpdate:
-		return e.AfterUpdate()
-	case AfterInsert:
-		return e.AfterInsert()
-	case AfterDelete:
-		return e.AfterDelete()
+
+func evalAfterHook(ctx context.Context, tx *Tx, op HookOp, entity any) (err error) {
+	switch op {
+	case HookInsert:
+		if h, ok := entity.(AfterInsertCtx); ok {
+			if err = h.AfterInsertCtx(ctx, tx); err != nil {
+				return
+			}
+		}
+		if h, ok := entity.(AfterInsert); ok {
+			err = h.AfterInsert()
+		}
+	case HookUpdate:
+		if h, ok := entity.(AfterUpdateCtx); ok {
+			if err = h.AfterUpdateCtx(ctx, tx); err != nil {
+				return
+			}
+		}
+		if h, ok := entity.(AfterUpdate); ok {
+			err = h.AfterUpdate()
+		}
+	case HookDelete:
+		if h, ok := entity.(AfterDeleteCtx); ok {
+			if err = h.AfterDeleteCtx(ctx, tx); err != nil {
+				return
+			}
+		}
+		if h, ok := entity.(AfterDelete); ok {
+			err = h.AfterDelete()
+		}
+	case HookFind:
+		if h, ok := entity.(AfterFind); ok {
+			err = h.AfterFind(ctx, tx)
+		}
 	}
-	return nil
+	return
 }
@@ -5,7 +5,12 @@
 
 package sqlxx
 
-import "testing"
+import (
+	"context"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
 
 type HookedUser struct {
 	ID   int64  `db:"id"`
@@ -28,7 +33,7 @@ func TestEvalAfterHooks(t *testing.T) {
 			Name: "gnodux2",
 		},
 	}
-	if err := EvalBeforeHooks(users...); err != nil {
+	if err := EvalBeforeHooks(HookInsert, users...); err != nil {
 		t.Error(err)
 	}
 	encoder.Encode(users)
@@ -42,8 +47,205 @@ func TestEvalAfterHooks(t *testing.T) {
 			Name: "gnodux2",
 		},
 	}
-	if err := EvalBeforeHooks(userss...); err != nil {
+	if err := EvalBeforeHooks(HookInsert, userss...); err != nil {
 		t.Error(err)
 	}
 	encoder.Encode(userss)
 }
+
+// CtxHookedRole 实现了上下文钩子的角色
+type CtxHookedRole struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Desc      string `json:"desc"`
+	IsDeleted bool   `json:"is_deleted"`
+
+	events []string
+}
+
+func (r *CtxHookedRole) TableName() string {
+	return "role"
+}
+
+func (r *CtxHookedRole) record(event string, ctx context.Context, tx *Tx) {
+	if tx != nil && TxFrom(ctx) == tx {
+		event += "(tx)"
+	}
+	r.events = append(r.events, event)
+}
+
+func (r *CtxHookedRole) BeforeSave(ctx context.Context, tx *Tx) error {
+	if r.Desc == "" {
+		r.Desc = "saved by hook"
+	}
+	r.record("BeforeSave", ctx, tx)
+	return nil
+}
+func (r *CtxHookedRole) BeforeInsertCtx(ctx context.Context, tx *Tx) error {
+	r.record("BeforeInsertCtx", ctx, tx)
+	return nil
+}
+func (r *CtxHookedRole) AfterInsertCtx(ctx context.Context, tx *Tx) error {
+	r.record("AfterInsertCtx", ctx, tx)
+	return nil
+}
+func (r *CtxHookedRole) BeforeUpdateCtx(ctx context.Context, tx *Tx) error {
+	r.record("BeforeUpdateCtx", ctx, tx)
+	return nil
+}
+func (r *CtxHookedRole) BeforeDeleteCtx(ctx context.Context, tx *Tx) error {
+	r.record("BeforeDeleteCtx", ctx, tx)
+	deletedRoles = append(deletedRoles, r.ID)
+	return nil
+}
+func (r *CtxHookedRole) AfterFind(ctx context.Context, tx *Tx) error {
+	r.record("AfterFind", ctx, tx)
+	return nil
+}
+
+var deletedRoles []int64
+
+func TestBaseMapper_CtxHooks(t *testing.T) {
+	mapper, err := NewMapper[BaseMapper[*CtxHookedRole]](DefaultName)
+	assert.NoError(t, err)
+	role := &CtxHookedRole{Name: "hooked"}
+	assert.NoError(t, mapper.Create(role))
+	assert.Equal(t, "saved by hook", role.Desc)
+	assert.Equal(t, []string{"BeforeSave(tx)", "BeforeInsertCtx(tx)", "AfterInsertCtx(tx)"}, role.events)
+
+	role.events = nil
+	assert.NoError(t, mapper.PartialUpdate(false, []string{"Desc"}, role))
+	assert.Equal(t, []string{"BeforeSave(tx)", "BeforeUpdateCtx(tx)"}, role.events)
+
+	found, err := mapper.ListById(nil, role.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, []string{"AfterFind"}, found[0].events)
+	}
+	err = mapper.Batch(context.Background(), nil, func(tx *Tx) error {
+		selected, _, err := mapper.SelectContext(tx.Context(), expr.UseCondition(expr.Eq(mapper.Column("ID"), role.ID)))
+		if assert.Len(t, selected, 1) {
+			assert.Equal(t, []string{"AfterFind(tx)"}, selected[0].events)
+		}
+		return err
+	})
+	assert.NoError(t, err)
+
+	deletedRoles = nil
+	assert.NoError(t, mapper.EraseById(nil, role.ID))
+	assert.Equal(t, []int64{role.ID}, deletedRoles)
+}
+
+// AllHookedRole 实现了全部六个钩子的角色
+type AllHookedRole struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Desc      string `json:"desc"`
+	IsDeleted bool   `json:"is_deleted"`
+}
+
+var hookEvents []string
+
+func (r *AllHookedRole) TableName() string { return "role" }
+func (r *AllHookedRole) BeforeInsert() error {
+	hookEvents = append(hookEvents, "BeforeInsert")
+	return nil
+}
+func (r *AllHookedRole) AfterInsert() error {
+	hookEvents = append(hookEvents, "AfterInsert")
+	return nil
+}
+func (r *AllHookedRole) BeforeUpdate() error {
+	hookEvents = append(hookEvents, "BeforeUpdate")
+	return nil
+}
+func (r *AllHookedRole) AfterUpdate() error {
+	hookEvents = append(hookEvents, "AfterUpdate")
+	return nil
+}
+func (r *AllHookedRole) BeforeDelete() error {
+	hookEvents = append(hookEvents, "BeforeDelete")
+	return nil
+}
+func (r *AllHookedRole) AfterDelete() error {
+	hookEvents = append(hookEvents, "AfterDelete")
+	return nil
+}
+
+func TestEvalHook_Dispatch(t *testing.T) {
+	tests := []struct {
+		op         HookOp
+		wantEvents []string
+	}{
+		{HookInsert, []string{"BeforeInsert", "AfterInsert"}},
+		{HookUpdate, []string{"BeforeUpdate", "AfterUpdate"}},
+		{HookDelete, []string{"BeforeDelete", "AfterDelete"}},
+		{HookFind, nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.op), func(t *testing.T) {
+			hookEvents = nil
+			role := &AllHookedRole{}
+			assert.NoError(t, EvalBeforeHook(tt.op, role))
+			assert.NoError(t, EvalAfterHook(tt.op, role))
+			assert.Equal(t, tt.wantEvents, hookEvents)
+		})
+	}
+	hookEvents = nil
+	assert.NoError(t, EvalBeforeHook(HookInsert, nil))
+	assert.NoError(t, EvalBeforeHooks(HookUpdate, AllHookedRole{}, AllHookedRole{}))
+	assert.Equal(t, []string{"BeforeUpdate", "BeforeUpdate"}, hookEvents)
+}
+
+func TestBaseMapper_HookDispatch(t *testing.T) {
+	mapper, err := NewMapper[BaseMapper[*AllHookedRole]](DefaultName)
+	assert.NoError(t, err)
+	newRole := func() *AllHookedRole {
+		role := &AllHookedRole{Name: "all hooks", Desc: "dispatch"}
+		assert.NoError(t, mapper.Create(role))
+		hookEvents = nil
+		return role
+	}
+	inserted := []string{"BeforeInsert", "AfterInsert"}
+	updated := []string{"BeforeUpdate", "AfterUpdate"}
+	deleted := []string{"BeforeDelete", "AfterDelete"}
+	tests := []struct {
+		name string
+		fn   func(role *AllHookedRole) error
+		want []string
+	}{
+		{"Create", func(role *AllHookedRole) error {
+			return mapper.Create(&AllHookedRole{Name: "created", Desc: "dispatch"})
+		}, inserted},
+		{"Insert", func(role *AllHookedRole) error {
+			return mapper.Insert(&AllHookedRole{Name: "inserted", Desc: "dispatch"})
+		}, inserted},
+		{"Update", func(role *AllHookedRole) error {
+			return mapper.Update(false, role)
+		}, updated},
+		{"PartialUpdate", func(role *AllHookedRole) error {
+			return mapper.PartialUpdate(false, []string{"Desc"}, role)
+		}, updated},
+		{"UpdateByExample", func(role *AllHookedRole) error {
+			_, err := mapper.UpdateByExample(&AllHookedRole{Desc: "updated"}, &AllHookedRole{ID: role.ID})
+			return err
+		}, updated},
+		{"DeleteById", func(role *AllHookedRole) error {
+			return mapper.DeleteById(nil, role.ID)
+		}, deleted},
+		{"EraseById", func(role *AllHookedRole) error {
+			return mapper.EraseById(nil, role.ID)
+		}, deleted},
+		{"DeleteByExample", func(role *AllHookedRole) error {
+			_, err := mapper.DeleteByExample(&AllHookedRole{ID: role.ID})
+			return err
+		}, deleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := newRole()
+			assert.NoError(t, tt.fn(role))
+			assert.Equal(t, tt.want, hookEvents)
+		})
+	}
+}
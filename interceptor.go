@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// OpSelect 查询多条记录
+	OpSelect = "select"
+	// OpGet 查询单条记录
+	OpGet = "get"
+	// OpExec 执行语句
+	OpExec = "exec"
+	// OpQuery 查询并返回游标
+	OpQuery = "query"
+	// OpPrepare 预编译语句(RunPrepared等方法中包含了语句的执行过程)
+	OpPrepare = "prepare"
+)
+
+// Statement 一次SQL执行的信息，在拦截器之间传递
+//
+// Before 中可以修改 Query/Args 实现SQL改写，After 中可以读取执行结果
+type Statement struct {
+	//Op 操作类型(OpSelect/OpGet/OpExec/OpQuery/OpPrepare)
+	Op string
+	//Query 渲染后的SQL
+	Query string
+	//Args 参数,位置参数为[]any,命名参数为map或struct
+	Args any
+	//Template 模版名称(非模版SQL为空)
+	Template string
+	//Method 发起调用的mapper方法(如 UserMapper.ListAll),参考 WithMethod
+	Method string
+	//DB 执行语句的数据库
+	DB *DB
+	//Tx 当前事务(非事务执行为nil)
+	Tx *Tx
+	//Start 开始时间
+	Start time.Time
+	//Duration 执行耗时
+	Duration time.Duration
+	//RowsAffected 影响(或返回)的行数,未知时为-1
+	RowsAffected int64
+	//Err 执行错误
+	Err error
+}
+
+// Interceptor 语句拦截器,用于日志、追踪、指标、慢查询、SQL改写等
+//
+// Before 返回错误时语句不会被执行，且只有Before成功的拦截器会被调用After(按注册的相反顺序)
+type Interceptor interface {
+	Before(ctx context.Context, stmt *Statement) (context.Context, error)
+	After(ctx context.Context, stmt *Statement)
+}
+
+// InterceptorFuncs 使用函数实现的拦截器，未设置的函数将被忽略
+type InterceptorFuncs struct {
+	BeforeFunc func(ctx context.Context, stmt *Statement) (context.Context, error)
+	AfterFunc  func(ctx context.Context, stmt *Statement)
+}
+
+func (i *InterceptorFuncs) Before(ctx context.Context, stmt *Statement) (context.Context, error) {
+	if i.BeforeFunc == nil {
+		return ctx, nil
+	}
+	return i.BeforeFunc(ctx, stmt)
+}
+
+func (i *InterceptorFuncs) After(ctx context.Context, stmt *Statement) {
+	if i.AfterFunc != nil {
+		i.AfterFunc(ctx, stmt)
+	}
+}
+
+// WithInterceptors 打开数据库时注册拦截器
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(d *DB) {
+		d.Use(interceptors...)
+	}
+}
+
+// Use 注册数据库级别的拦截器,在Factory级别的拦截器之后执行
+//
+// 拦截器应在初始化阶段注册，执行过程中注册不是并发安全的
+func (d *DB) Use(interceptors ...Interceptor) {
+	d.interceptors = append(d.interceptors, interceptors...)
+}
+
+// Use 注册Factory级别的拦截器,对Factory中所有的数据库生效
+func (m *Factory) Use(interceptors ...Interceptor) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.interceptors = append(m.interceptors, interceptors...)
+}
+
+func (d *DB) chain() []Interceptor {
+	if d.m == nil || len(d.m.interceptors) == 0 {
+		return d.interceptors
+	}
+	if len(d.interceptors) == 0 {
+		return d.m.interceptors
+	}
+	chain := make([]Interceptor, 0, len(d.m.interceptors)+len(d.interceptors))
+	return append(append(chain, d.m.interceptors...), d.interceptors...)
+}
+
+// intercept 通过拦截器链执行语句,fn中应使用stmt.Query/stmt.Args(可能已被拦截器改写)
+func (d *DB) intercept(ctx context.Context, stmt *Statement, fn func(ctx context.Context, stmt *Statement) error) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	chain := d.chain()
+	stmt.DB = d
+	if stmt.Method == "" {
+		stmt.Method = MethodFrom(ctx)
+	}
+	stmt.RowsAffected = -1
+	stmt.Start = time.Now()
+	called := 0
+	for _, ic := range chain {
+		var next context.Context
+		if next, err = ic.Before(ctx, stmt); err != nil {
+			break
+		}
+		if next != nil {
+			ctx = next
+		}
+		called++
+	}
+	if err == nil {
+		err = fn(ctx, stmt)
+	}
+	stmt.Duration = time.Since(stmt.Start)
+	stmt.Err = err
+	d.logStatement(stmt)
+	for idx := called - 1; idx >= 0; idx-- {
+		chain[idx].After(ctx, stmt)
+	}
+	return err
+}
+
+// logStatement 输出语句日志(Debug),FieldLogger以结构化字段输出
+func (d *DB) logStatement(stmt *Statement) {
+	logger := d.Logger()
+	if le, ok := logger.(levelEnabler); ok && !le.debugEnabled() {
+		return
+	}
+	redactor := Redactor(RedactSensitive)
+	if d.m != nil {
+		redactor = d.m.Redactor()
+	}
+	args := RedactStatement(stmt, redactor)
+	fl, ok := logger.(FieldLogger)
+	if !ok {
+		logger.Debug(stmt.Op+":", stmt.Query, args)
+		return
+	}
+	fields := Fields{
+		"op":         stmt.Op,
+		"query":      stmt.Query,
+		"args":       args,
+		"duration":   stmt.Duration,
+		"datasource": d.name,
+	}
+	if stmt.Template != "" {
+		fields["template"] = stmt.Template
+	}
+	if stmt.Method != "" {
+		fields["method"] = stmt.Method
+	}
+	if stmt.Tx != nil {
+		fields["tx"] = stmt.Tx.ID()
+	}
+	if stmt.RowsAffected >= 0 {
+		fields["rows"] = stmt.RowsAffected
+	}
+	if stmt.Err != nil {
+		fields["error"] = stmt.Err
+	}
+	fl.WithFields(fields).Debug("sql " + stmt.Op)
+}
+
+type methodKey struct{}
+
+// WithMethod 在上下文中记录发起调用的mapper方法名称,拦截器可通过Statement.Method读取
+//
+// BoostMapper 绑定的函数会自动设置为"结构体名称.字段名称"
+func WithMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodKey{}, method)
+}
+
+// MethodFrom 获取上下文中的mapper方法名称
+func MethodFrom(ctx context.Context) string {
+	if m, ok := ctx.Value(methodKey{}).(string); ok {
+		return m
+	}
+	return ""
+}
+
+// newStatement 创建语句信息，如果sqlOrTpl是模版则记录模版名称
+func newStatement(op, sqlOrTpl, query string, args any) *Statement {
+	stmt := &Statement{Op: op, Query: query, Args: args}
+	if strings.HasSuffix(sqlOrTpl, sqlSuffix) {
+		stmt.Template = sqlOrTpl
+	}
+	return stmt
+}
+
+// sizeOf 获取查询结果的行数(dest为slice指针时为slice长度，否则为1)
+func sizeOf(dest any) int64 {
+	v := reflect.ValueOf(dest)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		return int64(v.Len())
+	}
+	return 1
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInterceptor(t *testing.T) {
+	f := NewFactory("interceptor")
+	var factoryCalls, dbCalls []string
+	f.Use(&InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, stmt *Statement) (context.Context, error) {
+			factoryCalls = append(factoryCalls, "before:"+stmt.Op)
+			return ctx, nil
+		},
+		AfterFunc: func(ctx context.Context, stmt *Statement) {
+			factoryCalls = append(factoryCalls, "after:"+stmt.Op)
+		},
+	})
+	db, err := f.Open(DefaultName, "mysql", testDSN,
+		WithInterceptors(&InterceptorFuncs{
+			AfterFunc: func(ctx context.Context, stmt *Statement) {
+				dbCalls = append(dbCalls, stmt.Template)
+				assert.NoError(t, stmt.Err)
+				assert.Greater(t, stmt.Duration.Nanoseconds(), int64(0))
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+
+	_, err = db.ParseTemplate("interceptor/select_roles.sql", "SELECT * FROM `role`")
+	assert.NoError(t, err)
+	var roles []Role
+	assert.NoError(t, db.Selectxx(&roles, "interceptor/select_roles.sql"))
+	assert.Equal(t, []string{"before:select", "after:select"}, factoryCalls)
+	assert.Equal(t, []string{"interceptor/select_roles.sql"}, dbCalls)
+}
+
+func TestInterceptor_Rewrite(t *testing.T) {
+	f := NewFactory("interceptor")
+	db, err := f.Open(DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	var rows int64
+	db.Use(&InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, stmt *Statement) (context.Context, error) {
+			stmt.Query = "SELECT * FROM `role` WHERE `name` = ?"
+			stmt.Args = []any{"admin"}
+			return ctx, nil
+		},
+		AfterFunc: func(ctx context.Context, stmt *Statement) {
+			rows = stmt.RowsAffected
+		},
+	})
+	var roles []Role
+	assert.NoError(t, db.Selectxx(&roles, "SELECT * FROM `role`"))
+	assert.Len(t, roles, 1)
+	assert.Equal(t, int64(1), rows)
+}
+
+func TestInterceptor_Abort(t *testing.T) {
+	f := NewFactory("interceptor")
+	db, err := f.Open(DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	errDenied := errors.New("denied")
+	afterCalled := false
+	db.Use(&InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, stmt *Statement) (context.Context, error) {
+			return ctx, errDenied
+		},
+		AfterFunc: func(ctx context.Context, stmt *Statement) {
+			afterCalled = true
+		},
+	})
+	err = db.Batch(context.Background(), nil, func(tx *Tx) error {
+		_, err := tx.Execxx("DELETE FROM `role`")
+		return err
+	})
+	assert.ErrorIs(t, err, errDenied)
+	assert.False(t, afterCalled)
+}
+
+func TestInterceptor_Method(t *testing.T) {
+	f := NewFactory("interceptor")
+	var methods []string
+	_, err := f.Open(DefaultName, "mysql", testDSN,
+		WithInterceptors(&InterceptorFuncs{
+			AfterFunc: func(ctx context.Context, stmt *Statement) {
+				methods = append(methods, stmt.Method)
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	m, err := NewMapperWith[RoleExtMapper](f, DefaultName)
+	assert.NoError(t, err)
+	_, err = m.ListByRoleName("admin")
+	assert.NoError(t, err)
+	_, err = m.ListByRole(&Role{Name: "admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"RoleExtMapper.ListByRoleName", "RoleExtMapper.ListByRole"}, methods)
+}
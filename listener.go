@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"sync"
+)
+
+// Listener 实体事件监听器,entity为实体指针(指针类型的实体为实体本身)
+//
+// 写操作中ctx包含当前事务,可以通过 TxFrom 获取并在同一事务中执行语句(如写入审计、outbox)
+type Listener func(ctx context.Context, entity any) error
+
+type listenerKey struct {
+	after bool
+	op    HookOp
+}
+
+// listeners 事件监听器注册表,内嵌在 Factory(对所有实体生效) 和 BaseMapper(对mapper的实体生效) 中
+//
+// 前置事件的执行顺序为 Factory、BaseMapper、实体钩子，后置事件顺序相反
+type listeners struct {
+	listenerMu  sync.RWMutex
+	listenerFns map[listenerKey][]Listener
+}
+
+// OnBefore 注册操作的前置监听器
+func (l *listeners) OnBefore(op HookOp, fn Listener) {
+	l.on(listenerKey{op: op}, fn)
+}
+
+// OnAfter 注册操作的后置监听器
+func (l *listeners) OnAfter(op HookOp, fn Listener) {
+	l.on(listenerKey{after: true, op: op}, fn)
+}
+
+func (l *listeners) OnBeforeInsert(fn Listener) { l.OnBefore(HookInsert, fn) }
+func (l *listeners) OnAfterInsert(fn Listener)  { l.OnAfter(HookInsert, fn) }
+func (l *listeners) OnBeforeUpdate(fn Listener) { l.OnBefore(HookUpdate, fn) }
+func (l *listeners) OnAfterUpdate(fn Listener)  { l.OnAfter(HookUpdate, fn) }
+func (l *listeners) OnBeforeDelete(fn Listener) { l.OnBefore(HookDelete, fn) }
+func (l *listeners) OnAfterDelete(fn Listener)  { l.OnAfter(HookDelete, fn) }
+func (l *listeners) OnAfterFind(fn Listener)    { l.OnAfter(HookFind, fn) }
+
+func (l *listeners) on(key listenerKey, fn Listener) {
+	l.listenerMu.Lock()
+	defer l.listenerMu.Unlock()
+	if l.listenerFns == nil {
+		l.listenerFns = map[listenerKey][]Listener{}
+	}
+	l.listenerFns[key] = append(l.listenerFns[key], fn)
+}
+
+func (l *listeners) get(after bool, op HookOp) []Listener {
+	if l == nil {
+		return nil
+	}
+	l.listenerMu.RLock()
+	defer l.listenerMu.RUnlock()
+	return l.listenerFns[listenerKey{after: after, op: op}]
+}
+
+// hasListeners 判断是否注册了操作的监听器
+func hasListeners(op HookOp, chain ...*listeners) bool {
+	for _, l := range chain {
+		if len(l.get(false, op)) > 0 || len(l.get(true, op)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// factoryListeners 获取所属Factory的监听器
+func (d *DB) factoryListeners() *listeners {
+	if d == nil || d.m == nil {
+		return nil
+	}
+	return &d.m.listeners
+}
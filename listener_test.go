@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestListeners(t *testing.T) {
+	f := NewFactory("listeners")
+	if _, err := f.Open(DefaultName, "mysql", testDSN); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	mapper, err := NewMapperWith[BaseMapper[*AllHookedRole]](f, DefaultName)
+	assert.NoError(t, err)
+
+	record := func(event string) Listener {
+		return func(ctx context.Context, entity any) error {
+			assert.IsType(t, &AllHookedRole{}, entity)
+			assert.NotNil(t, TxFrom(ctx), event)
+			hookEvents = append(hookEvents, event)
+			return nil
+		}
+	}
+	f.OnBeforeInsert(record("factory before"))
+	f.OnAfterInsert(record("factory after"))
+	mapper.OnBeforeInsert(record("mapper before"))
+	mapper.OnAfterInsert(record("mapper after"))
+
+	hookEvents = nil
+	role := &AllHookedRole{Name: "listened", Desc: "listeners"}
+	assert.NoError(t, mapper.Create(role))
+	assert.Equal(t, []string{
+		"factory before", "mapper before", "BeforeInsert",
+		"AfterInsert", "mapper after", "factory after",
+	}, hookEvents)
+
+	errDenied := errors.New("denied")
+	var deletedId int64
+	f.OnBeforeDelete(func(ctx context.Context, entity any) error {
+		deletedId = entity.(*AllHookedRole).ID
+		return errDenied
+	})
+	hookEvents = nil
+	assert.ErrorIs(t, mapper.EraseById(nil, role.ID), errDenied)
+	assert.Equal(t, role.ID, deletedId)
+	assert.Empty(t, hookEvents)
+
+	found, err := mapper.ListById(nil, role.ID)
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+}
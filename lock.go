@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/cookieY/sqlx"
+	"github.com/gnodux/sqlxx/expr"
+	"time"
+)
+
+const (
+	// DefaultLockTable 锁表名称,数据库方言不支持咨询锁时使用(参考 dialect.Driver.AdvisoryLock)
+	DefaultLockTable = "sqlxx_lock"
+	// lockRetryInterval 锁表加锁失败后的重试间隔
+	lockRetryInterval = 50 * time.Millisecond
+)
+
+var (
+	//ErrLockTimeout 获取锁超时
+	ErrLockTimeout = errors.New("lock timeout")
+	//ErrLockNotHeld 释放的锁不是由当前数据库持有
+	ErrLockNotHeld = errors.New("lock is not held")
+)
+
+// heldLock 已获取的锁,咨询锁持有获取锁的连接(会话级的锁需要在同一连接中释放),锁表记录持有者
+type heldLock struct {
+	conn  *sql.Conn
+	owner string
+}
+
+// Lock 获取数据库锁(跨进程互斥),获取成功后需要调用 Unlock 释放
+//
+// MySQL/Postgres/SQL Server 使用会话级的咨询锁(GET_LOCK/pg_advisory_lock/sp_getapplock),持有锁期间占用一个连接;
+// 其他数据库(如SQLite)使用锁表(DefaultLockTable),进程异常退出时需要手动删除锁记录
+//
+// timeout<=0 时一直等待直到上下文结束，超时返回 ErrLockTimeout。锁不可重入，同一进程中重复获取同一个锁会等待
+func (d *DB) Lock(ctx context.Context, name string, timeout time.Duration) (err error) {
+	if d == nil {
+		return ErrNilDB
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	lockCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var held *heldLock
+	if d.driver.AdvisoryLock != "" {
+		held, err = d.advisoryLock(lockCtx, name, timeout)
+	} else {
+		held, err = d.tableLock(lockCtx, name)
+	}
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(lockCtx.Err(), context.DeadlineExceeded) {
+			return ErrLockTimeout
+		}
+		return err
+	}
+	d.lockMu.Lock()
+	defer d.lockMu.Unlock()
+	if d.locks == nil {
+		d.locks = map[string]*heldLock{}
+	}
+	d.locks[name] = held
+	return nil
+}
+
+// Unlock 释放 Lock 获取的锁,锁不是由当前数据库持有时返回 ErrLockNotHeld
+func (d *DB) Unlock(ctx context.Context, name string) error {
+	if d == nil {
+		return ErrNilDB
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d.lockMu.Lock()
+	held, ok := d.locks[name]
+	delete(d.locks, name)
+	d.lockMu.Unlock()
+	if !ok {
+		return ErrLockNotHeld
+	}
+	if held.conn == nil {
+		_, err := d.execExpr(withoutTx(ctx), nil, expr.Delete(expr.Name(DefaultLockTable)).Where(expr.And(
+			expr.Eq(expr.Name("name"), expr.Var("name", name)),
+			expr.Eq(expr.Name("owner"), expr.Var("owner", held.owner)))))
+		return err
+	}
+	defer held.conn.Close()
+	released, err := d.lockQuery(ctx, held.conn, d.driver.AdvisoryUnlock, map[string]any{"name": name})
+	if err == nil && !released {
+		err = ErrLockNotHeld
+	}
+	return err
+}
+
+// WithLock 获取锁后执行fn,执行完成后释放锁(参考 Lock)
+func (d *DB) WithLock(ctx context.Context, name string, timeout time.Duration, fn func(ctx context.Context) error) (err error) {
+	if err = d.Lock(ctx, name, timeout); err != nil {
+		return
+	}
+	defer func() {
+		//上下文取消后仍然需要释放锁
+		if unlockErr := d.Unlock(context.WithoutCancel(ctx), name); err == nil {
+			err = unlockErr
+		}
+	}()
+	return fn(ctx)
+}
+
+// advisoryLock 在独占的连接上获取咨询锁
+func (d *DB) advisoryLock(ctx context.Context, name string, timeout time.Duration) (*heldLock, error) {
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seconds, ms := -1, int64(-1)
+	if timeout > 0 {
+		seconds, ms = int((timeout+time.Second-1)/time.Second), timeout.Milliseconds()
+	}
+	acquired, err := d.lockQuery(ctx, conn, d.driver.AdvisoryLock, map[string]any{
+		"name":       name,
+		"timeout":    seconds,
+		"timeout_ms": ms,
+	})
+	if err == nil && !acquired {
+		err = ErrLockTimeout
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &heldLock{conn: conn}, nil
+}
+
+// lockQuery 在连接上执行加锁(解锁)语句,语句返回1表示成功
+func (d *DB) lockQuery(ctx context.Context, conn *sql.Conn, namedQuery string, arg map[string]any) (ok bool, err error) {
+	query, args, err := sqlx.Named(namedQuery, arg)
+	if err != nil {
+		return false, err
+	}
+	stmt := newStatement(OpGet, "", d.Rebind(query), args)
+	err = d.intercept(ctx, stmt, func(ctx context.Context, stmt *Statement) error {
+		var result sql.NullInt64
+		if err := conn.QueryRowContext(ctx, stmt.Query, argList(stmt.Args)...).Scan(&result); err != nil {
+			return err
+		}
+		stmt.RowsAffected = 1
+		ok = result.Valid && result.Int64 == 1
+		return nil
+	})
+	return
+}
+
+// tableLock 在锁表中插入锁记录，锁记录已存在(被其他持有者占用)时等待后重试
+func (d *DB) tableLock(ctx context.Context, name string) (*heldLock, error) {
+	ctx = withoutTx(ctx)
+	if err := d.createLockTable(ctx); err != nil {
+		return nil, err
+	}
+	owner, err := lockOwner()
+	if err != nil {
+		return nil, err
+	}
+	table := expr.Name(DefaultLockTable)
+	for {
+		_, err = d.execExpr(ctx, nil, expr.InsertInto(table).
+			SetExpr(expr.Name("name"), expr.Var("name", name)).
+			SetExpr(expr.Name("owner"), expr.Var("owner", owner)).
+			SetExpr(expr.Name("acquired_at"), expr.Var("acquired_at", time.Now())))
+		if err == nil {
+			return &heldLock{owner: owner}, nil
+		}
+		//锁记录不存在说明不是主键冲突,直接返回错误
+		var count int
+		if countErr := d.getExpr(ctx, nil, &count, expr.Select(expr.Count).From(table).
+			Where(expr.Eq(expr.Name("name"), expr.Var("name", name)))); countErr != nil || count == 0 {
+			return nil, err
+		}
+		timer := time.NewTimer(lockRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// createLockTable 创建锁表(已存在时忽略)
+func (d *DB) createLockTable(ctx context.Context) error {
+	d.lockMu.Lock()
+	defer d.lockMu.Unlock()
+	if d.lockTable {
+		return nil
+	}
+	if _, err := d.doExec(ctx, nil, "", fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (name VARCHAR(255) PRIMARY KEY, owner VARCHAR(64) NOT NULL, acquired_at DATETIME NOT NULL)",
+		d.driver.SQLNameFunc(DefaultLockTable)), nil); err != nil {
+		return err
+	}
+	d.lockTable = true
+	return nil
+}
+
+// lockOwner 生成锁表中的持有者标识
+func lockOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withoutTx 移除上下文中的事务,锁的获取和释放不能加入调用者的事务
+func withoutTx(ctx context.Context) context.Context {
+	return WithTx(ctx, nil)
+}
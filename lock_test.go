@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDB_Lock(t *testing.T) {
+	tableDriver := *dialect.MySQL
+	tableDriver.AdvisoryLock, tableDriver.AdvisoryUnlock = "", ""
+	tests := []struct {
+		name   string
+		driver *dialect.Driver
+	}{
+		{"advisory", dialect.MySQL},
+		{"table", &tableDriver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFactory("lock")
+			defer f.Shutdown()
+			// 两个数据库实例模拟两个进程
+			var dbs []*DB
+			for _, name := range []string{"p1", "p2"} {
+				db, err := OpenWith(f, tt.driver, testDSN)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.Set(name, db)
+				dbs = append(dbs, db)
+			}
+			ctx := context.Background()
+			name := "lock_test_" + tt.name
+
+			assert.NoError(t, dbs[0].Lock(ctx, name, time.Second))
+			assert.ErrorIs(t, dbs[1].Lock(ctx, name, 100*time.Millisecond), ErrLockTimeout)
+			assert.ErrorIs(t, dbs[1].Unlock(ctx, name), ErrLockNotHeld)
+			assert.NoError(t, dbs[0].Unlock(ctx, name))
+
+			errFailed := errors.New("failed")
+			called := false
+			assert.ErrorIs(t, dbs[1].WithLock(ctx, name, time.Second, func(ctx context.Context) error {
+				called = true
+				assert.ErrorIs(t, dbs[0].Lock(ctx, name, 100*time.Millisecond), ErrLockTimeout)
+				return errFailed
+			}), errFailed)
+			assert.True(t, called)
+			assert.NoError(t, dbs[0].WithLock(ctx, name, time.Second, func(ctx context.Context) error {
+				return nil
+			}))
+		})
+	}
+}
@@ -21,10 +21,49 @@ type Logger interface {
 	Errorf(string, ...any)
 }
 
+// Fields 结构化日志字段
+type Fields map[string]any
+
+// FieldLogger 支持结构化字段的日志，语句日志会以字段方式输出(query/args/template/duration/datasource/tx等)
+type FieldLogger interface {
+	Logger
+	WithFields(fields Fields) Logger
+}
+
+// levelEnabler 可选接口，用于在日志级别未开启时跳过字段构建
+type levelEnabler interface {
+	debugEnabled() bool
+}
+
 var (
-	log Logger = logrus.StandardLogger()
+	log Logger = NewLogrusLogger(logrus.StandardLogger())
 )
 
+// SetLogger 设置全局日志(Factory未设置日志时使用)
 func SetLogger(l Logger) {
 	log = l
 }
+
+// logrusLogger logrus适配
+type logrusLogger struct {
+	logrus.Ext1FieldLogger
+}
+
+// NewLogrusLogger 创建logrus日志适配(*logrus.Logger或*logrus.Entry)
+func NewLogrusLogger(l logrus.Ext1FieldLogger) FieldLogger {
+	return &logrusLogger{Ext1FieldLogger: l}
+}
+
+func (l *logrusLogger) WithFields(fields Fields) Logger {
+	return &logrusLogger{Ext1FieldLogger: l.Ext1FieldLogger.WithFields(logrus.Fields(fields))}
+}
+
+func (l *logrusLogger) debugEnabled() bool {
+	switch lg := l.Ext1FieldLogger.(type) {
+	case *logrus.Logger:
+		return lg.IsLevelEnabled(logrus.DebugLevel)
+	case *logrus.Entry:
+		return lg.Logger.IsLevelEnabled(logrus.DebugLevel)
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// LevelTrace slog中Trace对应的日志级别
+const LevelTrace = slog.LevelDebug - 4
+
+// slogLogger log/slog适配
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger 创建log/slog日志适配
+func NewSlogLogger(l *slog.Logger) FieldLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) log(level slog.Level, msg string) {
+	s.l.Log(context.Background(), level, msg)
+}
+
+func (s *slogLogger) Trace(args ...any)            { s.log(LevelTrace, fmt.Sprint(args...)) }
+func (s *slogLogger) Tracef(f string, args ...any) { s.log(LevelTrace, fmt.Sprintf(f, args...)) }
+func (s *slogLogger) Debug(args ...any)            { s.log(slog.LevelDebug, fmt.Sprint(args...)) }
+func (s *slogLogger) Debugf(f string, args ...any) { s.log(slog.LevelDebug, fmt.Sprintf(f, args...)) }
+func (s *slogLogger) Info(args ...any)             { s.log(slog.LevelInfo, fmt.Sprint(args...)) }
+func (s *slogLogger) Infof(f string, args ...any)  { s.log(slog.LevelInfo, fmt.Sprintf(f, args...)) }
+func (s *slogLogger) Warn(args ...any)             { s.log(slog.LevelWarn, fmt.Sprint(args...)) }
+func (s *slogLogger) Warnf(f string, args ...any)  { s.log(slog.LevelWarn, fmt.Sprintf(f, args...)) }
+func (s *slogLogger) Error(args ...any)            { s.log(slog.LevelError, fmt.Sprint(args...)) }
+func (s *slogLogger) Errorf(f string, args ...any) { s.log(slog.LevelError, fmt.Sprintf(f, args...)) }
+
+// WithFields 字段按名称排序后作为slog属性
+func (s *slogLogger) WithFields(fields Fields) Logger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	return &slogLogger{l: s.l.With(attrs...)}
+}
+
+func (s *slogLogger) debugEnabled() bool {
+	return s.l.Enabled(context.Background(), slog.LevelDebug)
+}
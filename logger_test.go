@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+type SensitiveUser struct {
+	ID       int64
+	Name     string
+	Password string `dbx:"sensitive"`
+}
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := NewFactory("slog")
+	f.SetLogger(NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	db, err := f.Open(DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	buf.Reset()
+
+	var users []User
+	err = db.Batch(context.Background(), nil, func(tx *Tx) error {
+		return tx.NamedSelect(&users, "SELECT * FROM `user` WHERE `name`=:name AND `password`=:password",
+			SensitiveUser{Name: "user_1", Password: "password"})
+	})
+	assert.NoError(t, err)
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "sql select", entry["msg"])
+	assert.Equal(t, DefaultName, entry["datasource"])
+	assert.Contains(t, entry, "tx")
+	assert.Contains(t, entry, "duration")
+	assert.Equal(t, map[string]any{"name": "user_1", "password": Redacted}, entry["args"])
+}
+
+func TestSlogLogger_Disabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := NewFactory("slog")
+	f.SetLogger(NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))))
+	db, err := f.Open(DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	buf.Reset()
+	var roles []Role
+	assert.NoError(t, db.Selectxx(&roles, "SELECT * FROM `role`"))
+	assert.Zero(t, buf.Len())
+}
@@ -6,8 +6,11 @@
 package sqlxx
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/gnodux/sqlxx/meta"
 	"github.com/gnodux/sqlxx/utils"
 	"path/filepath"
 	"reflect"
@@ -27,6 +30,12 @@ const (
 	// TagReadonly 事务是否只读
 	TagReadonly = "readonly"
 
+	// TagPropagation 事务传播行为(Required/RequiresNew/Nested)
+	TagPropagation = "propagation"
+
+	// TagRetry 事务重试策略,参考 ParseRetryPolicy
+	TagRetry = "retry"
+
 	// TxDefault 默认事务级别
 	TxDefault = "Default"
 
@@ -58,13 +67,51 @@ var (
 	ExecFuncType      = reflect.TypeOf(ExecFunc(nil))
 	NamedExecFuncType = reflect.TypeOf(NamedExecFunc(nil))
 	TxFuncType        = reflect.TypeOf(TxFunc(nil))
+
+	ExecContextFuncType      = reflect.TypeOf(ExecContextFunc(nil))
+	NamedExecContextFuncType = reflect.TypeOf(NamedExecContextFunc(nil))
+	TxContextFuncType        = reflect.TypeOf(TxContextFunc(nil))
 )
 
-// parseExtTags 解析字段的自定义tag，包含：数据源、sql模版（或inline sql）、事务级别、事务是否只读等
-func parseExtTags(field reflect.StructField) (ds, tpl string, level sql.IsolationLevel, readOnly bool) {
-	ds = field.Tag.Get(TagDS)
-	tpl = field.Tag.Get(TagSQL)
-	txtLevel := field.Tag.Get(TagTx)
+// fieldTags 字段的自定义tag
+type fieldTags struct {
+	ds          string
+	tpl         string
+	level       sql.IsolationLevel
+	readOnly    bool
+	propagation Propagation
+	retry       *RetryPolicy
+}
+
+// txDefinition 根据tag生成事务定义
+func (t fieldTags) txDefinition() TxDefinition {
+	return TxDefinition{
+		Options:     &sql.TxOptions{Isolation: t.level, ReadOnly: t.readOnly},
+		Propagation: t.propagation,
+		Retry:       t.retry,
+	}
+}
+
+// parseExtTags 解析字段的自定义tag，包含：数据源、sql模版（或inline sql）、事务级别、事务是否只读、传播行为等
+func parseExtTags(field reflect.StructField) (tags fieldTags, err error) {
+	tags.ds = field.Tag.Get(TagDS)
+	tags.tpl = field.Tag.Get(TagSQL)
+	tags.level = parseIsolation(field.Tag.Get(TagTx))
+	r := field.Tag.Get(TagReadonly)
+	if r != "" && strings.ToLower(r) != "false" {
+		tags.readOnly = true
+	}
+	tags.propagation = ParsePropagation(field.Tag.Get(TagPropagation))
+	if retry, ok := field.Tag.Lookup(TagRetry); ok {
+		if tags.retry, err = ParseRetryPolicy(retry); err != nil {
+			return tags, fmt.Errorf("%s.%s: %w", field.PkgPath, field.Name, err)
+		}
+	}
+	return
+}
+
+// parseIsolation 解析事务级别名称
+func parseIsolation(txtLevel string) (level sql.IsolationLevel) {
 	switch txtLevel {
 	case TxReadCommitted:
 		level = sql.LevelReadCommitted
@@ -83,17 +130,30 @@ func parseExtTags(field reflect.StructField) (ds, tpl string, level sql.Isolatio
 	default:
 		level = sql.LevelDefault
 	}
-	r := field.Tag.Get(TagReadonly)
-	if r != "" && strings.ToLower(r) != "false" {
-		readOnly = true
-	}
 	return
 }
 
 // BoostMapper 对mapper的Field进行wrap处理、绑定数据源、绑定sql模版、绑定事务级别、绑定是否只读等
 //
 // change: 2023-7-12 修改绑定策略，从延迟绑定修改到boost时绑定，动态打开数据库的需求不高，且模版延迟绑定和获取数据库需要使用到锁，对性能有一定影响
-func BoostMapper(dest interface{}, factory *Factory, ds string) error {
+func BoostMapper(dest interface{}, factory *Factory, ds string, opts ...BoostOption) error {
+	o := &boostOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	var bindings []*binding
+	if err := boostMapper(dest, factory, ds, &bindings); err != nil {
+		return err
+	}
+	factory.bind(bindings...)
+	if o.strict {
+		return validateBindings(context.Background(), bindings, o.validate)
+	}
+	return nil
+}
+
+// boostMapper 绑定mapper的字段,记录绑定的函数(用于启动校验)
+func boostMapper(dest interface{}, factory *Factory, ds string, bindings *[]*binding) error {
 	currentDb, err := factory.Get(ds)
 	if err != nil {
 		return err
@@ -108,15 +168,22 @@ func BoostMapper(dest interface{}, factory *Factory, ds string) error {
 	if ii, ok := dest.(interface{ init() }); ok {
 		ii.init()
 	}
+	if em, ok := dest.(interface{ Meta() *meta.Entity }); ok {
+		currentDb.RegisterEntity(em.Meta())
+	}
 	v = v.Elem()
 	for idx := 0; idx < v.Type().NumField(); idx++ {
 		field := v.Type().Field(idx)
-		fieldDs, sqlTpl, isoLevel, readonly := parseExtTags(field)
+		tags, err := parseExtTags(field)
+		if err != nil {
+			return err
+		}
+		fieldDs, sqlTpl := tags.ds, tags.tpl
 		if fieldDs == "" {
 			fieldDs = ds
 		}
 		if field.IsExported() && field.Type.Kind() == reflect.Struct {
-			if err := BoostMapper(v.Field(idx).Addr().Interface(), factory, fieldDs); err != nil {
+			if err := boostMapper(v.Field(idx).Addr().Interface(), factory, fieldDs, bindings); err != nil {
 				return err
 			}
 			continue
@@ -161,16 +228,26 @@ func BoostMapper(dest interface{}, factory *Factory, ds string) error {
 				}
 				tplList = append(tplList, sqlTpl)
 			}
+			method := v.Type().Name() + "." + field.Name
+			ctx := WithMethod(context.Background(), method)
+			b := &binding{db: currentDb, method: method, templates: []string{sqlTpl}}
 			switch field.Type {
 			case ExecFuncType:
-				v.Field(idx).Set(reflect.ValueOf(NewExecFuncWith(currentDb, sqlTpl)))
+				v.Field(idx).Set(reflect.ValueOf(newExecFunc(ctx, currentDb, sqlTpl)))
 			case NamedExecFuncType:
-				v.Field(idx).Set(reflect.ValueOf(NewNamedExecFuncWith(currentDb, sqlTpl)))
+				v.Field(idx).Set(reflect.ValueOf(newNamedExecFunc(ctx, currentDb, sqlTpl)))
+				b.named = true
 			case TxFuncType:
-				v.Field(idx).Set(reflect.ValueOf(NewTxFuncWith(currentDb, sqlTpl, &sql.TxOptions{
-					Isolation: isoLevel,
-					ReadOnly:  readonly,
-				})))
+				v.Field(idx).Set(reflect.ValueOf(newTxFunc(ctx, currentDb, sqlTpl, tags.txDefinition())))
+				b.tx = true
+			case ExecContextFuncType:
+				v.Field(idx).Set(reflect.ValueOf(newExecContextFunc(method, currentDb, sqlTpl)))
+			case NamedExecContextFuncType:
+				v.Field(idx).Set(reflect.ValueOf(newNamedExecContextFunc(method, currentDb, sqlTpl)))
+				b.named = true
+			case TxContextFuncType:
+				v.Field(idx).Set(reflect.ValueOf(newTxContextFunc(method, currentDb, sqlTpl, tags.txDefinition())))
+				b.tx = true
 			default:
 				name := field.Type.Name()
 				//begin: 判断是否泛型，并去除泛型参数
@@ -183,7 +260,7 @@ func BoostMapper(dest interface{}, factory *Factory, ds string) error {
 				switch name {
 				case "SelectFunc":
 					fnVal = func(values []reflect.Value) []reflect.Value {
-						ret, err := SelectWith(field.Type.Out(0).Elem(), currentDb, tplList, values[0].Interface().([]any))
+						ret, err := selectWith(ctx, field.Type.Out(0).Elem(), currentDb, tplList, values[0].Interface().([]any))
 						return []reflect.Value{
 							utils.ValueOrZero(ret, field.Type.Out(0)),
 							utils.ValueOrZero(err, field.Type.Out(1)),
@@ -191,7 +268,7 @@ func BoostMapper(dest interface{}, factory *Factory, ds string) error {
 					}
 				case "NamedSelectFunc":
 					fnVal = func(values []reflect.Value) []reflect.Value {
-						ret, err := NamedSelectWith(field.Type.Out(0).Elem(), currentDb, tplList, values[0].Interface())
+						ret, err := namedSelectWith(ctx, field.Type.Out(0).Elem(), currentDb, tplList, values[0].Interface())
 						return []reflect.Value{
 							utils.ValueOrZero(ret, field.Type.Out(0)),
 							utils.ValueOrZero(err, field.Type.Out(1)),
@@ -199,7 +276,7 @@ func BoostMapper(dest interface{}, factory *Factory, ds string) error {
 					}
 				case "GetFunc":
 					fnVal = func(values []reflect.Value) []reflect.Value {
-						ret, err := GetWith(field.Type.Out(0), currentDb, tplList, values[0].Interface().([]any))
+						ret, err := getWith(ctx, field.Type.Out(0), currentDb, tplList, values[0].Interface().([]any))
 						return []reflect.Value{
 							utils.ValueOrZero(ret, field.Type.Out(0)),
 							utils.ValueOrZero(err, field.Type.Out(1)),
@@ -207,40 +284,81 @@ func BoostMapper(dest interface{}, factory *Factory, ds string) error {
 					}
 				case "NamedGetFunc":
 					fnVal = func(values []reflect.Value) []reflect.Value {
-						ret, err := NamedGetWith(field.Type.Out(0), currentDb, tplList, values[0].Interface())
+						ret, err := namedGetWith(ctx, field.Type.Out(0), currentDb, tplList, values[0].Interface())
+						return []reflect.Value{
+							utils.ValueOrZero(ret, field.Type.Out(0)),
+							utils.ValueOrZero(err, field.Type.Out(1)),
+						}
+					}
+				case "SelectContextFunc":
+					fnVal = func(values []reflect.Value) []reflect.Value {
+						ret, err := selectWith(callerContext(values[0], method), field.Type.Out(0).Elem(), currentDb, tplList, values[1].Interface().([]any))
+						return []reflect.Value{
+							utils.ValueOrZero(ret, field.Type.Out(0)),
+							utils.ValueOrZero(err, field.Type.Out(1)),
+						}
+					}
+				case "NamedSelectContextFunc":
+					fnVal = func(values []reflect.Value) []reflect.Value {
+						ret, err := namedSelectWith(callerContext(values[0], method), field.Type.Out(0).Elem(), currentDb, tplList, values[1].Interface())
+						return []reflect.Value{
+							utils.ValueOrZero(ret, field.Type.Out(0)),
+							utils.ValueOrZero(err, field.Type.Out(1)),
+						}
+					}
+				case "GetContextFunc":
+					fnVal = func(values []reflect.Value) []reflect.Value {
+						ret, err := getWith(callerContext(values[0], method), field.Type.Out(0), currentDb, tplList, values[1].Interface().([]any))
+						return []reflect.Value{
+							utils.ValueOrZero(ret, field.Type.Out(0)),
+							utils.ValueOrZero(err, field.Type.Out(1)),
+						}
+					}
+				case "NamedGetContextFunc":
+					fnVal = func(values []reflect.Value) []reflect.Value {
+						ret, err := namedGetWith(callerContext(values[0], method), field.Type.Out(0), currentDb, tplList, values[1].Interface())
 						return []reflect.Value{
 							utils.ValueOrZero(ret, field.Type.Out(0)),
 							utils.ValueOrZero(err, field.Type.Out(1)),
 						}
 					}
 				}
-				if fnVal != nil {
-					v.Field(idx).Set(reflect.MakeFunc(field.Type, fnVal))
+				if fnVal == nil {
+					continue
 				}
+				v.Field(idx).Set(reflect.MakeFunc(field.Type, fnVal))
+				b.templates, b.named, b.result = tplList, strings.HasPrefix(name, "Named"), field.Type.Out(0)
 			}
+			*bindings = append(*bindings, b)
 		}
 	}
 	return nil
 }
 
+// callerContext 获取调用方传入的上下文(第一个参数),并记录mapper方法名称
+func callerContext(v reflect.Value, method string) context.Context {
+	ctx, _ := v.Interface().(context.Context)
+	return methodContext(ctx, method)
+}
+
 // Boost 对mapper的Field进行wrap处理、绑定数据源、绑定sql模版、绑定事务级别、绑定是否只读等
 // dest: mapper对象
 // ds: 数据源
-func Boost(dest interface{}, ds string) error {
-	return BoostMapper(dest, StdFactory, ds)
+func Boost(dest interface{}, ds string, opts ...BoostOption) error {
+	return BoostMapper(dest, StdFactory, ds, opts...)
 }
 
 // NewMapperWith 创建一个mapper
 // factory: 数据库管理器
 // dataSource: 数据源
-func NewMapperWith[T any](factory *Factory, dataSource string) (*T, error) {
+func NewMapperWith[T any](factory *Factory, dataSource string, opts ...BoostOption) (*T, error) {
 	var d T
-	err := BoostMapper(&d, factory, dataSource)
+	err := BoostMapper(&d, factory, dataSource, opts...)
 	return &d, err
 }
 
 // NewMapper 创建一个mapper
 // dataSource: 数据源
-func NewMapper[T any](dataSource string) (*T, error) {
-	return NewMapperWith[T](StdFactory, dataSource)
+func NewMapper[T any](dataSource string, opts ...BoostOption) (*T, error) {
+	return NewMapperWith[T](StdFactory, dataSource, opts...)
 }
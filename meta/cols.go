@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package meta
+
+import (
+	"fmt"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/gnodux/sqlxx/utils"
+	"reflect"
+)
+
+// Col 带值类型的列,值的类型在编译时检查,字段重命名时条件也会编译失败
+//
+// 通常不直接创建,而是通过 NewCols/MustCols 按照实体绑定,例如:
+//
+//	type UserColumns struct {
+//		Name     meta.Col[string]
+//		Birthday meta.Col[time.Time]
+//	}
+//	var UserCols = meta.MustCols[User, UserColumns]()
+//	UserCols.Name.Eq("admin")
+type Col[V any] struct {
+	*Column
+}
+
+func (c Col[V]) Eq(value V) *expr.BinaryExpr {
+	return expr.Eq(c.Column, bindVar(value))
+}
+func (c Col[V]) Ne(value V) *expr.BinaryExpr {
+	return expr.Ne(c.Column, bindVar(value))
+}
+func (c Col[V]) Gt(value V) *expr.BinaryExpr {
+	return expr.Gt(c.Column, bindVar(value))
+}
+func (c Col[V]) Ge(value V) *expr.BinaryExpr {
+	return expr.Ge(c.Column, bindVar(value))
+}
+func (c Col[V]) Lt(value V) *expr.BinaryExpr {
+	return expr.Lt(c.Column, bindVar(value))
+}
+func (c Col[V]) Le(value V) *expr.BinaryExpr {
+	return expr.Le(c.Column, bindVar(value))
+}
+
+// Like 模糊匹配,pattern 为匹配模式(例如 "abc%")
+func (c Col[V]) Like(pattern string) *expr.BinaryExpr {
+	return expr.Like(c.Column, expr.AutoVar(pattern))
+}
+func (c Col[V]) In(values ...V) *expr.BinaryExpr {
+	return expr.In(c.Column, c.ColumnName, derefAll(values)...)
+}
+func (c Col[V]) NotIn(values ...V) *expr.BinaryExpr {
+	return expr.NotIn(c.Column, c.ColumnName, derefAll(values)...)
+}
+func (c Col[V]) Between(min, max V) *expr.BetweenExpr {
+	return expr.Between(c.Column, expr.AutoVar(deref(min)), expr.AutoVar(deref(max)))
+}
+
+// IsNull 列为NULL
+func (c Col[V]) IsNull() *expr.BinaryExpr {
+	return expr.Eq(c.Column, nil)
+}
+
+// IsNotNull 列不为NULL
+func (c Col[V]) IsNotNull() *expr.BinaryExpr {
+	return expr.Ne(c.Column, nil)
+}
+
+// Set 赋值(用于 Query.Update),空指针赋值为NULL
+func (c Col[V]) Set(value V) *expr.BinaryExpr {
+	return expr.Assign(c.Column, deref(value))
+}
+func (c Col[V]) Asc() expr.Expr {
+	return expr.Asc(c.Column)
+}
+func (c Col[V]) Desc() expr.Expr {
+	return expr.Desc(c.Column)
+}
+
+// bind 绑定实体的列,值类型与字段类型不一致时返回错误
+func (c *Col[V]) bind(col *Column) error {
+	if t := reflect.TypeOf((*V)(nil)).Elem(); t != col.Type {
+		return fmt.Errorf("%s is %s, but declared as %s", col.Name, col.Type, t)
+	}
+	c.Column = col
+	return nil
+}
+
+// NewCols 按照实体T绑定列集合C:C的每个 Col 字段按照字段名(或列名)匹配实体的列
+func NewCols[T any, C any]() (C, error) {
+	var (
+		cols   C
+		entity = NewEntity(new(T))
+	)
+	v := reflect.ValueOf(&cols).Elem()
+	if v.Kind() != reflect.Struct {
+		return cols, fmt.Errorf("columns of %s must be a struct, got %s", entity.Name, v.Type())
+	}
+	for idx := 0; idx < v.NumField(); idx++ {
+		field := v.Type().Field(idx)
+		binder, ok := v.Field(idx).Addr().Interface().(interface{ bind(*Column) error })
+		if !field.IsExported() || !ok {
+			continue
+		}
+		col := entity.Column(field.Name)
+		if col == nil {
+			//展开的结构体字段按照列名匹配,例如 AddrStreet 匹配 addr_street
+			col = entity.Column(utils.LowerCase(field.Name))
+		}
+		if col == nil {
+			return cols, fmt.Errorf("column %s not found in %s", field.Name, entity.Name)
+		}
+		if err := binder.bind(col); err != nil {
+			return cols, fmt.Errorf("column %s.%w", entity.Name, err)
+		}
+	}
+	return cols, nil
+}
+
+// MustCols 同 NewCols,绑定失败时panic(通常在包变量初始化时使用)
+func MustCols[T any, C any]() C {
+	return utils.Must(NewCols[T, C]())
+}
+
+// deref 指针类型的值取其指向的值,空指针为nil(转换为 IS NULL)
+func deref(value any) any {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr {
+		return value
+	}
+	if v.IsNil() {
+		return nil
+	}
+	return v.Elem().Interface()
+}
+
+// bindVar 值作为绑定参数(自动命名),空指针为nil(转换为 IS NULL)
+func bindVar(value any) any {
+	if v := deref(value); v != nil {
+		return expr.AutoVar(v)
+	}
+	return nil
+}
+
+func derefAll[V any](values []V) []any {
+	var list []any
+	for _, value := range values {
+		list = append(list, deref(value))
+	}
+	return list
+}
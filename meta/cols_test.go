@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package meta
+
+import (
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type colRole string
+
+type colUser struct {
+	Id       int64
+	Name     string
+	Nickname *string
+	Role     colRole
+	Score    float64
+	Birthday time.Time
+}
+
+type colUserColumns struct {
+	Id       Col[int64]
+	Name     Col[string]
+	Nickname Col[*string]
+	Role     Col[colRole]
+	Score    Col[float64]
+	Birthday Col[time.Time]
+}
+
+func TestCols(t *testing.T) {
+	cols := MustCols[colUser, colUserColumns]()
+	nickname := "nick"
+	day := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		exp  expr.Expr
+		//want 期望的SQL(正则),自动命名的参数为 :param_N
+		want     string
+		wantArgs []any
+	}{
+		{name: "eq", exp: cols.Name.Eq("admin"), want: "^`name` = :param_\\d+$", wantArgs: []any{"admin"}},
+		{name: "ne", exp: cols.Id.Ne(1), want: "^`id` != :param_\\d+$", wantArgs: []any{int64(1)}},
+		{name: "named type with quote", exp: cols.Role.Eq("x' OR '1'='1"), want: "^`role` = :param_\\d+$", wantArgs: []any{colRole("x' OR '1'='1")}},
+		{name: "float", exp: cols.Score.Gt(0.0000004), want: "^`score` > :param_\\d+$", wantArgs: []any{0.0000004}},
+		{name: "pointer", exp: cols.Nickname.Eq(&nickname), want: "^`nickname` = :param_\\d+$", wantArgs: []any{"nick"}},
+		{name: "nil pointer", exp: cols.Nickname.Eq(nil), want: "^`nickname` IS NULL$", wantArgs: []any{}},
+		{name: "set", exp: cols.Name.Set("admin"), want: "^`name` = :param_\\d+$", wantArgs: []any{"admin"}},
+		{name: "set nil pointer", exp: cols.Nickname.Set(nil), want: "^`nickname` = :param_\\d+$", wantArgs: []any{nil}},
+		{name: "is not null", exp: cols.Nickname.IsNotNull(), want: "^`nickname` IS NOT NULL$", wantArgs: []any{}},
+		{name: "like", exp: cols.Name.Like("ad%"), want: "^`name` LIKE :param_\\d+$", wantArgs: []any{"ad%"}},
+		{name: "in", exp: cols.Id.In(1, 2), want: "^`id` IN \\( :id_0,:id_1 \\)$", wantArgs: []any{int64(1), int64(2)}},
+		{name: "between", exp: cols.Birthday.Between(day, day.AddDate(0, 1, 0)), want: "^`birthday` BETWEEN :param_\\d+ AND :param_\\d+$", wantArgs: []any{day, day.AddDate(0, 1, 0)}},
+		{name: "desc", exp: cols.Birthday.Desc(), want: "^`birthday` DESC$", wantArgs: []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := expr.NewTracedBuffer(dialect.MySQL).BuildNamed(tt.exp)
+			assert.NoError(t, err)
+			assert.Regexp(t, tt.want, query)
+			values := []any{}
+			for _, arg := range args {
+				values = append(values, arg)
+			}
+			assert.ElementsMatch(t, tt.wantArgs, values)
+		})
+	}
+}
+
+func TestNewCols(t *testing.T) {
+	_, err := NewCols[colUser, struct{ Id Col[string] }]()
+	assert.EqualError(t, err, "column colUser.Id is int64, but declared as string")
+	_, err = NewCols[colUser, struct{ Email Col[string] }]()
+	assert.EqualError(t, err, "column Email not found in colUser")
+	_, err = NewCols[colUser, int]()
+	assert.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gnodux/sqlxx/expr"
 	"github.com/gnodux/sqlxx/utils"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +19,19 @@ const (
 	MarkIgnore    = "_"
 	MarkTenantKey = "tenantKey"
 	MarkIsDeleted = "softDelete"
+	MarkSensitive = "sensitive"
+	//MarkSize 长度,例如 dbx:"size:64"
+	MarkSize = "size"
+	//MarkIndex 创建索引
+	MarkIndex = "index"
+	//MarkUnique 唯一约束
+	MarkUnique = "unique"
+	//MarkDefault 默认值(原样写入DDL,不能包含逗号),例如 dbx:"default:0"
+	MarkDefault = "default"
+	//MarkEmbedded 结构体字段展开为多个列(列名不带前缀)
+	MarkEmbedded = "embedded"
+	//MarkPrefix 结构体字段展开为多个列,列名带前缀,例如 dbx:"prefix:addr_"
+	MarkPrefix = "prefix"
 )
 
 var ()
@@ -26,6 +40,11 @@ type TableName interface {
 	TableName() string
 }
 
+// Auditable 需要记录变更审计的实体(Audit返回true时 BaseMapper 会记录更新、删除前后的列差异)
+type Auditable interface {
+	Audit() bool
+}
+
 type Entity struct {
 	Columns        []*Column
 	TableName      string
@@ -34,6 +53,10 @@ type Entity struct {
 	PrimaryKey     *Column
 	TenantKey      *Column
 	LogicDeleteKey *Column
+	//Audit 是否记录变更审计(参考 Auditable)
+	Audit bool
+	//Relations 关联关系(参考 Relation)
+	Relations []*Relation
 }
 
 func (m *Entity) String() string {
@@ -43,10 +66,16 @@ func (m *Entity) String() string {
 func (m *Entity) Format(buffer *expr.TracedBuffer) {
 	buffer.AppendString(buffer.SQLNameFunc(m.TableName))
 }
+
+// ColumnExprs 查询的列,嵌入结构体的列使用字段路径作为别名(参考 Column.Path)
 func (m *Entity) ColumnExprs() []expr.Expr {
 	var exprs []expr.Expr
 	for _, col := range m.Columns {
-		exprs = append(exprs, col)
+		if col.Nested() {
+			exprs = append(exprs, expr.Alias(col, col.Path))
+		} else {
+			exprs = append(exprs, col)
+		}
 	}
 	return exprs
 }
@@ -71,13 +100,26 @@ func (m *Entity) Column(name string) *Column {
 }
 
 type Column struct {
-	Name             string
-	ColumnName       string
+	//Name 字段名,嵌入结构体的字段为完整路径,例如 Address.Street
+	Name       string
+	ColumnName string
+	//Path sqlx映射的字段路径,命名参数和查询结果的列名使用该路径,例如 address.street
+	Path             string
 	Type             reflect.Type
 	IsPrimaryKey     bool
 	IsTenantKey      bool
 	IsLogicDeleteKey bool
 	Ignore           bool
+	//Sensitive 敏感字段,日志中的参数会被脱敏
+	Sensitive bool
+	//Size 长度(生成DDL时使用,字符串默认为255)
+	Size int
+	//Index 是否创建索引
+	Index bool
+	//Unique 是否唯一
+	Unique bool
+	//Default 默认值表达式(为空表示没有默认值)
+	Default string
 }
 
 func (c *Column) String() string {
@@ -88,13 +130,50 @@ func (c *Column) Format(buffer *expr.TracedBuffer) {
 	buffer.AppendString(buffer.SQLNameFunc(c.ColumnName))
 }
 
+// Nested 是否为嵌入结构体的列(字段路径与列名不一致)
+func (c *Column) Nested() bool {
+	return c.Path != "" && c.Path != c.ColumnName
+}
+
+// ArgName 命名参数的名称
+func (c *Column) ArgName() string {
+	if c.Path == "" {
+		return c.ColumnName
+	}
+	return c.Path
+}
+
+// FieldValue 获取结构体(或指针)中列对应的字段,alloc为true时为路径上的空指针分配内存,否则返回无效值
+func (c *Column) FieldValue(v reflect.Value, alloc bool) reflect.Value {
+	for _, name := range strings.Split(c.Name, ".") {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				if !alloc || v.Kind() == reflect.Interface || !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		v = v.FieldByName(name)
+	}
+	return v
+}
+
 func NewEntity(v any) *Entity {
 	meta := &Entity{
 		TableName: GetTableName(v),
 		Columns:   ListValueColumns(v),
+		Relations: ListRelations(reflect.TypeOf(v)),
 		Type:      reflect.TypeOf(v),
 		Name:      GetTypeName(v),
 	}
+	if a, ok := v.(Auditable); ok {
+		meta.Audit = a.Audit()
+	}
 	utils.Each(meta.Columns, func(idx int, col *Column) bool {
 		if col.IsTenantKey {
 			meta.TenantKey = col
@@ -116,25 +195,60 @@ func ListValueColumns(v any) []*Column {
 
 }
 func ListColumns(t reflect.Type) []*Column {
+	return listColumns(t, "", "", "")
+}
+
+// listColumns 获取结构体的列,name/path/prefix 分别为嵌入结构体的字段名、字段路径和列名前缀
+func listColumns(t reflect.Type, name, path, prefix string) []*Column {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 	var fields []*Column
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if field.IsExported() {
-			if field.Anonymous {
-				fields = append(fields, ListColumns(field.Type)...)
-			} else {
-				fields = append(fields, NewColumnDefWith(t.Field(i)))
-			}
+		if !field.IsExported() || IsRelation(field) {
+			continue
+		}
+		embedded, fieldPrefix := embeddedTag(field)
+		switch {
+		case field.Anonymous:
+			fields = append(fields, listColumns(field.Type, name, path, prefix+fieldPrefix)...)
+		case embedded:
+			fields = append(fields, listColumns(field.Type, name+field.Name+".", path+utils.LowerCase(field.Name)+".", prefix+fieldPrefix)...)
+		default:
+			fields = append(fields, newColumn(field, name, path, prefix))
 		}
 	}
 	return fields
 }
+
+// embeddedTag 字段是否展开为多个列及列名前缀
+func embeddedTag(f reflect.StructField) (embedded bool, prefix string) {
+	for _, tag := range strings.Split(f.Tag.Get(TagField), ",") {
+		if tag == MarkEmbedded {
+			embedded = true
+		} else if value, ok := strings.CutPrefix(tag, MarkPrefix+":"); ok {
+			embedded, prefix = true, value
+		}
+	}
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return embedded && t.Kind() == reflect.Struct, prefix
+}
 func parseTags(col *Column, tags string) {
 	tagList := strings.Split(tags, ",")
 	for _, tag := range tagList {
+		if name, value, ok := strings.Cut(tag, ":"); ok {
+			switch name {
+			case MarkSize:
+				col.Size, _ = strconv.Atoi(value)
+			case MarkDefault:
+				col.Default = value
+			}
+			continue
+		}
 		switch tag {
 		case MarkPK:
 			col.IsPrimaryKey = true
@@ -144,14 +258,31 @@ func parseTags(col *Column, tags string) {
 			col.IsTenantKey = true
 		case MarkIsDeleted:
 			col.IsLogicDeleteKey = true
+		case MarkSensitive:
+			col.Sensitive = true
+		case MarkIndex:
+			col.Index = true
+		case MarkUnique:
+			col.Unique = true
 		}
 	}
 }
 func NewColumnDefWith(f reflect.StructField) *Column {
+	return newColumn(f, "", "", "")
+}
+
+// newColumn 创建列,name/path/prefix 参考 listColumns
+func newColumn(f reflect.StructField, name, path, prefix string) *Column {
 	col := &Column{}
 	parseTags(col, f.Tag.Get(TagField))
-	col.Name = f.Name
-	col.ColumnName = utils.LowerCase(f.Name)
+	col.Name = name + f.Name
+	col.Path = path + utils.LowerCase(f.Name)
+	col.ColumnName = prefix + utils.LowerCase(f.Name)
+	col.Type = f.Type
+	if name != "" {
+		//展开的结构体字段只能通过标签指定主键、租户等
+		return col
+	}
 	if col.ColumnName == "tenant_id" {
 		col.IsTenantKey = true
 	}
@@ -161,7 +292,6 @@ func NewColumnDefWith(f reflect.StructField) *Column {
 	if col.ColumnName == "is_deleted" {
 		col.IsLogicDeleteKey = true
 	}
-	col.Type = f.Type
 	return col
 }
 
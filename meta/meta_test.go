@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package meta
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type embeddedAddress struct {
+	Id     int64
+	Street string
+}
+
+type EmbeddedBase struct {
+	Code string
+}
+
+type embeddedEntity struct {
+	Id           int64
+	Home         embeddedAddress  `dbx:"prefix:home_"`
+	Work         *embeddedAddress `dbx:"embedded,prefix:work_"`
+	Plain        embeddedAddress  `dbx:"embedded"`
+	EmbeddedBase `dbx:"prefix:base_"`
+}
+
+func TestListColumns_Embedded(t *testing.T) {
+	entity := NewEntity(&embeddedEntity{})
+	var got [][3]string
+	for _, col := range entity.Columns {
+		got = append(got, [3]string{col.Name, col.ColumnName, col.Path})
+	}
+	assert.Equal(t, [][3]string{
+		{"Id", "id", "id"},
+		{"Home.Id", "home_id", "home.id"},
+		{"Home.Street", "home_street", "home.street"},
+		{"Work.Id", "work_id", "work.id"},
+		{"Work.Street", "work_street", "work.street"},
+		{"Plain.Id", "id", "plain.id"},
+		{"Plain.Street", "street", "plain.street"},
+		{"Code", "base_code", "code"},
+	}, got)
+	assert.Equal(t, "Id", entity.PrimaryKey.Name)
+
+	v := &embeddedEntity{}
+	work := entity.Column("work_street")
+	assert.False(t, work.FieldValue(reflect.ValueOf(v), false).IsValid())
+	work.FieldValue(reflect.ValueOf(v).Elem(), true).SetString("Nanjing Road")
+	assert.Equal(t, "Nanjing Road", v.Work.Street)
+}
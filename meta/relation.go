@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package meta
+
+import (
+	"github.com/gnodux/sqlxx/utils"
+	"reflect"
+	"strings"
+)
+
+// RelationKind 关联关系类型
+type RelationKind string
+
+const (
+	//HasOne 一对一,外键在关联实体上,例如 dbx:"hasOne,foreignKey:user_id"
+	HasOne RelationKind = "hasOne"
+	//HasMany 一对多,外键在关联实体上,例如 dbx:"hasMany,foreignKey:user_id"
+	HasMany RelationKind = "hasMany"
+	//BelongsTo 属于,外键在当前实体上,例如 dbx:"belongsTo,foreignKey:tenant_id"
+	BelongsTo RelationKind = "belongsTo"
+	//ManyToMany 多对多,通过中间表关联,例如 dbx:"manyToMany:user_role,foreignKey:user_id,references:role_id"
+	ManyToMany RelationKind = "manyToMany"
+)
+
+const (
+	//MarkForeignKey 外键列名
+	MarkForeignKey = "foreignKey"
+	//MarkReferences 多对多中间表中引用关联实体主键的列名
+	MarkReferences = "references"
+)
+
+// Relation 关联关系(关联字段不是列,不参与查询和写入)
+type Relation struct {
+	//Name 字段名
+	Name string
+	Kind RelationKind
+	//Type 字段类型,HasMany/ManyToMany 为切片,其他为结构体或结构体指针
+	Type reflect.Type
+	//Target 关联实体的结构体类型
+	Target reflect.Type
+	//ForeignKey 外键列名:HasOne/HasMany 为关联实体上引用当前实体主键的列;BelongsTo 为当前实体上引用关联实体主键的列;
+	//ManyToMany 为中间表上引用当前实体主键的列。默认为 <实体名>_id(BelongsTo 为 <字段名>_id)
+	ForeignKey string
+	//References 中间表上引用关联实体主键的列(仅ManyToMany),默认为 <关联实体名>_id
+	References string
+	//JoinTable 中间表(仅ManyToMany),默认为 <实体名>_<关联实体名>
+	JoinTable string
+}
+
+// Many 关联字段是否为切片
+func (r *Relation) Many() bool {
+	return r.Kind == HasMany || r.Kind == ManyToMany
+}
+
+// Relation 根据字段名获取关联关系
+func (m *Entity) Relation(name string) *Relation {
+	for _, rel := range m.Relations {
+		if rel.Name == name {
+			return rel
+		}
+	}
+	return nil
+}
+
+// ListRelations 获取结构体的关联关系(包含匿名嵌入的结构体)
+func ListRelations(t reflect.Type) []*Relation {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var relations []*Relation
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			relations = append(relations, ListRelations(field.Type)...)
+		} else if rel := NewRelation(t, field); rel != nil {
+			relations = append(relations, rel)
+		}
+	}
+	return relations
+}
+
+// NewRelation 解析字段的关联关系,不是关联字段时返回nil
+func NewRelation(owner reflect.Type, f reflect.StructField) *Relation {
+	rel := &Relation{Name: f.Name, Type: f.Type}
+	for _, tag := range strings.Split(f.Tag.Get(TagField), ",") {
+		name, value, _ := strings.Cut(tag, ":")
+		switch name {
+		case string(HasOne), string(HasMany), string(BelongsTo):
+			rel.Kind = RelationKind(name)
+		case string(ManyToMany):
+			rel.Kind, rel.JoinTable = ManyToMany, value
+		case MarkForeignKey:
+			rel.ForeignKey = value
+		case MarkReferences:
+			rel.References = value
+		}
+	}
+	if rel.Kind == "" {
+		return nil
+	}
+	rel.Target = f.Type
+	for rel.Target.Kind() == reflect.Ptr || rel.Target.Kind() == reflect.Slice {
+		rel.Target = rel.Target.Elem()
+	}
+	ownerName, targetName := utils.LowerCase(owner.Name()), utils.LowerCase(rel.Target.Name())
+	if rel.ForeignKey == "" {
+		if rel.Kind == BelongsTo {
+			rel.ForeignKey = utils.LowerCase(f.Name) + "_id"
+		} else {
+			rel.ForeignKey = ownerName + "_id"
+		}
+	}
+	if rel.Kind == ManyToMany {
+		if rel.References == "" {
+			rel.References = targetName + "_id"
+		}
+		if rel.JoinTable == "" {
+			rel.JoinTable = ownerName + "_" + targetName
+		}
+	}
+	return rel
+}
+
+// IsRelation 字段是否为关联字段
+func IsRelation(f reflect.StructField) bool {
+	for _, tag := range strings.Split(f.Tag.Get(TagField), ",") {
+		name, _, _ := strings.Cut(tag, ":")
+		switch RelationKind(name) {
+		case HasOne, HasMany, BelongsTo, ManyToMany:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package meta
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type relRole struct {
+	Id int64
+}
+
+type relAccount struct {
+	Id       int64
+	TenantId int64
+	Profile  *relRole  `dbx:"hasOne,foreignKey:owner_id"`
+	Roles    []relRole `dbx:"manyToMany"`
+	Managers []relRole `dbx:"manyToMany:account_manager,foreignKey:account,references:manager"`
+	Tenant   relRole   `dbx:"belongsTo"`
+}
+
+func TestListRelations(t *testing.T) {
+	entity := NewEntity(relAccount{})
+	assert.Len(t, entity.Columns, 2)
+	tests := []struct {
+		name string
+		want Relation
+	}{
+		{name: "Profile", want: Relation{Kind: HasOne, ForeignKey: "owner_id"}},
+		{name: "Roles", want: Relation{Kind: ManyToMany, ForeignKey: "rel_account_id", References: "rel_role_id", JoinTable: "rel_account_rel_role"}},
+		{name: "Managers", want: Relation{Kind: ManyToMany, ForeignKey: "account", References: "manager", JoinTable: "account_manager"}},
+		{name: "Tenant", want: Relation{Kind: BelongsTo, ForeignKey: "tenant_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel := entity.Relation(tt.name)
+			if !assert.NotNil(t, rel) {
+				return
+			}
+			assert.Equal(t, tt.want.Kind, rel.Kind)
+			assert.Equal(t, tt.want.ForeignKey, rel.ForeignKey)
+			assert.Equal(t, tt.want.References, rel.References)
+			assert.Equal(t, tt.want.JoinTable, rel.JoinTable)
+			assert.Equal(t, "relRole", rel.Target.Name())
+		})
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// Package migrate 数据库结构迁移
+//
+// 从 fs.FS(可以使用embed.FS)中读取版本化的迁移文件，文件名格式为"版本_名称.up.sql"和"版本_名称.down.sql",
+// 例如 0001_create_user.up.sql,版本为整数。已执行的版本和校验和记录在历史表中，执行期间持有数据库锁(参考 sqlxx.DB.Lock)
+//
+// 数据库使用咨询锁时锁占用一个连接,迁移在连接池的其他连接上执行,连接池最大连接数为1时返回 ErrPoolTooSmall。
+// DDL不支持事务的数据库(例如MySQL)执行迁移前在历史表中记录dirty标记,迁移失败时标记保留,
+// 之后的 Up/Down 返回 ErrDirty,需要人工修复数据库后删除(回滚失败时更新dirty为false)该版本的历史记录
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultTable 默认的迁移历史表名称
+	DefaultTable = "sqlxx_schema_history"
+	// DefaultLockTimeout 默认的获取迁移锁超时时间
+	DefaultLockTimeout = time.Minute
+)
+
+var (
+	// ErrUnsupportedDialect 不支持的数据库方言
+	ErrUnsupportedDialect = errors.New("migrate: unsupported dialect")
+	// ErrChecksumMismatch 已执行的迁移文件被修改
+	ErrChecksumMismatch = errors.New("migrate: checksum mismatch")
+	// ErrNoDownMigration 回滚的版本没有down文件
+	ErrNoDownMigration = errors.New("migrate: no down migration")
+	// ErrMissingMigration 已执行的版本没有对应的迁移文件
+	ErrMissingMigration = errors.New("migrate: missing migration")
+	// ErrDirty 迁移执行失败(DDL不支持事务),数据库处于未知状态,需要人工修复
+	ErrDirty = errors.New("migrate: dirty database")
+	// ErrPoolTooSmall 咨询锁占用唯一的连接,迁移无法执行
+	ErrPoolTooSmall = errors.New("migrate: advisory lock needs more than one connection")
+)
+
+// fileRegexp 迁移文件名称:版本_名称.up.sql/版本_名称.down.sql
+var fileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration 迁移
+type Migration struct {
+	Version int64
+	Name    string
+	//Up 升级语句
+	Up string
+	//Down 回滚语句,为空表示不支持回滚
+	Down string
+	//Checksum Up语句的校验和(sha256)
+	Checksum string
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Status 迁移状态
+type Status struct {
+	*Migration
+	//Applied 是否已执行
+	Applied bool
+	//AppliedAt 执行时间
+	AppliedAt time.Time
+	//Modified 执行后迁移文件被修改(校验和不一致)
+	Modified bool
+	//Missing 已执行但迁移文件不存在(Migration只包含版本和名称)
+	Missing bool
+	//Dirty 迁移执行失败,需要人工修复
+	Dirty bool
+}
+
+// Option 迁移配置
+type Option func(m *Migrator)
+
+// WithTable 设置迁移历史表名称,默认为 DefaultTable
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithDir 设置迁移文件所在的目录,默认为根目录
+func WithDir(dir string) Option {
+	return func(m *Migrator) {
+		m.dir = dir
+	}
+}
+
+// WithLockTimeout 设置获取迁移锁的超时时间,默认为 DefaultLockTimeout
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithDryRun 只输出需要执行的迁移和语句(日志),不执行迁移也不修改历史表
+func WithDryRun(dryRun bool) Option {
+	return func(m *Migrator) {
+		m.dryRun = dryRun
+	}
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db          *sqlxx.DB
+	table       string
+	dir         string
+	lockTimeout time.Duration
+	dryRun      bool
+	migrations  []*Migration
+}
+
+// New 创建迁移执行器并加载迁移文件
+func New(db *sqlxx.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	m := &Migrator{
+		db:          db,
+		table:       DefaultTable,
+		dir:         ".",
+		lockTimeout: DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	var err error
+	if m.migrations, err = load(fsys, m.dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Migrations 已加载的迁移(按版本排序)
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// load 读取目录中的迁移文件(不匹配文件名格式的文件被忽略)
+func load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[int64]*Migration{}
+	for _, entry := range entries {
+		matches := fileRegexp.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := versions[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: matches[2]}
+			versions[version] = mig
+		} else if mig.Name != matches[2] {
+			return nil, fmt.Errorf("migrate: duplicate version %d: %s, %s", version, mig.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+	migrations := make([]*Migration, 0, len(versions))
+	for _, mig := range versions {
+		if mig.Checksum == "" {
+			return nil, fmt.Errorf("migrate: version %d has no up migration", mig.Version)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
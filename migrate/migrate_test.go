@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package migrate
+
+import (
+	"context"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/dialect"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testDSN 测试数据库(MySQL)
+const testDSN = "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true"
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"single", "CREATE TABLE t (id INT)", []string{"CREATE TABLE t (id INT)"}},
+		{"multiple", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n", []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{"string", "INSERT INTO t VALUES ('a;b');INSERT INTO t VALUES ('it''s')", []string{"INSERT INTO t VALUES ('a;b')", "INSERT INTO t VALUES ('it''s')"}},
+		{"quoted name", "SELECT `a;b` FROM [c;d]", []string{"SELECT `a;b` FROM [c;d]"}},
+		{"comments", "-- drop; table\nDROP TABLE a; /* ; */\n-- only comment;", []string{"-- drop; table\nDROP TABLE a"}},
+		{"dollar quote", "CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE sql; SELECT $1",
+			[]string{"CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $1"}},
+		{"empty", " ;\n; ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Split(tt.script))
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	tests := []struct {
+		driver *dialect.Driver
+		table  string
+		want   string
+	}{
+		{dialect.MySQL, "schema_migrations", "CREATE TABLE IF NOT EXISTS `schema_migrations`"},
+		{dialect.SQLServer, "schema_migrations", "IF OBJECT_ID(N'[schema_migrations]', N'U') IS NULL\nCREATE TABLE [schema_migrations]"},
+		{dialect.SQLServer, "o'neil", "IF OBJECT_ID(N'[o''neil]', N'U') IS NULL\nCREATE TABLE [o'neil]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver.Name+"/"+tt.table, func(t *testing.T) {
+			schema, err := Schema(tt.driver, tt.table)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(schema, tt.want), schema)
+		})
+	}
+	_, err := Schema(&dialect.Driver{Name: "unknown"}, "schema_migrations")
+	assert.ErrorIs(t, err, ErrUnsupportedDialect)
+}
+
+func TestMigrator(t *testing.T) {
+	f := sqlxx.NewFactory("migrate")
+	defer f.Shutdown()
+	db, err := f.Open(sqlxx.DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+	history, table := "history_"+suffix, "migrated_"+suffix
+	fsys := fstest.MapFS{
+		"migrations/0001_create.up.sql":   {Data: []byte("CREATE TABLE " + table + " (id BIGINT PRIMARY KEY);")},
+		"migrations/0001_create.down.sql": {Data: []byte("DROP TABLE " + table + ";")},
+		"migrations/0002_seed.up.sql":     {Data: []byte("INSERT INTO " + table + " VALUES (1);\nINSERT INTO " + table + " VALUES (2);")},
+		"migrations/0002_seed.down.sql":   {Data: []byte("DELETE FROM " + table + ";")},
+		"migrations/README.md":            {Data: []byte("ignored")},
+	}
+	ctx := context.Background()
+	newMigrator := func(opts ...Option) *Migrator {
+		m, err := New(db, fsys, append([]Option{WithDir("migrations"), WithTable(history)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	defer db.Execxx("DROP TABLE IF EXISTS " + history)
+
+	t.Run("dry run", func(t *testing.T) {
+		applied, err := newMigrator(WithDryRun(true)).Up(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, applied, 2)
+		statuses, err := newMigrator(WithDryRun(true)).Status(ctx)
+		assert.NoError(t, err)
+		assert.False(t, statuses[0].Applied)
+	})
+	t.Run("up", func(t *testing.T) {
+		m := newMigrator()
+		applied, err := m.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, m.Migrations(), applied)
+		var count int
+		assert.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM "+table))
+		assert.Equal(t, 2, count)
+		applied, err = m.Up(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+	t.Run("status", func(t *testing.T) {
+		statuses, err := newMigrator().Status(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, statuses, 2) {
+			assert.True(t, statuses[0].Applied)
+			assert.False(t, statuses[0].Modified)
+			assert.Equal(t, "2_seed", statuses[1].String())
+		}
+	})
+	t.Run("checksum mismatch", func(t *testing.T) {
+		fsys["migrations/0002_seed.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO " + table + " VALUES (3);")}
+		defer func() {
+			fsys["migrations/0002_seed.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO " + table + " VALUES (1);\nINSERT INTO " + table + " VALUES (2);")}
+		}()
+		m := newMigrator()
+		_, err := m.Up(ctx)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		statuses, err := m.Status(ctx)
+		assert.NoError(t, err)
+		assert.True(t, statuses[1].Modified)
+	})
+	t.Run("dirty", func(t *testing.T) {
+		fsys["migrations/0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO " + table + " VALUES (3);\nINSERT INTO missing_" + suffix + " VALUES (1);")}
+		defer delete(fsys, "migrations/0003_broken.up.sql")
+		m := newMigrator()
+		_, err := m.Up(ctx)
+		assert.ErrorIs(t, err, ErrDirty)
+		statuses, err := m.Status(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, statuses, 3) {
+			assert.True(t, statuses[2].Applied)
+			assert.True(t, statuses[2].Dirty)
+		}
+		_, err = m.Up(ctx)
+		assert.ErrorIs(t, err, ErrDirty)
+		_, err = m.Down(ctx, 1)
+		assert.ErrorIs(t, err, ErrDirty)
+		//人工修复后删除失败的记录
+		_, err = db.Exec("DELETE FROM " + table + " WHERE id = 3")
+		assert.NoError(t, err)
+		_, err = db.Exec("DELETE FROM " + history + " WHERE version = 3")
+		assert.NoError(t, err)
+	})
+	t.Run("pool too small", func(t *testing.T) {
+		single, err := f.Open("single", "mysql", testDSN, sqlxx.WithMaxOpenConns(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := New(single, fsys, WithDir("migrations"), WithTable(history))
+		assert.NoError(t, err)
+		_, err = m.Up(ctx)
+		assert.ErrorIs(t, err, ErrPoolTooSmall)
+	})
+	t.Run("down", func(t *testing.T) {
+		m := newMigrator()
+		reverted, err := m.Down(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []*Migration{m.Migrations()[1]}, reverted)
+		reverted, err = m.Down(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, []*Migration{m.Migrations()[0]}, reverted)
+		statuses, err := m.Status(ctx)
+		assert.NoError(t, err)
+		assert.False(t, statuses[0].Applied)
+	})
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/expr"
+	"sort"
+	"time"
+)
+
+// record 迁移历史记录
+type record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+	Dirty     bool
+}
+
+// Up 按版本顺序执行所有未执行的迁移,返回执行的迁移
+//
+// 已执行的迁移文件被修改时返回 ErrChecksumMismatch,存在执行失败的迁移时返回 ErrDirty,不执行任何迁移
+func (m *Migrator) Up(ctx context.Context) (applied []*Migration, err error) {
+	err = m.withLock(ctx, func(ctx context.Context) error {
+		history, err := m.clean(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if rec, ok := history[mig.Version]; ok && rec.Checksum != mig.Checksum {
+				return fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := history[mig.Version]; ok {
+				continue
+			}
+			if err = m.run(ctx, mig, true); err != nil {
+				return err
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return
+}
+
+// Down 按版本倒序回滚最近执行的n个迁移,返回回滚的迁移
+//
+// 需要回滚的迁移没有down文件(或迁移文件不存在)、存在执行失败的迁移时返回错误,不执行任何回滚
+func (m *Migrator) Down(ctx context.Context, n int) (reverted []*Migration, err error) {
+	err = m.withLock(ctx, func(ctx context.Context) error {
+		history, err := m.clean(ctx)
+		if err != nil {
+			return err
+		}
+		var targets []*Migration
+		for idx := len(m.migrations) - 1; idx >= 0 && len(targets) < n; idx-- {
+			if _, ok := history[m.migrations[idx].Version]; ok {
+				targets = append(targets, m.migrations[idx])
+				delete(history, m.migrations[idx].Version)
+			}
+		}
+		for _, rec := range history {
+			if len(targets) > 0 && rec.Version > targets[len(targets)-1].Version {
+				return fmt.Errorf("%w: %d_%s", ErrMissingMigration, rec.Version, rec.Name)
+			}
+		}
+		for _, mig := range targets {
+			if mig.Down == "" {
+				return fmt.Errorf("%w: %s", ErrNoDownMigration, mig)
+			}
+		}
+		for _, mig := range targets {
+			if err = m.run(ctx, mig, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return
+}
+
+// Status 获取所有迁移的状态(按版本排序),包括已执行但迁移文件不存在的版本
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	history, err := m.history(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []*Status
+	for _, mig := range m.migrations {
+		status := &Status{Migration: mig}
+		if rec, ok := history[mig.Version]; ok {
+			status.Applied, status.AppliedAt, status.Modified = true, rec.AppliedAt, rec.Checksum != mig.Checksum
+			status.Dirty = rec.Dirty
+			delete(history, mig.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, rec := range history {
+		statuses = append(statuses, &Status{
+			Migration: &Migration{Version: rec.Version, Name: rec.Name, Checksum: rec.Checksum},
+			Applied:   true,
+			AppliedAt: rec.AppliedAt,
+			Missing:   true,
+			Dirty:     rec.Dirty,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// withLock 创建历史表并持有迁移锁执行fn(dry-run不创建历史表,也不加锁)
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.dryRun {
+		return fn(ctx)
+	}
+	//咨询锁占用一个连接,迁移需要另一个连接
+	if m.db.Driver().AdvisoryLock != "" && m.db.Stats().MaxOpenConnections == 1 {
+		return ErrPoolTooSmall
+	}
+	schema, err := Schema(m.db.Driver(), m.table)
+	if err != nil {
+		return err
+	}
+	if _, err = m.db.ExecxxContext(ctx, schema); err != nil {
+		return err
+	}
+	return m.db.WithLock(ctx, "migrate:"+m.table, m.lockTimeout, fn)
+}
+
+// history 读取已执行的迁移,dry-run时历史表不存在视为没有执行过迁移
+func (m *Migrator) history(ctx context.Context) (map[int64]*record, error) {
+	var records []*record
+	query := expr.Select(expr.Name("version"), expr.Name("name"), expr.Name("checksum"), expr.Name("applied_at"), expr.Name("dirty")).
+		From(expr.Name(m.table))
+	if err := m.db.SelectExprContext(ctx, &records, query); err != nil {
+		if m.dryRun {
+			m.db.Logger().Warn("migrate(dry-run): read history failed: ", err)
+			return map[int64]*record{}, nil
+		}
+		return nil, err
+	}
+	history := make(map[int64]*record, len(records))
+	for _, rec := range records {
+		history[rec.Version] = rec
+	}
+	return history, nil
+}
+
+// clean 读取已执行的迁移,存在执行失败(dirty)的迁移时返回 ErrDirty
+func (m *Migrator) clean(ctx context.Context) (map[int64]*record, error) {
+	history, err := m.history(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range history {
+		if rec.Dirty {
+			return nil, fmt.Errorf("%w: %d_%s", ErrDirty, rec.Version, rec.Name)
+		}
+	}
+	return history, nil
+}
+
+// run 执行迁移并更新历史表,数据库支持事务性DDL时在同一事务中执行
+func (m *Migrator) run(ctx context.Context, mig *Migration, up bool) error {
+	script, direction := mig.Up, "up"
+	if !up {
+		script, direction = mig.Down, "down"
+	}
+	statements := Split(script)
+	logger := m.db.Logger()
+	if m.dryRun {
+		logger.Info("migrate(dry-run) ", direction, " ", mig)
+		for _, stmt := range statements {
+			logger.Info(stmt)
+		}
+		return nil
+	}
+	logger.Info("migrate ", direction, " ", mig)
+	table, version := expr.Name(m.table), expr.Eq(expr.Name("version"), expr.Var("version", mig.Version))
+	insert := expr.InsertInto(table).
+		SetExpr(expr.Name("version"), expr.Var("version", mig.Version)).
+		SetExpr(expr.Name("name"), expr.Var("name", mig.Name)).
+		SetExpr(expr.Name("checksum"), expr.Var("checksum", mig.Checksum)).
+		SetExpr(expr.Name("applied_at"), expr.Var("applied_at", time.Now()))
+	var historyExpr expr.Expr = expr.Delete(table).Where(version)
+	if up {
+		historyExpr = insert
+	}
+	if !m.db.Driver().TransactionalDDL {
+		//DDL不支持事务,执行前标记dirty(失败时保留标记),成功后清除标记(回滚时删除记录)
+		var dirtyExpr expr.Expr = expr.Update(table).Set(expr.Eq(expr.Name("dirty"), expr.Var("dirty", true))).Where(version)
+		if up {
+			dirtyExpr = insert.SetExpr(expr.Name("dirty"), expr.Var("dirty", true))
+			historyExpr = expr.Update(table).Set(expr.Eq(expr.Name("dirty"), expr.Var("dirty", false))).Where(version)
+		}
+		if _, err := m.db.ExecExprContext(ctx, dirtyExpr); err != nil {
+			return err
+		}
+		for _, stmt := range statements {
+			if _, err := m.db.ExecxxContext(ctx, stmt); err != nil {
+				return fmt.Errorf("%w: migrate %s %s: %w", ErrDirty, direction, mig, err)
+			}
+		}
+		_, err := m.db.ExecExprContext(ctx, historyExpr)
+		return err
+	}
+	def := sqlxx.TxDefinition{Propagation: sqlxx.PropagationRequiresNew}
+	return m.db.BatchWith(ctx, def, "", func(tx *sqlxx.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Execxx(stmt); err != nil {
+				return fmt.Errorf("migrate %s %s: %w", direction, mig, err)
+			}
+		}
+		_, err := tx.ExecExpr(historyExpr)
+		return err
+	})
+}
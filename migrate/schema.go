@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package migrate
+
+import (
+	"fmt"
+	"github.com/gnodux/sqlxx/dialect"
+	"strings"
+)
+
+// schemas 各数据库方言的历史表建表语句(参数为表名)
+var schemas = map[string]string{
+	dialect.MySQL.Name: `CREATE TABLE IF NOT EXISTS %[1]s
+(
+    version    BIGINT PRIMARY KEY NOT NULL,
+    name       VARCHAR(255)       NOT NULL,
+    checksum   VARCHAR(64)        NOT NULL,
+    applied_at DATETIME           NOT NULL,
+    dirty      BOOLEAN            NOT NULL DEFAULT FALSE
+)`,
+	dialect.Postgres.Name: `CREATE TABLE IF NOT EXISTS %[1]s
+(
+    version    BIGINT PRIMARY KEY NOT NULL,
+    name       VARCHAR(255)       NOT NULL,
+    checksum   VARCHAR(64)        NOT NULL,
+    applied_at TIMESTAMP          NOT NULL,
+    dirty      BOOLEAN            NOT NULL DEFAULT FALSE
+)`,
+	dialect.SQLServer.Name: `IF OBJECT_ID(N%[2]s, N'U') IS NULL
+CREATE TABLE %[1]s
+(
+    version    BIGINT PRIMARY KEY NOT NULL,
+    name       NVARCHAR(255)      NOT NULL,
+    checksum   VARCHAR(64)        NOT NULL,
+    applied_at DATETIME2          NOT NULL,
+    dirty      BIT                NOT NULL DEFAULT 0
+)`,
+	dialect.SQLite.Name: `CREATE TABLE IF NOT EXISTS %[1]s
+(
+    version    INTEGER PRIMARY KEY NOT NULL,
+    name       VARCHAR(255)        NOT NULL,
+    checksum   VARCHAR(64)         NOT NULL,
+    applied_at DATETIME            NOT NULL,
+    dirty      BOOLEAN             NOT NULL DEFAULT 0
+)`,
+}
+
+// Schema 获取迁移历史表的建表语句
+func Schema(driver *dialect.Driver, table string) (string, error) {
+	if driver == nil {
+		return "", ErrUnsupportedDialect
+	}
+	schema, ok := schemas[driver.Name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDialect, driver.Name)
+	}
+	return fmt.Sprintf(schema, driver.SQLNameFunc(table), dialect.QuotedString(driver.SQLNameFunc(table))), nil
+}
+
+// Split 将迁移文件拆分为单条语句(以分号分隔)
+//
+// 忽略字符串、引用名称、注释和Postgres美元符号引用($$...$$)中的分号,不包含有效内容的语句被丢弃
+func Split(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		hasContent bool
+	)
+	flush := func() {
+		if hasContent {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasContent = false
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == ';':
+			flush()
+			continue
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.WriteString(script[i : i+end])
+			i += end - 1
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 2
+			} else {
+				end += 2
+			}
+			current.WriteString(script[i : i+2+end])
+			i += 1 + end
+			continue
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(script[i+1:], closing)
+			if end < 0 {
+				end = len(script) - i - 1
+			} else {
+				end++
+			}
+			current.WriteString(script[i : i+1+end])
+			i += end
+			hasContent = true
+			continue
+		case c == '$':
+			if tag := dollarTag(script[i:]); tag != "" {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					end = len(script) - i - len(tag)
+				} else {
+					end += len(tag)
+				}
+				current.WriteString(script[i : i+len(tag)+end])
+				i += len(tag) + end - 1
+				hasContent = true
+				continue
+			}
+		}
+		current.WriteByte(c)
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			hasContent = true
+		}
+	}
+	flush()
+	return statements
+}
+
+// dollarTag 解析Postgres美元符号引用的标签($$或$tag$),不是美元符号引用时返回空字符串
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
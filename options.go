@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import "time"
+
+// Option 打开数据库时的可选配置(OpenWith/Factory.Open)
+type Option func(*DB)
+
+// WithMaxOpenConns 设置最大打开连接数(<=0 表示不限制)
+func WithMaxOpenConns(n int) Option {
+	return func(d *DB) {
+		d.SetMaxOpenConns(n)
+	}
+}
+
+// WithMaxIdleConns 设置最大空闲连接数(<=0 表示不保留空闲连接)
+func WithMaxIdleConns(n int) Option {
+	return func(d *DB) {
+		d.SetMaxIdleConns(n)
+	}
+}
+
+// WithConnMaxLifetime 设置连接最长存活时间(<=0 表示不过期)
+func WithConnMaxLifetime(du time.Duration) Option {
+	return func(d *DB) {
+		d.SetConnMaxLifetime(du)
+	}
+}
+
+// WithConnMaxIdleTime 设置连接最长空闲时间(<=0 表示不过期)
+func WithConnMaxIdleTime(du time.Duration) Option {
+	return func(d *DB) {
+		d.SetConnMaxIdleTime(du)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// Package otel 基于sqlxx拦截器的OpenTelemetry追踪与指标集成
+//
+// 每条语句生成一个span，并记录耗时、行数直方图:
+//
+//	i, err := otel.NewInterceptor()
+//	factory.Use(i)
+//
+// otel 是独立的module(github.com/gnodux/sqlxx/otel),只有使用时才会引入OpenTelemetry依赖;
+// 本地开发时 otel/go.work 使用仓库中的sqlxx(替换 go.mod 中依赖的版本)
+package otel
+
+import (
+	"context"
+	"github.com/gnodux/sqlxx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// InstrumentationName 追踪与指标的instrumentation名称
+	InstrumentationName = "github.com/gnodux/sqlxx/otel"
+
+	// TemplateKey SQL模版名称,如 builtin/create.sql
+	TemplateKey = attribute.Key("db.sqlxx.template")
+	// MethodKey mapper方法名称,如 UserMapper.ListAll
+	MethodKey = attribute.Key("db.sqlxx.method")
+	// RowsKey 影响(或返回)的行数
+	RowsKey = attribute.Key("db.sqlxx.rows")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	attributes     []attribute.KeyValue
+	withStatement  bool
+}
+
+// Option 拦截器配置
+type Option func(*config)
+
+// WithTracerProvider 指定TracerProvider,默认使用全局的otel.GetTracerProvider()
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider 指定MeterProvider,默认使用全局的otel.GetMeterProvider()
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// WithAttributes 为所有span和指标追加固定属性(如数据源名称)
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attributes = append(c.attributes, attrs...)
+	}
+}
+
+// WithStatement 是否在span中记录db.statement,默认记录
+func WithStatement(enable bool) Option {
+	return func(c *config) {
+		c.withStatement = enable
+	}
+}
+
+// Interceptor 实现sqlxx.Interceptor,为每条语句生成span并记录指标
+type Interceptor struct {
+	cfg      config
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	rows     metric.Int64Histogram
+}
+
+// NewInterceptor 创建拦截器
+func NewInterceptor(opts ...Option) (*Interceptor, error) {
+	cfg := config{withStatement: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.meterProvider == nil {
+		cfg.meterProvider = otel.GetMeterProvider()
+	}
+	i := &Interceptor{
+		cfg:    cfg,
+		tracer: cfg.tracerProvider.Tracer(InstrumentationName, trace.WithSchemaURL(semconv.SchemaURL)),
+	}
+	meter := cfg.meterProvider.Meter(InstrumentationName, metric.WithSchemaURL(semconv.SchemaURL))
+	var err error
+	if i.duration, err = meter.Float64Histogram("db.sqlxx.duration",
+		metric.WithDescription("Duration of sql statements"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if i.rows, err = meter.Int64Histogram("db.sqlxx.rows",
+		metric.WithDescription("Rows affected or returned by sql statements"),
+		metric.WithUnit("{row}")); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Before 开始span
+func (i *Interceptor) Before(ctx context.Context, stmt *sqlxx.Statement) (context.Context, error) {
+	attrs := append(i.attributes(stmt), i.cfg.attributes...)
+	if i.cfg.withStatement {
+		attrs = append(attrs, semconv.DBStatementKey.String(stmt.Query))
+	}
+	ctx, _ = i.tracer.Start(ctx, spanName(stmt),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	return ctx, nil
+}
+
+// After 结束span并记录指标
+func (i *Interceptor) After(ctx context.Context, stmt *sqlxx.Statement) {
+	span := trace.SpanFromContext(ctx)
+	if stmt.RowsAffected >= 0 {
+		span.SetAttributes(RowsKey.Int64(stmt.RowsAffected))
+	}
+	if stmt.Err != nil {
+		span.RecordError(stmt.Err)
+		span.SetStatus(codes.Error, stmt.Err.Error())
+	}
+	span.End()
+
+	attrs := metric.WithAttributes(append(i.attributes(stmt), i.cfg.attributes...)...)
+	i.duration.Record(ctx, stmt.Duration.Seconds(), attrs)
+	if stmt.RowsAffected >= 0 {
+		i.rows.Record(ctx, stmt.RowsAffected, attrs)
+	}
+}
+
+// attributes 语句的公共属性(span与指标共用,不包含高基数的db.statement)
+func (i *Interceptor) attributes(stmt *sqlxx.Statement) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.DBOperationKey.String(stmt.Op)}
+	if stmt.DB != nil && stmt.DB.Driver() != nil {
+		attrs = append(attrs, dbSystem(stmt.DB.Driver().Name))
+	}
+	if stmt.Template != "" {
+		attrs = append(attrs, TemplateKey.String(stmt.Template))
+	}
+	if stmt.Method != "" {
+		attrs = append(attrs, MethodKey.String(stmt.Method))
+	}
+	return attrs
+}
+
+// dbSystem 驱动名称对应的 db.system 属性(semconv定义的取值)
+func dbSystem(driver string) attribute.KeyValue {
+	switch driver {
+	case "mysql":
+		return semconv.DBSystemMySQL
+	case "postgres", "pgx":
+		return semconv.DBSystemPostgreSQL
+	case "mssql", "sqlserver":
+		return semconv.DBSystemMSSQL
+	case "sqlite3", "sqlite":
+		return semconv.DBSystemSqlite
+	}
+	return semconv.DBSystemOtherSQL
+}
+
+// spanName span名称,优先使用mapper方法名称,其次模版名称,最后是操作类型
+func spanName(stmt *sqlxx.Statement) string {
+	switch {
+	case stmt.Method != "":
+		return stmt.Method
+	case stmt.Template != "":
+		return stmt.Template
+	default:
+		return "sqlxx." + stmt.Op
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package otel
+
+import (
+	"context"
+	"errors"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/dialect"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"testing"
+	"time"
+)
+
+func TestInterceptor(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	i, err := NewInterceptor(WithTracerProvider(tp), WithMeterProvider(mp))
+	assert.NoError(t, err)
+	// sqlx.Open 不会建立连接，这里仅用于提供驱动信息
+	db, err := sqlxx.OpenWith(sqlxx.NewFactory("otel"), dialect.MySQL, "test:test@tcp(localhost)/test")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		stmt     *sqlxx.Statement
+		wantName string
+		wantCode codes.Code
+	}{
+		{
+			name: "mapper method",
+			stmt: &sqlxx.Statement{Op: sqlxx.OpSelect, Query: "SELECT * FROM `user`", Template: "examples/select_users.sql",
+				Method: "MyMapper.ListAll", DB: db, RowsAffected: 10, Duration: time.Millisecond},
+			wantName: "MyMapper.ListAll",
+			wantCode: codes.Unset,
+		}, {
+			name: "template",
+			stmt: &sqlxx.Statement{Op: sqlxx.OpPrepare, Query: "INSERT INTO `user`(`name`) VALUES(:name)",
+				Template: "builtin/create.sql", DB: db, RowsAffected: -1, Duration: time.Millisecond},
+			wantName: "builtin/create.sql",
+			wantCode: codes.Unset,
+		}, {
+			name: "error",
+			stmt: &sqlxx.Statement{Op: sqlxx.OpExec, Query: "DELETE FROM `user`", DB: db, RowsAffected: -1,
+				Err: errors.New("access denied")},
+			wantName: "sqlxx.exec",
+			wantCode: codes.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			ctx, err := i.Before(context.Background(), tt.stmt)
+			assert.NoError(t, err)
+			i.After(ctx, tt.stmt)
+			spans := exporter.GetSpans()
+			if assert.Len(t, spans, 1) {
+				span := spans[0]
+				assert.Equal(t, tt.wantName, span.Name)
+				assert.Equal(t, tt.wantCode, span.Status.Code)
+				attrs := map[string]any{}
+				for _, kv := range span.Attributes {
+					attrs[string(kv.Key)] = kv.Value.AsInterface()
+				}
+				assert.Equal(t, "mysql", attrs[string(semconv.DBSystemKey)])
+				assert.Equal(t, tt.stmt.Query, attrs[string(semconv.DBStatementKey)])
+				assert.Equal(t, tt.stmt.Op, attrs[string(semconv.DBOperationKey)])
+				if tt.stmt.Template != "" {
+					assert.Equal(t, tt.stmt.Template, attrs[string(TemplateKey)])
+				}
+				if tt.stmt.RowsAffected >= 0 {
+					assert.Equal(t, tt.stmt.RowsAffected, attrs[string(RowsKey)])
+				} else {
+					assert.NotContains(t, attrs, string(RowsKey))
+				}
+			}
+		})
+	}
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	if duration, ok := metrics["db.sqlxx.duration"].(metricdata.Histogram[float64]); assert.True(t, ok) {
+		var count uint64
+		for _, dp := range duration.DataPoints {
+			count += dp.Count
+		}
+		assert.Equal(t, uint64(3), count)
+	}
+	if rows, ok := metrics["db.sqlxx.rows"].(metricdata.Histogram[int64]); assert.True(t, ok) {
+		assert.Len(t, rows.DataPoints, 1)
+		assert.Equal(t, int64(10), rows.DataPoints[0].Sum)
+	}
+}
+
+func TestInterceptor_WithoutStatement(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	i, err := NewInterceptor(WithTracerProvider(tp), WithStatement(false))
+	assert.NoError(t, err)
+	stmt := &sqlxx.Statement{Op: sqlxx.OpSelect, Query: "SELECT 1", RowsAffected: -1}
+	ctx, _ := i.Before(context.Background(), stmt)
+	i.After(ctx, stmt)
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		for _, kv := range spans[0].Attributes {
+			assert.NotEqual(t, semconv.DBStatementKey, kv.Key)
+		}
+	}
+}
+
+func TestDBSystem(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", "mysql"},
+		{"postgres", "postgresql"},
+		{"mssql", "mssql"},
+		{"sqlite3", "sqlite"},
+		{"unknown", "other_sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			assert.Equal(t, tt.want, dbSystem(tt.driver).Value.AsString())
+		})
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// Package outbox 事务性发件箱
+//
+// 业务修改与待发布的消息在同一事务中提交(Tx.Enqueue),投递者(Poll/Run)从发件箱表中领取消息并交给处理函数发布，
+// 处理失败的消息按照退避策略重试，超过最大次数后标记为失败
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/expr"
+	"time"
+)
+
+const (
+	// DefaultTable 默认的发件箱表名称
+	DefaultTable = "sqlxx_outbox"
+	// DefaultBatchSize 默认每次领取的消息数量
+	DefaultBatchSize = 100
+	// DefaultInterval 默认的轮询间隔
+	DefaultInterval = time.Second
+	// DefaultMaxAttempts 默认的最大投递次数
+	DefaultMaxAttempts = 10
+	// DefaultBackoff 默认的初始重试间隔
+	DefaultBackoff = time.Second
+	// DefaultMaxBackoff 默认的最大重试间隔
+	DefaultMaxBackoff = 5 * time.Minute
+)
+
+// Status 消息状态
+type Status int
+
+const (
+	// StatusPending 待投递(包括等待重试)
+	StatusPending Status = iota
+	// StatusDone 投递成功
+	StatusDone
+	// StatusFailed 超过最大投递次数
+	StatusFailed
+)
+
+// ErrUnsupportedDialect 不支持的数据库方言
+var ErrUnsupportedDialect = errors.New("outbox: unsupported dialect")
+
+// Message 发件箱消息
+type Message struct {
+	ID      int64
+	Topic   string
+	Payload []byte
+	//Attempts 之前的投递次数
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Handler 消息处理函数(通常是发布到消息队列),返回错误时消息会被重试
+//
+// 同一条消息可能被投递多次(例如处理成功但事务提交失败),处理函数应保证幂等
+type Handler func(ctx context.Context, msg *Message) error
+
+// Option 发件箱配置
+type Option func(o *Outbox)
+
+// WithTable 设置发件箱表名称,默认为 DefaultTable
+func WithTable(table string) Option {
+	return func(o *Outbox) {
+		o.table = table
+	}
+}
+
+// WithBatchSize 设置每次领取的消息数量
+func WithBatchSize(n int) Option {
+	return func(o *Outbox) {
+		o.batchSize = n
+	}
+}
+
+// WithInterval 设置轮询间隔(Run)
+func WithInterval(du time.Duration) Option {
+	return func(o *Outbox) {
+		o.interval = du
+	}
+}
+
+// WithMaxAttempts 设置最大投递次数
+func WithMaxAttempts(n int) Option {
+	return func(o *Outbox) {
+		o.maxAttempts = n
+	}
+}
+
+// WithBackoff 设置重试间隔,每次重试翻倍,不超过maxBackoff
+func WithBackoff(backoff, maxBackoff time.Duration) Option {
+	return func(o *Outbox) {
+		o.backoff = backoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// Outbox 事务性发件箱
+type Outbox struct {
+	db          *sqlxx.DB
+	table       string
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// New 创建发件箱并注册到数据库(Tx.Enqueue 将消息写入该发件箱)
+func New(db *sqlxx.DB, opts ...Option) *Outbox {
+	o := &Outbox{
+		db:          db,
+		table:       DefaultTable,
+		batchSize:   DefaultBatchSize,
+		interval:    DefaultInterval,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	db.SetEnqueuer(o.Enqueue)
+	return o
+}
+
+// Table 发件箱表名称
+func (o *Outbox) Table() string {
+	return o.table
+}
+
+// CreateTable 创建发件箱表(已存在时忽略)
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	schema, err := Schema(o.db.Driver(), o.table)
+	if err != nil {
+		return err
+	}
+	_, err = o.db.ExecxxContext(ctx, schema)
+	return err
+}
+
+// Enqueue 在事务中写入消息
+//
+// payload 为[]byte、string或json.RawMessage时直接写入，其他类型序列化为JSON
+func (o *Outbox) Enqueue(tx *sqlxx.Tx, topic string, payload any) error {
+	var data string
+	switch p := payload.(type) {
+	case []byte:
+		data = string(p)
+	case json.RawMessage:
+		data = string(p)
+	case string:
+		data = p
+	default:
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	//DATETIME 可能不保存(或四舍五入)小数秒,截断后避免消息在写入的一秒内无法领取
+	now := time.Now().Truncate(time.Second)
+	_, err := tx.ExecExpr(expr.InsertInto(expr.Name(o.table)).
+		SetExpr(expr.Name("topic"), expr.Var("topic", topic)).
+		SetExpr(expr.Name("payload"), expr.Var("payload", data)).
+		SetExpr(expr.Name("status"), expr.Var("status", StatusPending)).
+		SetExpr(expr.Name("attempts"), expr.Var("attempts", 0)).
+		SetExpr(expr.Name("available_at"), expr.Var("available_at", now)).
+		SetExpr(expr.Name("created_at"), expr.Var("created_at", now)))
+	return err
+}
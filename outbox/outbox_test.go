@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/dialect"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDSN 测试数据库(MySQL)
+const testDSN = "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true"
+
+func TestSchema(t *testing.T) {
+	tests := []struct {
+		driver *dialect.Driver
+		want   []string
+	}{
+		{dialect.MySQL, []string{"CREATE TABLE IF NOT EXISTS `outbox`", "AUTO_INCREMENT"}},
+		{dialect.Postgres, []string{`CREATE TABLE IF NOT EXISTS "outbox"`, "BIGSERIAL"}},
+		{dialect.SQLServer, []string{"IF OBJECT_ID(N'[outbox]', N'U') IS NULL", "CREATE TABLE [outbox]", "IDENTITY"}},
+		{dialect.SQLite, []string{`CREATE TABLE IF NOT EXISTS "outbox"`, "AUTOINCREMENT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver.Name, func(t *testing.T) {
+			schema, err := Schema(tt.driver, "outbox")
+			assert.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, schema, want)
+			}
+		})
+	}
+	schema, err := Schema(dialect.SQLServer, "o'neil")
+	assert.NoError(t, err)
+	assert.Contains(t, schema, "IF OBJECT_ID(N'[o''neil]', N'U') IS NULL")
+	_, err = Schema(&dialect.Driver{Name: "unknown"}, "outbox")
+	assert.ErrorIs(t, err, ErrUnsupportedDialect)
+}
+
+func TestClaimSQL(t *testing.T) {
+	tests := []struct {
+		driver *dialect.Driver
+		want   string
+	}{
+		{dialect.MySQL, "SELECT id, topic, payload, attempts, created_at FROM `outbox` WHERE status = :status AND available_at <= :now ORDER BY id LIMIT 10 FOR UPDATE SKIP LOCKED"},
+		{dialect.Postgres, `SELECT id, topic, payload, attempts, created_at FROM "outbox" WHERE status = :status AND available_at <= :now ORDER BY id LIMIT 10 FOR UPDATE SKIP LOCKED`},
+		{dialect.SQLServer, "SELECT TOP 10 id, topic, payload, attempts, created_at FROM [outbox] WITH (UPDLOCK, ROWLOCK, READPAST) WHERE status = :status AND available_at <= :now ORDER BY id"},
+		{dialect.SQLite, `SELECT id, topic, payload, attempts, created_at FROM "outbox" WHERE status = :status AND available_at <= :now ORDER BY id LIMIT 10`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver.Name, func(t *testing.T) {
+			assert.Equal(t, tt.want, claimSQL(tt.driver, "outbox", 10))
+		})
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	f := sqlxx.NewFactory("outbox")
+	defer f.Shutdown()
+	db, err := f.Open(sqlxx.DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := "outbox_test_" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+	errNoEnqueuer := db.Batch(context.Background(), nil, func(tx *sqlxx.Tx) error {
+		return tx.Enqueue("user.created", nil)
+	})
+	assert.ErrorIs(t, errNoEnqueuer, sqlxx.ErrNoEnqueuer)
+
+	o := New(db, WithTable(table), WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))
+	assert.NoError(t, o.CreateTable(context.Background()))
+	defer db.Execxx("DROP TABLE " + table)
+
+	errFailed := errors.New("failed")
+	assert.NoError(t, db.Batch(context.Background(), nil, func(tx *sqlxx.Tx) error {
+		if err := tx.Enqueue("user.created", map[string]any{"id": 1}); err != nil {
+			return err
+		}
+		return tx.Enqueue("user.deleted", "2")
+	}))
+	assert.ErrorIs(t, db.Batch(context.Background(), nil, func(tx *sqlxx.Tx) error {
+		if err := tx.Enqueue("user.rollback", nil); err != nil {
+			return err
+		}
+		return errFailed
+	}), errFailed)
+
+	var delivered []string
+	handler := func(ctx context.Context, msg *Message) error {
+		delivered = append(delivered, msg.Topic+":"+string(msg.Payload))
+		if msg.Topic == "user.deleted" {
+			return errFailed
+		}
+		return nil
+	}
+	n, err := o.Poll(context.Background(), handler)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{`user.created:{"id":1}`, "user.deleted:2"}, delivered)
+
+	time.Sleep(10 * time.Millisecond)
+	delivered = nil
+	n, err = o.Poll(context.Background(), handler)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"user.deleted:2"}, delivered)
+
+	time.Sleep(10 * time.Millisecond)
+	n, err = o.Poll(context.Background(), handler)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	var statuses []Status
+	assert.NoError(t, db.Select(&statuses, "SELECT status FROM "+table+" ORDER BY id"))
+	assert.Equal(t, []Status{StatusDone, StatusFailed}, statuses)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package outbox
+
+import (
+	"context"
+	"github.com/gnodux/sqlxx"
+	"time"
+)
+
+// Poll 领取一批待投递的消息并交给处理函数，返回领取的消息数量
+//
+// 领取、处理和更新状态在同一个新事务中完成，领取的记录被锁定(FOR UPDATE SKIP LOCKED 或等效语句),多个投递者可以并发执行
+func (o *Outbox) Poll(ctx context.Context, handler Handler) (n int, err error) {
+	driver := o.db.Driver()
+	err = o.db.BatchWith(ctx, sqlxx.TxDefinition{Propagation: sqlxx.PropagationRequiresNew}, "", func(tx *sqlxx.Tx) error {
+		var messages []*Message
+		if err := tx.NamedSelect(&messages, claimSQL(driver, o.table, o.batchSize), map[string]any{
+			"status": StatusPending,
+			"now":    time.Now(),
+		}); err != nil {
+			return err
+		}
+		n = len(messages)
+		for _, msg := range messages {
+			if err := o.dispatch(tx, handler, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// dispatch 处理消息并更新状态:成功标记为完成，失败则延迟重试，超过最大投递次数标记为失败
+func (o *Outbox) dispatch(tx *sqlxx.Tx, handler Handler, msg *Message) error {
+	driver := o.db.Driver()
+	now := time.Now()
+	handleErr := handler(tx.Context(), msg)
+	attempts := msg.Attempts + 1
+	if handleErr == nil {
+		_, err := tx.NamedExecxx(updateSQL(driver, o.table, "status", "attempts", "processed_at"), map[string]any{
+			"id":           msg.ID,
+			"status":       StatusDone,
+			"attempts":     attempts,
+			"processed_at": now,
+		})
+		return err
+	}
+	status := StatusPending
+	if attempts >= o.maxAttempts {
+		status = StatusFailed
+	}
+	o.db.Logger().Warn("outbox message(", msg.ID, ",", msg.Topic, ") failed(", attempts, "/", o.maxAttempts, "): ", handleErr)
+	_, err := tx.NamedExecxx(updateSQL(driver, o.table, "status", "attempts", "last_error", "available_at"), map[string]any{
+		"id":           msg.ID,
+		"status":       status,
+		"attempts":     attempts,
+		"last_error":   handleErr.Error(),
+		"available_at": now.Add(o.retryAfter(attempts)).Truncate(time.Second),
+	})
+	return err
+}
+
+// retryAfter 第n次投递失败后的重试间隔
+func (o *Outbox) retryAfter(n int) time.Duration {
+	backoff := o.backoff
+	for i := 1; i < n && backoff < o.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > o.maxBackoff {
+		backoff = o.maxBackoff
+	}
+	return backoff
+}
+
+// Run 持续投递消息直到上下文结束
+//
+// 领取的消息少于批量大小时等待轮询间隔后再次领取，领取失败时记录日志并在轮询间隔后重试
+func (o *Outbox) Run(ctx context.Context, handler Handler) error {
+	for {
+		n, err := o.Poll(ctx, handler)
+		if err != nil {
+			o.db.Logger().Error("outbox poll failed: ", err)
+		}
+		if err == nil && n >= o.batchSize {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		timer := time.NewTimer(o.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package outbox
+
+import (
+	"fmt"
+	"github.com/gnodux/sqlxx/dialect"
+	"strings"
+)
+
+// schemas 各数据库方言的建表语句(参数为表名)
+var schemas = map[string]string{
+	dialect.MySQL.Name: `CREATE TABLE IF NOT EXISTS %[1]s
+(
+    id           BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    topic        VARCHAR(255)                      NOT NULL,
+    payload      TEXT                              NOT NULL,
+    status       INT                               NOT NULL DEFAULT 0,
+    attempts     INT                               NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    available_at DATETIME                          NOT NULL,
+    created_at   DATETIME                          NOT NULL,
+    processed_at DATETIME
+)`,
+	dialect.Postgres.Name: `CREATE TABLE IF NOT EXISTS %[1]s
+(
+    id           BIGSERIAL PRIMARY KEY,
+    topic        VARCHAR(255) NOT NULL,
+    payload      TEXT         NOT NULL,
+    status       INT          NOT NULL DEFAULT 0,
+    attempts     INT          NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    available_at TIMESTAMP    NOT NULL,
+    created_at   TIMESTAMP    NOT NULL,
+    processed_at TIMESTAMP
+)`,
+	dialect.SQLServer.Name: `IF OBJECT_ID(N%[2]s, N'U') IS NULL
+CREATE TABLE %[1]s
+(
+    id           BIGINT IDENTITY (1,1) PRIMARY KEY,
+    topic        NVARCHAR(255) NOT NULL,
+    payload      NVARCHAR(MAX) NOT NULL,
+    status       INT           NOT NULL DEFAULT 0,
+    attempts     INT           NOT NULL DEFAULT 0,
+    last_error   NVARCHAR(MAX),
+    available_at DATETIME2     NOT NULL,
+    created_at   DATETIME2     NOT NULL,
+    processed_at DATETIME2
+)`,
+	dialect.SQLite.Name: `CREATE TABLE IF NOT EXISTS %[1]s
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    topic        VARCHAR(255) NOT NULL,
+    payload      TEXT         NOT NULL,
+    status       INT          NOT NULL DEFAULT 0,
+    attempts     INT          NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    available_at DATETIME     NOT NULL,
+    created_at   DATETIME     NOT NULL,
+    processed_at DATETIME
+)`,
+}
+
+// Schema 获取发件箱表的建表语句
+func Schema(driver *dialect.Driver, table string) (string, error) {
+	if driver == nil {
+		return "", ErrUnsupportedDialect
+	}
+	schema, ok := schemas[driver.Name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDialect, driver.Name)
+	}
+	return fmt.Sprintf(schema, driver.SQLNameFunc(table), dialect.QuotedString(driver.SQLNameFunc(table))), nil
+}
+
+// claimSQL 领取待发送消息的语句，领取的记录在事务结束前被锁定,其他投递者跳过已锁定的记录
+//
+// 不支持行锁的数据库(如SQLite,写事务串行执行)不追加锁定语句
+func claimSQL(driver *dialect.Driver, table string, limit int) string {
+	const columns = "id, topic, payload, attempts, created_at"
+	const where = "WHERE status = :status AND available_at <= :now ORDER BY id"
+	name := driver.SQLNameFunc(table)
+	lock := driver.LockSQL(dialect.LockForUpdate, dialect.LockSkipLocked)
+	switch {
+	case driver.LockHints:
+		return fmt.Sprintf("SELECT TOP %d %s FROM %s WITH (%s) %s", limit, columns, name, lock, where)
+	case lock != "":
+		return fmt.Sprintf("SELECT %s FROM %s %s LIMIT %d %s", columns, name, where, limit, lock)
+	default:
+		return fmt.Sprintf("SELECT %s FROM %s %s LIMIT %d", columns, name, where, limit)
+	}
+}
+
+// updateSQL 更新消息状态的语句
+func updateSQL(driver *dialect.Driver, table string, columns ...string) string {
+	sets := make([]string, 0, len(columns))
+	for _, col := range columns {
+		sets = append(sets, col+" = :"+col)
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE id = :id", driver.SQLNameFunc(table), strings.Join(sets, ", "))
+}
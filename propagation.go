@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/cookieY/sqlx"
+)
+
+// Propagation 事务传播行为,决定上下文中已存在事务时如何执行
+type Propagation int
+
+const (
+	// PropagationRequired 存在事务则加入，否则开启新事务(默认)
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew 总是开启新事务(与上下文中的事务相互独立)
+	PropagationRequiresNew
+	// PropagationNested 存在事务则创建保存点(失败时回滚到保存点)，否则开启新事务
+	PropagationNested
+)
+
+const (
+	// TxRequired 传播行为：Required
+	TxRequired = "Required"
+	// TxRequiresNew 传播行为：RequiresNew
+	TxRequiresNew = "RequiresNew"
+	// TxNested 传播行为：Nested
+	TxNested = "Nested"
+)
+
+func (p Propagation) String() string {
+	switch p {
+	case PropagationRequiresNew:
+		return TxRequiresNew
+	case PropagationNested:
+		return TxNested
+	default:
+		return TxRequired
+	}
+}
+
+// ParsePropagation 解析传播行为名称，无法识别时返回 PropagationRequired
+func ParsePropagation(name string) Propagation {
+	switch name {
+	case TxRequiresNew:
+		return PropagationRequiresNew
+	case TxNested:
+		return PropagationNested
+	default:
+		return PropagationRequired
+	}
+}
+
+// TxDefinition 事务定义
+//
+// Options/Retry 仅在开启新事务时生效，加入已有事务或创建保存点时由外层事务决定
+type TxDefinition struct {
+	Options     *sql.TxOptions
+	Propagation Propagation
+	//Retry 重试策略,为空时使用数据库默认的策略(参考 WithRetry)
+	Retry *RetryPolicy
+}
+
+type txKey struct{}
+
+// WithTx 将事务放入上下文，使用该上下文执行的语句(同一数据库)会在事务中执行
+//
+// 事务回调中的 Tx.Context() 已经包含了当前事务，通常不需要手动调用
+func WithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom 获取上下文中的事务，不存在时返回nil
+func TxFrom(ctx context.Context) *Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// activeTx 获取执行语句使用的事务：显式传入的事务优先，否则使用上下文中属于当前数据库且未结束的事务
+func (d *DB) activeTx(ctx context.Context, tx *Tx) *Tx {
+	if tx != nil {
+		return tx
+	}
+	if current := TxFrom(ctx); current != nil && current.db == d && !current.physical().done.Load() {
+		return current
+	}
+	return nil
+}
+
+// BatchWith 按照事务定义执行fn
+//
+// 上下文中存在(当前数据库的)事务时，根据传播行为加入该事务、开启新事务或创建保存点
+func (d *DB) BatchWith(ctx context.Context, def TxDefinition, tpl string, fn func(tx *Tx) error) error {
+	if d == nil {
+		return ErrNilDB
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	current := d.activeTx(ctx, nil)
+	switch {
+	case current != nil && def.Propagation == PropagationRequired:
+		joined := current.join(ctx, tpl)
+		if err := fn(joined); err != nil {
+			//加入的函数失败时,即使外层处理了错误,事务也只能回滚
+			joined.setRollbackOnly(err)
+			return err
+		}
+		return nil
+	case current != nil && def.Propagation == PropagationNested:
+		return current.nested(ctx, tpl, fn)
+	}
+	retry := def.Retry
+	if retry == nil {
+		retry = d.retry
+	}
+	return retry.run(ctx, d, func() error {
+		return d.begin(ctx, def.Options, tpl, fn)
+	})
+}
+
+// begin 开启新事务执行fn,fn返回错误(或panic)时回滚，否则提交
+func (d *DB) begin(ctx context.Context, opts *sql.TxOptions, tpl string, fn func(tx *Tx) error) (err error) {
+	var tx *sqlx.Tx
+	if tx, err = d.BeginTxx(ctx, opts); err != nil {
+		return err
+	}
+	wrapped := NewTxWith(tx, d, tpl)
+	wrapped.ctx = WithTx(ctx, wrapped)
+	defer func() {
+		wrapped.done.Store(true)
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			wrapped.callbacks.run(panicError(p))
+			panic(p)
+		}
+		if err == nil {
+			err = wrapped.rollbackOnlyErr()
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		wrapped.callbacks.run(err)
+	}()
+	return fn(wrapped)
+}
+
+// join 加入当前事务，返回使用新模版的事务(与当前事务共享同一个数据库事务)
+func (t *Tx) join(ctx context.Context, tpl string) *Tx {
+	joined := &Tx{Tx: t.Tx, id: t.id, db: t.db, tpl: tpl, root: t.physical(), savepoint: t.savepoint, callbacks: t.callbacks, status: t.status}
+	joined.ctx = WithTx(ctx, joined)
+	return joined
+}
+
+// nested 在当前事务中创建保存点执行fn,fn返回错误时回滚到保存点，外层事务不受影响
+func (t *Tx) nested(ctx context.Context, tpl string, fn func(tx *Tx) error) (err error) {
+	root := t.physical()
+	root.savepoints++
+	sp := t.join(ctx, tpl)
+	sp.savepoint = fmt.Sprintf("sqlxx_sp_%d", root.savepoints)
+	sp.callbacks = &txCallbacks{}
+	sp.status = &txStatus{}
+	driver := t.db.driver
+	if _, err = t.db.doExec(ctx, sp, "", driver.SavepointSQL(sp.savepoint), nil); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = t.db.doExec(ctx, sp, "", driver.RollbackToSQL(sp.savepoint), nil)
+			sp.callbacks.run(panicError(p))
+			panic(p)
+		}
+		if err == nil {
+			err = sp.rollbackOnlyErr()
+		}
+		if err == nil {
+			if release := driver.ReleaseSavepointSQL(sp.savepoint); release != "" {
+				_, err = t.db.doExec(ctx, sp, "", release, nil)
+			}
+		}
+		if err != nil {
+			//fn失败、只能回滚或释放保存点失败时回滚到保存点,并执行保存点的回滚回调
+			if _, rbErr := t.db.doExec(ctx, sp, "", driver.RollbackToSQL(sp.savepoint), nil); rbErr != nil {
+				err = errors.Join(err, rbErr)
+			}
+			sp.callbacks.run(err)
+			return
+		}
+		t.callbacks.merge(sp.callbacks)
+	}()
+	return fn(sp)
+}
+
+// panicError 将panic的值转换为错误(包装 ErrTxPanic)
+func panicError(p any) error {
+	if err, ok := p.(error); ok {
+		return fmt.Errorf("%w: %w", ErrTxPanic, err)
+	}
+	return fmt.Errorf("%w: %v", ErrTxPanic, p)
+}
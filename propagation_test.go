@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParsePropagation(t *testing.T) {
+	tests := []struct {
+		name string
+		want Propagation
+	}{
+		{"", PropagationRequired},
+		{TxRequired, PropagationRequired},
+		{TxRequiresNew, PropagationRequiresNew},
+		{TxNested, PropagationNested},
+		{"unknown", PropagationRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParsePropagation(tt.name))
+		})
+	}
+}
+
+func TestDriver_Savepoint(t *testing.T) {
+	tests := []struct {
+		driver                       *dialect.Driver
+		savepoint, rollback, release string
+	}{
+		{dialect.MySQL, "SAVEPOINT sp", "ROLLBACK TO SAVEPOINT sp", "RELEASE SAVEPOINT sp"},
+		{dialect.Postgres, "SAVEPOINT sp", "ROLLBACK TO SAVEPOINT sp", "RELEASE SAVEPOINT sp"},
+		{dialect.SQLite, "SAVEPOINT sp", "ROLLBACK TO SAVEPOINT sp", "RELEASE SAVEPOINT sp"},
+		{dialect.SQLServer, "SAVE TRANSACTION sp", "ROLLBACK TRANSACTION sp", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver.Name, func(t *testing.T) {
+			assert.Equal(t, tt.savepoint, tt.driver.SavepointSQL("sp"))
+			assert.Equal(t, tt.rollback, tt.driver.RollbackToSQL("sp"))
+			assert.Equal(t, tt.release, tt.driver.ReleaseSavepointSQL("sp"))
+		})
+	}
+}
+
+// txRecorder 记录语句执行所在的事务以及保存点语句(保存点语句替换为空查询，测试数据库不一定支持保存点)
+type txRecorder struct {
+	savepoints []string
+	txs        []uint64
+}
+
+func (r *txRecorder) interceptor() Interceptor {
+	return &InterceptorFuncs{BeforeFunc: func(ctx context.Context, stmt *Statement) (context.Context, error) {
+		if strings.Contains(stmt.Query, "SAVEPOINT") {
+			r.savepoints = append(r.savepoints, stmt.Query)
+			stmt.Query = "SELECT 1"
+		}
+		return ctx, nil
+	}, AfterFunc: func(ctx context.Context, stmt *Statement) {
+		var id uint64
+		if stmt.Tx != nil {
+			id = stmt.Tx.ID()
+		}
+		r.txs = append(r.txs, id)
+	}}
+}
+
+type propagationMapper struct {
+	ListRoles SelectContextFunc[Role] `sql:"SELECT * FROM role"`
+	Nested    TxContextFunc           `propagation:"Nested"`
+}
+
+func TestBatchWith_Propagation(t *testing.T) {
+	f := NewFactory("propagation")
+	rec := &txRecorder{}
+	db, err := f.Open(DefaultName, "mysql", testDSN,
+		WithInterceptors(rec.interceptor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	mapper, err := NewMapperWith[BaseMapper[*Role]](f, DefaultName)
+	assert.NoError(t, err)
+
+	t.Run("required", func(t *testing.T) {
+		rec.txs = nil
+		var outer uint64
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			outer = tx.ID()
+			return db.BatchWith(tx.Context(), TxDefinition{}, "", func(inner *Tx) error {
+				assert.Equal(t, outer, inner.ID())
+				_, _, err := mapper.SelectContext(inner.Context())
+				return err
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{outer}, rec.txs)
+	})
+	t.Run("boosted", func(t *testing.T) {
+		boosted, err := NewMapperWith[propagationMapper](f, DefaultName)
+		assert.NoError(t, err)
+		rec.txs, rec.savepoints = nil, nil
+		var outer uint64
+		err = db.Batch(context.Background(), nil, func(tx *Tx) error {
+			outer = tx.ID()
+			return boosted.Nested(tx.Context(), func(inner *Tx) error {
+				_, err := boosted.ListRoles(inner.Context())
+				return err
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{outer, outer, outer}, rec.txs)
+		assert.Equal(t, []string{"SAVEPOINT sqlxx_sp_1", "RELEASE SAVEPOINT sqlxx_sp_1"}, rec.savepoints)
+	})
+	t.Run("required rollback only", func(t *testing.T) {
+		errInner := errors.New("inner failed")
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			innerErr := db.BatchWith(tx.Context(), TxDefinition{}, "", func(inner *Tx) error {
+				if _, err := inner.Exec("INSERT INTO `role` (`name`, `desc`) VALUES ('rollback_only', 'partial write')"); err != nil {
+					return err
+				}
+				return errInner
+			})
+			assert.ErrorIs(t, innerErr, errInner)
+			assert.True(t, tx.RollbackOnly())
+			//外层处理了错误,事务仍然回滚
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrRollbackOnly)
+		assert.ErrorIs(t, err, errInner)
+		var count int
+		assert.NoError(t, db.Get(&count, "SELECT COUNT(1) FROM `role` WHERE `name`='rollback_only'"))
+		assert.Equal(t, 0, count)
+	})
+	t.Run("requires new", func(t *testing.T) {
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			return db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationRequiresNew}, "", func(inner *Tx) error {
+				assert.NotEqual(t, tx.ID(), inner.ID())
+				return nil
+			})
+		})
+		assert.NoError(t, err)
+	})
+	t.Run("nested", func(t *testing.T) {
+		rec.savepoints = nil
+		errInner := errors.New("inner failed")
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			nestedErr := db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationNested}, "", func(inner *Tx) error {
+				assert.Equal(t, tx.ID(), inner.ID())
+				assert.Equal(t, "sqlxx_sp_1", inner.Savepoint())
+				return errInner
+			})
+			assert.ErrorIs(t, nestedErr, errInner)
+			return db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationNested}, "", func(inner *Tx) error {
+				assert.Equal(t, "sqlxx_sp_2", inner.Savepoint())
+				return nil
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"SAVEPOINT sqlxx_sp_1", "ROLLBACK TO SAVEPOINT sqlxx_sp_1",
+			"SAVEPOINT sqlxx_sp_2", "RELEASE SAVEPOINT sqlxx_sp_2",
+		}, rec.savepoints)
+	})
+	t.Run("finished tx is ignored", func(t *testing.T) {
+		var ctx context.Context
+		assert.NoError(t, db.Batch(context.Background(), nil, func(tx *Tx) error {
+			ctx = tx.Context()
+			return nil
+		}))
+		rec.txs = nil
+		_, _, err := mapper.SelectContext(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{0}, rec.txs)
+	})
+}
@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/gnodux/sqlxx/meta"
+	"reflect"
+)
+
+var (
+	//ErrTenantRequired 实体包含租户列,但查询没有指定租户(参考 Query.Tenant/Query.AllTenants)
+	ErrTenantRequired = errors.New("tenant is required")
+	//ErrNoCondition 更新、删除时没有指定条件
+	ErrNoCondition = errors.New("condition is required")
+)
+
+// Query 类型安全的查询构造器,由 BaseMapper.Query 创建,最终编译为 SelectExpr/UpdateExpr/DeleteExpr 执行
+//
+// 1. 实体包含租户列时必须通过 Tenant 指定租户(或使用 AllTenants 显式跨租户)
+// 2. 实体包含逻辑删除列时默认过滤已删除的记录(WithDeleted 包含已删除的记录),Delete 为逻辑删除
+// 3. 查询后执行 AfterFind 钩子;Update/Delete/Erase 不执行钩子,但开启审计的实体会记录变更
+// 4. 通过 Preload 批量加载关联关系(参考 meta.Relation)
+//
+// 例如:
+//
+//	users, err := mapper.Query().Tenant(1).Where(UserCols.Name.Like("a%")).OrderBy(UserCols.Id.Desc()).Limit(10).List(ctx)
+type Query[T any] struct {
+	mapper      *BaseMapper[T]
+	where       []expr.Expr
+	orderBy     []expr.Expr
+	limit       int
+	offset      int
+	tenantId    any
+	hasTenant   bool
+	allTenants  bool
+	withDeleted bool
+	preloads    []string
+}
+
+// Query 创建查询构造器
+func (b *BaseMapper[T]) Query() *Query[T] {
+	b.init()
+	return &Query[T]{mapper: b}
+}
+
+// Where 添加查询条件,多次调用时使用AND连接
+func (q *Query[T]) Where(conditions ...expr.Expr) *Query[T] {
+	q.where = append(q.where, conditions...)
+	return q
+}
+
+// OrderBy 添加排序,例如 OrderBy(UserCols.Id.Desc())
+func (q *Query[T]) OrderBy(exps ...expr.Expr) *Query[T] {
+	q.orderBy = append(q.orderBy, exps...)
+	return q
+}
+
+// Limit 限制返回的记录数,0为不限制
+func (q *Query[T]) Limit(limit int) *Query[T] {
+	q.limit = limit
+	return q
+}
+
+// Offset 跳过的记录数,通常与 Limit 一起用于分页
+func (q *Query[T]) Offset(offset int) *Query[T] {
+	q.offset = offset
+	return q
+}
+
+// Tenant 限定租户
+func (q *Query[T]) Tenant(tenantId any) *Query[T] {
+	q.tenantId, q.hasTenant = tenantId, true
+	return q
+}
+
+// AllTenants 不限定租户(跨租户查询、更新)
+func (q *Query[T]) AllTenants() *Query[T] {
+	q.allTenants = true
+	return q
+}
+
+// WithDeleted 包含逻辑删除的记录
+func (q *Query[T]) WithDeleted() *Query[T] {
+	q.withDeleted = true
+	return q
+}
+
+// condition 查询条件(包含租户和逻辑删除的条件),没有条件时返回nil
+func (q *Query[T]) condition() (expr.Expr, error) {
+	cond, err := q.scope(q.mapper.meta)
+	if err != nil {
+		return nil, err
+	}
+	cond = append(cond, q.where...)
+	if len(cond) == 0 {
+		return nil, nil
+	}
+	return expr.And(cond...), nil
+}
+
+// scope 实体的租户和逻辑删除条件
+func (q *Query[T]) scope(m *meta.Entity) ([]expr.Expr, error) {
+	var cond []expr.Expr
+	if m.TenantKey != nil {
+		switch {
+		case q.hasTenant:
+			cond = append(cond, expr.Eq(m.TenantKey, expr.Var("query_tenant_id", q.tenantId)))
+		case !q.allTenants:
+			return nil, ErrTenantRequired
+		}
+	}
+	if key := m.LogicDeleteKey; key != nil && !q.withDeleted {
+		active, _, nullable := deletedValues(key.Type)
+		exp := expr.Expr(expr.Eq(key, expr.Var("query_deleted", active)))
+		if nullable {
+			//可以为空的列,NULL视为未删除
+			exp = expr.Paren(expr.Or(exp, expr.Eq(key, nil)))
+		}
+		cond = append(cond, exp)
+	}
+	return cond, nil
+}
+
+// deletedValues 逻辑删除列未删除、已删除的值(布尔为false/true,其他为0/1),
+// nullable 表示列可以为空(指针或 sql.NullXxx 类型)
+func deletedValues(t reflect.Type) (active, deleted any, nullable bool) {
+	if t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	if t.Kind() == reflect.Struct {
+		//sql.NullBool/sql.NullInt64 等,第一个字段为值
+		if _, ok := t.FieldByName("Valid"); ok && t.NumField() == 2 {
+			t, nullable = t.Field(0).Type, true
+		}
+	}
+	if t.Kind() == reflect.Bool {
+		return false, true, nullable
+	}
+	return 0, 1, nullable
+}
+
+// selectExpr 构建查询表达式
+func (q *Query[T]) selectExpr(columns ...expr.Expr) (*expr.SelectExpr, error) {
+	cond, err := q.condition()
+	if err != nil {
+		return nil, err
+	}
+	s := expr.Select(columns...).From(q.mapper.meta).Where(cond).Limit(q.limit).Offset(q.offset)
+	if len(q.orderBy) > 0 {
+		s.OrderBy(q.orderBy...)
+	}
+	return s, nil
+}
+
+// List 查询记录
+func (q *Query[T]) List(ctx context.Context) (result []T, err error) {
+	b := q.mapper
+	s, err := q.selectExpr(b.meta.ColumnExprs()...)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.SelectExprContext(ctx, &result, s); err != nil {
+		return nil, err
+	}
+	if err = q.preload(ctx, result); err != nil {
+		return nil, err
+	}
+	err = runAfterHooks(ctx, b.activeTx(ctx, nil), HookFind, result, b.hookChain()...)
+	return
+}
+
+// First 查询第一条记录,记录不存在时返回 sql.ErrNoRows(不修改查询构造器)
+func (q *Query[T]) First(ctx context.Context) (entity T, err error) {
+	var result []T
+	c := *q
+	c.limit = 1
+	if result, err = c.List(ctx); err != nil {
+		return
+	}
+	if len(result) == 0 {
+		return entity, sql.ErrNoRows
+	}
+	return result[0], nil
+}
+
+// Count 统计记录数(忽略排序和分页)
+func (q *Query[T]) Count(ctx context.Context) (total int64, err error) {
+	cond, err := q.condition()
+	if err != nil {
+		return 0, err
+	}
+	err = q.mapper.GetExprContext(ctx, &total, expr.Select(expr.Count).From(q.mapper.meta).Where(cond))
+	return
+}
+
+// Exists 是否存在符合条件的记录
+func (q *Query[T]) Exists(ctx context.Context) (bool, error) {
+	s, err := q.selectExpr(expr.Raw(1))
+	if err != nil {
+		return false, err
+	}
+	var rows []int
+	if err = q.mapper.SelectExprContext(ctx, &rows, s.Limit(1)); err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
+// Pluck 查询单列的值,dest为切片指针,例如:
+//
+//	var names []string
+//	err := mapper.Query().Tenant(1).Pluck(ctx, &names, UserCols.Name)
+func (q *Query[T]) Pluck(ctx context.Context, dest any, column expr.Expr) error {
+	s, err := q.selectExpr(column)
+	if err != nil {
+		return err
+	}
+	return q.mapper.SelectExprContext(ctx, dest, s)
+}
+
+// Update 更新符合条件的记录,sets为赋值表达式,例如 Update(ctx, UserCols.Name.Set("admin"))
+func (q *Query[T]) Update(ctx context.Context, sets ...expr.Expr) (effect int64, err error) {
+	b := q.mapper
+	if len(sets) == 0 {
+		return 0, errors.New("update must have one set")
+	}
+	cond, err := q.writeCondition()
+	if err != nil {
+		return 0, err
+	}
+	err = b.UpdateTxContext(ctx, func(tx *Tx) error {
+		effect, err = b.execAudited(tx, AuditUpdate, cond, expr.Update(b.meta).Set(sets...).Where(cond))
+		return err
+	})
+	return
+}
+
+// Delete 删除符合条件的记录,实体包含逻辑删除列时为逻辑删除
+func (q *Query[T]) Delete(ctx context.Context) (effect int64, err error) {
+	b := q.mapper
+	if b.meta.LogicDeleteKey == nil {
+		return q.Erase(ctx)
+	}
+	cond, err := q.writeCondition()
+	if err != nil {
+		return 0, err
+	}
+	key := b.meta.LogicDeleteKey
+	_, deleted, _ := deletedValues(key.Type)
+	err = b.DeleteTxContext(ctx, func(tx *Tx) error {
+		effect, err = b.execAudited(tx, AuditDelete, cond, expr.Update(b.meta).Set(expr.Eq(key, expr.Var("query_set_deleted", deleted))).Where(cond))
+		return err
+	})
+	return
+}
+
+// Erase 物理删除符合条件的记录
+func (q *Query[T]) Erase(ctx context.Context) (effect int64, err error) {
+	b := q.mapper
+	cond, err := q.writeCondition()
+	if err != nil {
+		return 0, err
+	}
+	err = b.EraseTxContext(ctx, func(tx *Tx) error {
+		effect, err = b.execAudited(tx, AuditErase, cond, expr.Delete(b.meta).Where(cond))
+		return err
+	})
+	return
+}
+
+// writeCondition 更新、删除的条件,必须指定查询条件,避免误操作整张表
+func (q *Query[T]) writeCondition() (expr.Expr, error) {
+	if len(q.where) == 0 {
+		return nil, ErrNoCondition
+	}
+	return q.condition()
+}
+
+// execAudited 在事务中执行写操作并记录审计
+func (b *BaseMapper[T]) execAudited(tx *Tx, op string, where expr.Expr, exp expr.Expr) (int64, error) {
+	trail, err := b.audit(tx, op, where)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecExpr(exp)
+	if err != nil {
+		return 0, err
+	}
+	effect, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return effect, trail.write()
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type QueryItem struct {
+	Id        int64
+	TenantId  int64
+	Name      string
+	Score     int
+	IsDeleted bool
+}
+
+type queryItemColumns struct {
+	Id    meta.Col[int64]
+	Name  meta.Col[string]
+	Score meta.Col[int]
+}
+
+var queryItemCols = meta.MustCols[QueryItem, queryItemColumns]()
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	_, err := MustGet(DefaultName).Exec("CREATE TABLE IF NOT EXISTS `query_item` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `tenant_id` BIGINT NOT NULL, `name` VARCHAR(64) NOT NULL, `score` INT NOT NULL, `is_deleted` BOOLEAN DEFAULT FALSE)")
+	assert.NoError(t, err)
+	mapper, err := NewMapper[BaseMapper[QueryItem]](DefaultName)
+	assert.NoError(t, err)
+	tenant := time.Now().UnixNano()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, mapper.Create(QueryItem{TenantId: tenant, Name: fmt.Sprintf("item_%d", i), Score: i * 10}))
+	}
+	assert.NoError(t, mapper.Create(QueryItem{TenantId: tenant + 1, Name: "item_0"}))
+	defer mapper.Query().AllTenants().WithDeleted().Where(queryItemCols.Name.Like("item_%")).Erase(ctx)
+
+	_, err = mapper.Query().List(ctx)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	_, err = mapper.Query().Tenant(tenant).Delete(ctx)
+	assert.ErrorIs(t, err, ErrNoCondition)
+
+	items, err := mapper.Query().Tenant(tenant).Where(queryItemCols.Score.Ge(20)).OrderBy(queryItemCols.Score.Desc()).Limit(2).List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "item_4", items[0].Name)
+		assert.Equal(t, "item_3", items[1].Name)
+	}
+	first, err := mapper.Query().Tenant(tenant).Where(queryItemCols.Name.Eq("item_1")).First(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, first.Score)
+	//First 不修改查询构造器,复用时仍然返回所有记录
+	reused := mapper.Query().Tenant(tenant).OrderBy(queryItemCols.Score)
+	first, err = reused.First(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "item_0", first.Name)
+	items, err = reused.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, items, 5)
+	_, err = mapper.Query().Tenant(tenant).Where(queryItemCols.Name.Eq("missing")).First(ctx)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	total, err := mapper.Query().Tenant(tenant).Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	exists, err := mapper.Query().Tenant(tenant).Where(queryItemCols.Score.Between(15, 25)).Exists(ctx)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	var names []string
+	assert.NoError(t, mapper.Query().Tenant(tenant).Where(queryItemCols.Score.Lt(20)).OrderBy(queryItemCols.Name).Pluck(ctx, &names, queryItemCols.Name))
+	assert.Equal(t, []string{"item_0", "item_1"}, names)
+
+	effect, err := mapper.Query().Tenant(tenant).Where(queryItemCols.Name.In("item_0", "item_1")).Update(ctx, queryItemCols.Score.Set(100))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), effect)
+
+	effect, err = mapper.Query().Tenant(tenant).Where(queryItemCols.Score.Eq(100)).Delete(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), effect)
+	total, err = mapper.Query().Tenant(tenant).Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	total, err = mapper.Query().Tenant(tenant).WithDeleted().Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	total, err = mapper.Query().AllTenants().Where(queryItemCols.Name.Eq("item_0")).Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+// QueryNullable 可以为空的逻辑删除列(sqlxx-gen 为可以为空的列生成指针字段)
+type QueryNullable struct {
+	Id        int64
+	Name      string
+	IsDeleted *bool
+}
+
+func TestQuery_NullableDeleted(t *testing.T) {
+	ctx := context.Background()
+	db := MustGet(DefaultName)
+	_, err := db.Exec("DROP TABLE IF EXISTS `query_nullable`")
+	assert.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE `query_nullable` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `name` VARCHAR(64) NOT NULL, `is_deleted` BOOLEAN NULL)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO `query_nullable` (`name`, `is_deleted`) VALUES ('null', NULL), ('active', FALSE), ('deleted', TRUE)")
+	assert.NoError(t, err)
+	mapper, err := NewMapper[BaseMapper[QueryNullable]](DefaultName)
+	assert.NoError(t, err)
+
+	var names []string
+	assert.NoError(t, mapper.Query().OrderBy(expr.Name("id")).Pluck(ctx, &names, expr.Name("name")))
+	assert.Equal(t, []string{"null", "active"}, names)
+
+	//赋值为NULL使用 = 绑定参数
+	effect, err := mapper.Query().Where(expr.Name("name").Eq("active")).Update(ctx, expr.Assign(expr.Name("is_deleted"), nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), effect)
+	active, err := mapper.Query().Where(expr.Name("name").Eq("active")).First(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, active.IsDeleted)
+
+	effect, err = mapper.Query().Where(expr.Name("name").Eq("null")).Delete(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), effect)
+	deleted, err := mapper.Query().WithDeleted().Where(expr.Name("name").Eq("null")).First(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, deleted.IsDeleted) {
+		assert.True(t, *deleted.IsDeleted)
+	}
+	total, err := mapper.Query().Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+func TestDeletedValues(t *testing.T) {
+	tests := []struct {
+		name            string
+		value           any
+		active, deleted any
+		nullable        bool
+	}{
+		{"bool", false, false, true, false},
+		{"int", 0, 0, 1, false},
+		{"bool pointer", (*bool)(nil), false, true, true},
+		{"int pointer", (*int8)(nil), 0, 1, true},
+		{"null bool", sql.NullBool{}, false, true, true},
+		{"null int", sql.NullInt64{}, 0, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, deleted, nullable := deletedValues(reflect.TypeOf(tt.value))
+			assert.Equal(t, tt.active, active)
+			assert.Equal(t, tt.deleted, deleted)
+			assert.Equal(t, tt.nullable, nullable)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/gnodux/sqlxx/utils"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted 脱敏后的参数值
+const Redacted = "******"
+
+// Redactor 参数脱敏函数
+//
+// name 为参数名称(命名参数为参数名，结构体参数为列名，位置参数为空),返回值为脱敏后的值
+type Redactor func(name string, value any) any
+
+// RedactNone 不脱敏
+func RedactNone(_ string, value any) any {
+	return value
+}
+
+// RedactAll 所有参数均脱敏
+func RedactAll(_ string, _ any) any {
+	return Redacted
+}
+
+// RedactNames 对指定名称的参数脱敏(不区分大小写，字段名称和列名均可)
+func RedactNames(names ...string) Redactor {
+	set := map[string]bool{}
+	for _, n := range names {
+		set[strings.ToLower(n)] = true
+		set[utils.LowerCase(n)] = true
+	}
+	return func(name string, value any) any {
+		if set[strings.ToLower(name)] {
+			return Redacted
+		}
+		return value
+	}
+}
+
+var (
+	//sensitiveTypes 类型 -> 该类型的敏感列(map[string]bool)
+	sensitiveTypes sync.Map
+	//sensitiveTables 小写的表名 -> 表的敏感列
+	sensitiveTables   = map[string]map[string]bool{}
+	sensitiveTablesMu sync.RWMutex
+	//identPattern SQL中的标识符(用于查找语句涉及的表)
+	identPattern = regexp.MustCompile(`[\w$]+`)
+)
+
+// RegisterSensitive 登记实体中标记为sensitive(`dbx:"sensitive"`)的列
+//
+// 敏感列按照实体的表名登记,不同表的同名列互不影响。BaseMapper和以结构体作为参数的语句会自动登记
+func RegisterSensitive(t reflect.Type) {
+	sensitiveOf(t)
+}
+
+// sensitiveOf 类型的敏感列(小写的字段名、列名、字段路径),首次调用时按照表名登记
+func sensitiveOf(t reflect.Type) map[string]bool {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cols, ok := sensitiveTypes.Load(t); ok {
+		return cols.(map[string]bool)
+	}
+	entity := meta.NewEntity(reflect.New(t).Interface())
+	cols := map[string]bool{}
+	for _, col := range entity.Columns {
+		if col.Sensitive {
+			cols[strings.ToLower(col.Name)] = true
+			cols[strings.ToLower(col.ColumnName)] = true
+			cols[strings.ToLower(col.Path)] = true
+		}
+	}
+	if actual, loaded := sensitiveTypes.LoadOrStore(t, cols); loaded {
+		return actual.(map[string]bool)
+	}
+	if len(cols) > 0 {
+		sensitiveTablesMu.Lock()
+		table := strings.ToLower(entity.TableName)
+		merged := map[string]bool{}
+		for k := range sensitiveTables[table] {
+			merged[k] = true
+		}
+		for k := range cols {
+			merged[k] = true
+		}
+		sensitiveTables[table] = merged
+		sensitiveTablesMu.Unlock()
+	}
+	return cols
+}
+
+// sensitive 语句涉及的敏感列:结构体参数类型的敏感列,以及SQL中出现的表(已登记)的敏感列
+func (s *Statement) sensitive() map[string]bool {
+	cols := map[string]bool{}
+	for k := range sensitiveOf(reflect.TypeOf(s.Args)) {
+		cols[k] = true
+	}
+	sensitiveTablesMu.RLock()
+	defer sensitiveTablesMu.RUnlock()
+	if len(sensitiveTables) == 0 {
+		return cols
+	}
+	for _, ident := range identPattern.FindAllString(strings.ToLower(s.Query), -1) {
+		for k := range sensitiveTables[ident] {
+			cols[k] = true
+		}
+	}
+	return cols
+}
+
+// RedactSensitive 对敏感列脱敏(默认的脱敏方式)
+//
+// 敏感列由语句参数的实体类型和SQL涉及的表确定(参考 RegisterSensitive 和 RedactStatement),
+// 语句涉及敏感列时,无法对应到列的位置参数全部脱敏。单独调用时不会修改参数值
+func RedactSensitive(_ string, value any) any {
+	return value
+}
+
+// isRedactSensitive 是否为 RedactSensitive(需要根据语句确定敏感列)
+func isRedactSensitive(r Redactor) bool {
+	return r != nil && reflect.ValueOf(r).Pointer() == reflect.ValueOf(Redactor(RedactSensitive)).Pointer()
+}
+
+// sensitiveRedactor 对指定的敏感列脱敏,存在敏感列时位置参数(name为空)全部脱敏
+func sensitiveRedactor(cols map[string]bool) Redactor {
+	return func(name string, value any) any {
+		if len(cols) == 0 {
+			return value
+		}
+		if name == "" || cols[strings.ToLower(name)] {
+			return Redacted
+		}
+		return value
+	}
+}
+
+// RedactStatement 对语句的参数脱敏,返回新的参数(不会修改原参数)
+//
+// RedactSensitive 按照语句涉及的敏感列脱敏,其他脱敏方式同 RedactArgs
+func RedactStatement(stmt *Statement, r Redactor) any {
+	if isRedactSensitive(r) {
+		return RedactArgs(stmt.Args, sensitiveRedactor(stmt.sensitive()))
+	}
+	return RedactArgs(stmt.Args, r)
+}
+
+// RedactArgs 对语句参数脱敏,返回新的参数(不会修改原参数)
+//
+// 位置参数返回[]any,命名参数(map/struct)返回map[string]any
+func RedactArgs(args any, r Redactor) any {
+	if r == nil || args == nil {
+		return args
+	}
+	if isRedactSensitive(r) {
+		//没有语句信息时只能根据结构体参数的类型确定敏感列
+		r = sensitiveRedactor(sensitiveOf(reflect.TypeOf(args)))
+	}
+	switch a := args.(type) {
+	case []any:
+		result := make([]any, len(a))
+		for idx, v := range a {
+			result[idx] = r("", v)
+		}
+		return result
+	case map[string]any:
+		result := make(map[string]any, len(a))
+		for k, v := range a {
+			result[k] = r(k, v)
+		}
+		return result
+	}
+	v := reflect.ValueOf(args)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return args
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return r("", args)
+	}
+	result := map[string]any{}
+	for k, val := range utils.ToMap(v.Interface()) {
+		name := NameFunc(k)
+		result[name] = r(name, val)
+	}
+	return result
+}
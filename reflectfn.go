@@ -6,7 +6,7 @@
 package sqlxx
 
 import (
-	"github.com/cookieY/sqlx"
+	"context"
 	"reflect"
 )
 
@@ -23,9 +23,16 @@ func getTpl(d *DB, templateList []string) string {
 	return ""
 }
 func SelectWith(p reflect.Type, db *DB, templateList []string, args []any) (any, error) {
+	return selectWith(context.Background(), p, db, templateList, args)
+}
+func selectWith(ctx context.Context, p reflect.Type, db *DB, templateList []string, args []any) (any, error) {
 	list := reflect.New(reflect.SliceOf(p))
 	tpl := getTpl(db, templateList)
-	err := db.Selectxx(list.Interface(), tpl, args...)
+	query, err := db.ParseSQL(tpl, args)
+	if err != nil {
+		return nil, err
+	}
+	err = db.doSelect(ctx, nil, list.Interface(), tpl, query, args)
 	if err != nil {
 		return nil, err
 	}
@@ -34,13 +41,26 @@ func SelectWith(p reflect.Type, db *DB, templateList []string, args []any) (any,
 }
 
 func NamedSelectWith(p reflect.Type, db *DB, templateList []string, arg any) (any, error) {
+	return namedSelectWith(context.Background(), p, db, templateList, arg)
+}
+func namedSelectWith(ctx context.Context, p reflect.Type, db *DB, templateList []string, arg any) (any, error) {
 	list := reflect.New(reflect.SliceOf(p))
 	tpl := getTpl(db, templateList)
-	err := db.NamedSelectxx(list.Interface(), tpl, arg)
+	query, err := db.ParseSQL(tpl, arg)
+	if err != nil {
+		return nil, err
+	}
+	if arg == nil {
+		arg = map[string]any{}
+	}
+	err = db.doNamedSelect(ctx, nil, list.Interface(), tpl, query, arg)
 	return list.Elem().Interface(), err
 }
 
 func NamedGetWith(p reflect.Type, db *DB, templateList []string, arg any) (any, error) {
+	return namedGetWith(context.Background(), p, db, templateList, arg)
+}
+func namedGetWith(ctx context.Context, p reflect.Type, db *DB, templateList []string, arg any) (any, error) {
 	var o reflect.Value
 	if p.Kind() == reflect.Pointer {
 		o = reflect.New(p.Elem())
@@ -48,14 +68,11 @@ func NamedGetWith(p reflect.Type, db *DB, templateList []string, arg any) (any,
 		o = reflect.New(p)
 	}
 	tpl := getTpl(db, templateList)
-	n, err := db.PrepareNamedxx(tpl, arg)
+	query, err := db.ParseSQL(tpl, arg)
 	if err != nil {
 		return nil, err
 	}
-	defer func(n *sqlx.NamedStmt) {
-		err = n.Close()
-	}(n)
-	err = n.Get(o.Interface(), arg)
+	err = db.doNamedGet(ctx, nil, o.Interface(), tpl, query, arg)
 	if p.Kind() == reflect.Pointer {
 		return o.Interface(), err
 	} else {
@@ -64,6 +81,9 @@ func NamedGetWith(p reflect.Type, db *DB, templateList []string, arg any) (any,
 }
 
 func GetWith(p reflect.Type, db *DB, templateList []string, args []any) (any, error) {
+	return getWith(context.Background(), p, db, templateList, args)
+}
+func getWith(ctx context.Context, p reflect.Type, db *DB, templateList []string, args []any) (any, error) {
 	var o reflect.Value
 	if p.Kind() == reflect.Pointer {
 		o = reflect.New(p.Elem())
@@ -71,14 +91,11 @@ func GetWith(p reflect.Type, db *DB, templateList []string, args []any) (any, er
 		o = reflect.New(p)
 	}
 	tpl := getTpl(db, templateList)
-	n, err := db.Preparexx(tpl, args)
+	query, err := db.ParseSQL(tpl, args)
 	if err != nil {
 		return nil, err
 	}
-	defer func(n *sqlx.Stmt) {
-		err = n.Close()
-	}(n)
-	err = n.Get(o.Interface(), args...)
+	err = db.doGet(ctx, nil, o.Interface(), tpl, query, args)
 	if p.Kind() == reflect.Pointer {
 		return o.Interface(), err
 	} else {
@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/gnodux/sqlxx/meta"
+	"reflect"
+)
+
+// Preload 创建预加载关联关系的查询,等同于 Query().Preload(names...)
+func (b *BaseMapper[T]) Preload(names ...string) *Query[T] {
+	return b.Query().Preload(names...)
+}
+
+// Preload 查询后加载关联关系(字段名,参考 meta.Relation),每个关联关系使用一次IN查询(多对多时额外查询一次中间表)
+//
+// 关联实体同样按照查询的租户和逻辑删除条件过滤
+func (q *Query[T]) Preload(names ...string) *Query[T] {
+	q.preloads = append(q.preloads, names...)
+	return q
+}
+
+// preload 加载关联关系
+func (q *Query[T]) preload(ctx context.Context, entities []T) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	owners := make([]reflect.Value, 0, len(entities))
+	for idx := range entities {
+		ev := reflect.ValueOf(&entities[idx]).Elem()
+		for ev.Kind() == reflect.Pointer {
+			if ev.IsNil() {
+				break
+			}
+			ev = ev.Elem()
+		}
+		if ev.Kind() == reflect.Struct {
+			owners = append(owners, ev)
+		}
+	}
+	for _, name := range q.preloads {
+		rel := q.mapper.meta.Relation(name)
+		if rel == nil {
+			return fmt.Errorf("relation %s not found in %s", name, q.mapper.meta.Name)
+		}
+		if err := q.loadRelation(ctx, rel, owners); err != nil {
+			return fmt.Errorf("preload %s.%s: %w", q.mapper.meta.Name, name, err)
+		}
+	}
+	return nil
+}
+
+// loadRelation 加载一个关联关系:查询关联实体,按照关联键分组后写入字段
+func (q *Query[T]) loadRelation(ctx context.Context, rel *meta.Relation, owners []reflect.Value) error {
+	m := q.mapper.meta
+	target := meta.NewEntity(reflect.New(rel.Target).Interface())
+	var (
+		ownerKey  *meta.Column
+		targetKey *meta.Column
+		//links 多对多时当前实体主键对应的关联实体主键(参考 relationKey)
+		links map[string][]string
+	)
+	switch rel.Kind {
+	case meta.BelongsTo:
+		ownerKey, targetKey = m.Column(rel.ForeignKey), target.PrimaryKey
+	case meta.HasOne, meta.HasMany:
+		ownerKey, targetKey = m.PrimaryKey, target.Column(rel.ForeignKey)
+	case meta.ManyToMany:
+		ownerKey, targetKey = m.PrimaryKey, target.PrimaryKey
+	}
+	if ownerKey == nil || targetKey == nil {
+		return fmt.Errorf("key column %s not found", rel.ForeignKey)
+	}
+	keys := distinctValues(owners, ownerKey)
+	if len(keys) == 0 {
+		return nil
+	}
+	if rel.Kind == meta.ManyToMany {
+		var err error
+		if links, keys, err = q.loadLinks(ctx, rel, keys); err != nil {
+			return err
+		}
+	}
+	rows := reflect.New(reflect.SliceOf(rel.Target))
+	if len(keys) > 0 {
+		scope, err := q.scope(target)
+		if err != nil {
+			return err
+		}
+		cond := append(scope, expr.In(targetKey, "preload_key", keys...))
+		if err = q.mapper.SelectExprContext(ctx, rows.Interface(), expr.Select(target.ColumnExprs()...).From(target).Where(expr.And(cond...))); err != nil {
+			return err
+		}
+	}
+	grouped := map[string][]reflect.Value{}
+	loaded := make([]any, 0, rows.Elem().Len())
+	for idx := 0; idx < rows.Elem().Len(); idx++ {
+		row := rows.Elem().Index(idx)
+		key := relationKey(columnValue(row, targetKey))
+		grouped[key] = append(grouped[key], row)
+		loaded = append(loaded, row.Addr().Interface())
+	}
+	//关联实体同样执行 AfterFind 钩子和(Factory的)监听器
+	if err := runAfterHooks(ctx, q.mapper.activeTx(ctx, nil), HookFind, loaded, q.mapper.factoryListeners()); err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		key := relationKey(columnValue(owner, ownerKey))
+		related := grouped[key]
+		if links != nil {
+			related = nil
+			for _, link := range links[key] {
+				related = append(related, grouped[link]...)
+			}
+		}
+		assignRelation(owner.FieldByName(rel.Name), related)
+	}
+	return nil
+}
+
+// loadLinks 查询多对多的中间表,返回当前实体主键对应的关联实体主键和所有关联实体主键
+func (q *Query[T]) loadLinks(ctx context.Context, rel *meta.Relation, keys []any) (map[string][]string, []any, error) {
+	var rows []struct {
+		OwnerKey  any `db:"owner_key"`
+		TargetKey any `db:"target_key"`
+	}
+	if err := q.mapper.SelectExprContext(ctx, &rows, expr.Select(
+		expr.Alias(expr.Name(rel.ForeignKey), "owner_key"), expr.Alias(expr.Name(rel.References), "target_key")).
+		From(expr.Name(rel.JoinTable)).
+		Where(expr.In(expr.Name(rel.ForeignKey), "preload_owner", keys...))); err != nil {
+		return nil, nil, err
+	}
+	links := map[string][]string{}
+	seen := map[string]bool{}
+	var targets []any
+	for _, row := range rows {
+		owner, target := relationKey(row.OwnerKey), relationKey(row.TargetKey)
+		links[owner] = append(links[owner], target)
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, row.TargetKey)
+		}
+	}
+	return links, targets, nil
+}
+
+// distinctValues 实体的列值(去重,忽略空值)
+func distinctValues(owners []reflect.Value, col *meta.Column) []any {
+	seen := map[string]bool{}
+	var values []any
+	for _, owner := range owners {
+		v := columnValue(owner, col)
+		if v == nil || reflect.ValueOf(v).IsZero() {
+			continue
+		}
+		if key := relationKey(v); !seen[key] {
+			seen[key] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// relationKey 关联键的值转换为字符串用于分组匹配:driver.Valuer 取其值,[]byte(及字节数组)按字节转换,
+// 使实体字段的值与中间表扫描得到的值(驱动返回的 int64/[]byte 等)可以相互匹配
+func relationKey(v any) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil {
+			v = value
+		}
+	}
+	switch vv := v.(type) {
+	case []byte:
+		return string(vv)
+	case string:
+		return vv
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// assignRelation 将关联实体写入字段:切片字段写入所有记录(没有记录时为空切片),其他字段写入第一条记录
+func assignRelation(field reflect.Value, rows []reflect.Value) {
+	if !field.CanSet() {
+		return
+	}
+	value := func(row reflect.Value, t reflect.Type) reflect.Value {
+		if t.Kind() == reflect.Pointer {
+			return row.Addr()
+		}
+		return row
+	}
+	switch field.Kind() {
+	case reflect.Slice:
+		list := reflect.MakeSlice(field.Type(), 0, len(rows))
+		for _, row := range rows {
+			list = reflect.Append(list, value(row, field.Type().Elem()))
+		}
+		field.Set(list)
+	default:
+		if len(rows) > 0 {
+			field.Set(value(rows[0], field.Type()))
+		}
+	}
+}
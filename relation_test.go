@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"github.com/gnodux/sqlxx/expr"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type RelUser struct {
+	Id        int64
+	TenantId  int64
+	Name      string
+	IsDeleted bool
+	Profile   *RelProfile `dbx:"hasOne"`
+	Posts     []RelPost   `dbx:"hasMany"`
+	Tags      []*RelTag   `dbx:"manyToMany"`
+}
+
+type RelProfile struct {
+	Id        int64
+	RelUserId int64
+	Bio       string
+}
+
+type RelPost struct {
+	Id        int64
+	TenantId  int64
+	RelUserId int64
+	Title     string
+	IsDeleted bool
+	Author    *RelUser `dbx:"belongsTo,foreignKey:rel_user_id"`
+}
+
+type RelTag struct {
+	Id    int64
+	Name  string
+	found int
+}
+
+func (t *RelTag) AfterFind(ctx context.Context, tx *Tx) error {
+	t.found++
+	return nil
+}
+
+func TestQuery_Preload(t *testing.T) {
+	ctx := context.Background()
+	db := MustGet(DefaultName)
+	for _, ddl := range []string{
+		"CREATE TABLE IF NOT EXISTS `rel_user` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `tenant_id` BIGINT NOT NULL, `name` VARCHAR(64) NOT NULL, `is_deleted` BOOLEAN DEFAULT FALSE)",
+		"CREATE TABLE IF NOT EXISTS `rel_profile` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `rel_user_id` BIGINT NOT NULL, `bio` VARCHAR(64) NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS `rel_post` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `tenant_id` BIGINT NOT NULL, `rel_user_id` BIGINT NOT NULL, `title` VARCHAR(64) NOT NULL, `is_deleted` BOOLEAN DEFAULT FALSE)",
+		"CREATE TABLE IF NOT EXISTS `rel_tag` (`id` BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL, `name` VARCHAR(64) NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS `rel_user_rel_tag` (`rel_user_id` BIGINT NOT NULL, `rel_tag_id` BIGINT NOT NULL)",
+	} {
+		_, err := db.Exec(ddl)
+		assert.NoError(t, err)
+	}
+	users, err := NewMapper[BaseMapper[*RelUser]](DefaultName)
+	assert.NoError(t, err)
+	profiles, err := NewMapper[BaseMapper[*RelProfile]](DefaultName)
+	assert.NoError(t, err)
+	posts, err := NewMapper[BaseMapper[*RelPost]](DefaultName)
+	assert.NoError(t, err)
+	tags, err := NewMapper[BaseMapper[*RelTag]](DefaultName)
+	assert.NoError(t, err)
+
+	tenant := time.Now().UnixNano()
+	alice, bob := &RelUser{TenantId: tenant, Name: "alice"}, &RelUser{TenantId: tenant, Name: "bob"}
+	assert.NoError(t, users.Create(alice, bob))
+	assert.NoError(t, profiles.Create(&RelProfile{RelUserId: alice.Id, Bio: "alice bio"}))
+	assert.NoError(t, posts.Create(
+		&RelPost{TenantId: tenant, RelUserId: alice.Id, Title: "first"},
+		&RelPost{TenantId: tenant, RelUserId: alice.Id, Title: "second"},
+		&RelPost{TenantId: tenant, RelUserId: alice.Id, Title: "deleted", IsDeleted: true},
+		&RelPost{TenantId: tenant + 1, RelUserId: alice.Id, Title: "other tenant"},
+	))
+	red, blue := &RelTag{Name: "red"}, &RelTag{Name: "blue"}
+	assert.NoError(t, tags.Create(red, blue))
+	_, err = db.Exec("INSERT INTO `rel_user_rel_tag` (`rel_user_id`, `rel_tag_id`) VALUES (?, ?), (?, ?), (?, ?)",
+		alice.Id, red.Id, alice.Id, blue.Id, bob.Id, blue.Id)
+	assert.NoError(t, err)
+
+	_, err = users.Preload("Unknown").Tenant(tenant).List(ctx)
+	assert.ErrorContains(t, err, "relation Unknown not found in RelUser")
+
+	list, err := users.Preload("Profile", "Posts", "Tags").Tenant(tenant).OrderBy(expr.Name("id")).List(ctx)
+	assert.NoError(t, err)
+	if !assert.Len(t, list, 2) {
+		return
+	}
+	if assert.NotNil(t, list[0].Profile) {
+		assert.Equal(t, "alice bio", list[0].Profile.Bio)
+	}
+	assert.Nil(t, list[1].Profile)
+	if assert.Len(t, list[0].Posts, 2) {
+		assert.ElementsMatch(t, []string{"first", "second"}, []string{list[0].Posts[0].Title, list[0].Posts[1].Title})
+	}
+	assert.NotNil(t, list[1].Posts)
+	assert.Empty(t, list[1].Posts)
+	assert.Len(t, list[0].Tags, 2)
+	if assert.Len(t, list[1].Tags, 1) {
+		assert.Equal(t, "blue", list[1].Tags[0].Name)
+		//预加载的关联实体执行 AfterFind 钩子
+		assert.Equal(t, 1, list[1].Tags[0].found)
+	}
+
+	post, err := posts.Preload("Author").Tenant(tenant).Where(expr.Name("title").Eq("first")).First(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, post.Author) {
+		assert.Equal(t, "alice", post.Author.Name)
+	}
+	all, err := users.Preload("Posts").Tenant(tenant).WithDeleted().Where(expr.Name("id").Eq(alice.Id)).First(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all.Posts, 3)
+}
+
+func TestRelationKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "int", value: int64(12), want: "12"},
+		{name: "bytes", value: []byte("12"), want: "12"},
+		{name: "string", value: "abc", want: "abc"},
+		{name: "byte array", value: [4]byte{'u', 'u', 'i', 'd'}, want: "uuid"},
+		{name: "valuer", value: sql.NullInt64{Int64: 12, Valid: true}, want: "12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, relationKey(tt.value))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoff 默认的初始退避时间
+	DefaultRetryBackoff = 10 * time.Millisecond
+	// DefaultRetryMaxBackoff 默认的最大退避时间
+	DefaultRetryMaxBackoff = time.Second
+)
+
+// RetryPolicy 事务重试策略,用于死锁、锁等待超时、序列化失败等可以通过重试解决的错误
+//
+// 重试会重新开启事务并再次执行事务函数，事务函数中不应包含无法重复执行的外部副作用
+type RetryPolicy struct {
+	//Attempts 最大尝试次数(包含第一次执行),<=1 表示不重试
+	Attempts int
+	//Backoff 初始退避时间,每次重试翻倍(实际等待时间在[0,退避时间)之间随机),为0时使用 DefaultRetryBackoff
+	Backoff time.Duration
+	//MaxBackoff 最大退避时间,为0时使用 DefaultRetryMaxBackoff
+	MaxBackoff time.Duration
+	//Retryable 判断错误是否可重试,为空时使用数据库方言的分类器(dialect.Driver.Retryable)
+	Retryable func(err error) bool
+}
+
+// WithRetry 设置数据库默认的事务重试策略(Batch/Batchxx/TxFunc),TxDefinition.Retry 可以覆盖该策略
+func WithRetry(policy RetryPolicy) Option {
+	return func(d *DB) {
+		d.retry = &policy
+	}
+}
+
+// ParseRetryPolicy 解析retry tag
+//
+// 格式为"次数[,初始退避时间[,最大退避时间]]",如 retry:"3" 或 retry:"5,20ms,1s",retry:"0" 表示不重试
+func ParseRetryPolicy(s string) (*RetryPolicy, error) {
+	parts := strings.Split(s, ",")
+	attempts, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry attempts %q: %w", s, err)
+	}
+	policy := &RetryPolicy{Attempts: attempts}
+	durations := []*time.Duration{&policy.Backoff, &policy.MaxBackoff}
+	for idx, part := range parts[1:] {
+		if idx >= len(durations) {
+			return nil, fmt.Errorf("invalid retry policy %q", s)
+		}
+		if *durations[idx], err = time.ParseDuration(strings.TrimSpace(part)); err != nil {
+			return nil, fmt.Errorf("invalid retry backoff %q: %w", s, err)
+		}
+	}
+	return policy, nil
+}
+
+// retryable 判断错误是否可重试
+func (p *RetryPolicy) retryable(d *DB, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return d.driver != nil && d.driver.IsRetryable(err)
+}
+
+// backoff 第n次重试前的等待时间(full jitter)
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	for i := 1; i < n && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// run 按照重试策略执行fn,策略为空时只执行一次
+func (p *RetryPolicy) run(ctx context.Context, d *DB, fn func() error) (err error) {
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || p == nil || attempt >= p.Attempts || !p.retryable(d, err) {
+			return err
+		}
+		wait := p.backoff(attempt)
+		d.Logger().Warn("retry transaction(", attempt, "/", p.Attempts, ") after ", wait, ": ", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// pgError 模拟 lib/pq 的错误
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string    { return "pq: " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+// mssqlError 模拟 go-mssqldb 的错误
+type mssqlError struct {
+	number int32
+}
+
+func (e mssqlError) Error() string         { return fmt.Sprintf("mssql: %d", e.number) }
+func (e mssqlError) SQLErrorNumber() int32 { return e.number }
+
+func TestDriver_IsRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver *dialect.Driver
+		err    error
+		want   bool
+	}{
+		{"mysql deadlock", dialect.MySQL, &mysql.MySQLError{Number: 1213}, true},
+		{"mysql lock wait timeout", dialect.MySQL, fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1205}), true},
+		{"mysql duplicate entry", dialect.MySQL, &mysql.MySQLError{Number: 1062}, false},
+		{"mysql plain error", dialect.MySQL, errors.New("1213"), false},
+		{"postgres serialization failure", dialect.Postgres, &pgError{code: "40001"}, true},
+		{"postgres deadlock", dialect.Postgres, &pgError{code: "40P01"}, true},
+		{"postgres unique violation", dialect.Postgres, &pgError{code: "23505"}, false},
+		{"mssql deadlock", dialect.SQLServer, mssqlError{number: 1205}, true},
+		{"mssql other", dialect.SQLServer, mssqlError{number: 2627}, false},
+		{"sqlite", dialect.SQLite, &mysql.MySQLError{Number: 1213}, false},
+		{"nil", dialect.MySQL, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.driver.IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestParseRetryPolicy(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    *RetryPolicy
+		wantErr bool
+	}{
+		{"3", &RetryPolicy{Attempts: 3}, false},
+		{"5,20ms", &RetryPolicy{Attempts: 5, Backoff: 20 * time.Millisecond}, false},
+		{"5, 20ms, 1s", &RetryPolicy{Attempts: 5, Backoff: 20 * time.Millisecond, MaxBackoff: time.Second}, false},
+		{"0", &RetryPolicy{}, false},
+		{"", nil, true},
+		{"three", nil, true},
+		{"3,soon", nil, true},
+		{"3,1ms,2ms,3ms", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := ParseRetryPolicy(tt.tag)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := &RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+	for n := 1; n <= 5; n++ {
+		wait := p.backoff(n)
+		assert.GreaterOrEqual(t, wait, time.Duration(0))
+		assert.Less(t, wait, 40*time.Millisecond)
+	}
+}
+
+func TestBatchWith_Retry(t *testing.T) {
+	f := NewFactory("retry")
+	db, err := f.Open(DefaultName, "mysql", testDSN,
+		WithRetry(RetryPolicy{Attempts: 3, Backoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	t.Run("retry until success", func(t *testing.T) {
+		calls := 0
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			calls++
+			if calls < 3 {
+				return deadlock
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("attempts exhausted", func(t *testing.T) {
+		calls := 0
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			calls++
+			return deadlock
+		})
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("not retryable", func(t *testing.T) {
+		calls := 0
+		errFailed := errors.New("failed")
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			calls++
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("joined tx is retried by the outer tx", func(t *testing.T) {
+		calls := 0
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			return db.BatchWith(tx.Context(), TxDefinition{}, "", func(inner *Tx) error {
+				calls++
+				return deadlock
+			})
+		})
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("retry tag", func(t *testing.T) {
+		var mapper struct {
+			Once TxFunc `retry:"1"`
+		}
+		assert.NoError(t, BoostMapper(&mapper, f, DefaultName))
+		calls := 0
+		err := mapper.Once(func(tx *Tx) error {
+			calls++
+			return deadlock
+		})
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, 1, calls)
+	})
+}
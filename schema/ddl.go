@@ -0,0 +1,259 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+// Package schema 根据实体元数据(meta.Entity)生成建表语句
+//
+// 列类型由Go类型映射(指针和sql.Null*类型的列允许为空),整数主键自增,
+// 通过dbx标签声明长度、索引、唯一约束和默认值(默认值原样写入DDL,标签以逗号分隔,因此默认值不能包含逗号),例如:
+//
+//	Name string `dbx:"size:64,unique,default:''"`
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/gnodux/sqlxx/meta"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultSize 字符串列未声明长度时的默认长度
+const DefaultSize = 255
+
+var (
+	// ErrUnsupportedDialect 不支持的数据库方言
+	ErrUnsupportedDialect = errors.New("schema: unsupported dialect")
+	// ErrUnsupportedType 无法映射为SQL类型的字段类型
+	ErrUnsupportedType = errors.New("schema: unsupported type")
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// types 各数据库方言的类型映射
+type types struct {
+	bool, int16, int32, int64, float32, float64 string
+	//varchar 字符串类型(参数为长度)
+	varchar string
+	bytes   string
+	time    string
+	//autoIncrement 自增主键的列定义(参数为整数类型)
+	autoIncrement string
+	//falseValue 逻辑删除列的默认值
+	falseValue string
+	//inlineIndex 是否在建表语句中定义索引
+	inlineIndex bool
+}
+
+var dialects = map[string]*types{
+	dialect.MySQL.Name: {
+		bool: "BOOLEAN", int16: "SMALLINT", int32: "INT", int64: "BIGINT", float32: "FLOAT", float64: "DOUBLE",
+		varchar: "VARCHAR(%d)", bytes: "BLOB", time: "DATETIME",
+		autoIncrement: "%s NOT NULL AUTO_INCREMENT PRIMARY KEY", falseValue: "0", inlineIndex: true,
+	},
+	dialect.Postgres.Name: {
+		bool: "BOOLEAN", int16: "SMALLINT", int32: "INTEGER", int64: "BIGINT", float32: "REAL", float64: "DOUBLE PRECISION",
+		varchar: "VARCHAR(%d)", bytes: "BYTEA", time: "TIMESTAMP",
+		autoIncrement: "%s GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY", falseValue: "FALSE",
+	},
+	dialect.SQLServer.Name: {
+		bool: "BIT", int16: "SMALLINT", int32: "INT", int64: "BIGINT", float32: "REAL", float64: "FLOAT",
+		varchar: "NVARCHAR(%d)", bytes: "VARBINARY(MAX)", time: "DATETIME2",
+		autoIncrement: "%s IDENTITY(1,1) PRIMARY KEY", falseValue: "0", inlineIndex: true,
+	},
+	dialect.SQLite.Name: {
+		bool: "BOOLEAN", int16: "INTEGER", int32: "INTEGER", int64: "INTEGER", float32: "REAL", float64: "REAL",
+		varchar: "VARCHAR(%d)", bytes: "BLOB", time: "DATETIME",
+		autoIncrement: "%s PRIMARY KEY AUTOINCREMENT", falseValue: "0",
+	},
+}
+
+// Option 建表配置
+type Option func(o *options)
+
+type options struct {
+	ifNotExists bool
+}
+
+// IfNotExists 表已存在时不创建(索引同样跳过)
+func IfNotExists() Option {
+	return func(o *options) {
+		o.ifNotExists = true
+	}
+}
+
+// lookup 获取方言的类型映射
+func lookup(driver *dialect.Driver) (*types, error) {
+	if driver == nil {
+		return nil, ErrUnsupportedDialect
+	}
+	t, ok := dialects[driver.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, driver.Name)
+	}
+	return t, nil
+}
+
+// Nullable 字段类型是否允许为空(指针和sql.Null*类型),返回去掉包装后的类型
+func Nullable(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem(), true
+	}
+	if t.Kind() == reflect.Struct && strings.HasPrefix(t.Name(), "Null") && t.NumField() == 2 {
+		if valid, ok := t.FieldByName("Valid"); ok && valid.Type.Kind() == reflect.Bool {
+			return t.Field(1 - valid.Index[0]).Type, true
+		}
+	}
+	return t, false
+}
+
+// ColumnType 获取列的SQL类型和是否允许为空(主键不允许为空)
+func ColumnType(driver *dialect.Driver, col *meta.Column) (string, bool, error) {
+	t, err := lookup(driver)
+	if err != nil {
+		return "", false, err
+	}
+	typ, nullable := Nullable(col.Type)
+	sqlType, err := t.sqlType(typ, col.Size)
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %s(%s)", err, col.Name, col.Type)
+	}
+	return sqlType, nullable && !col.IsPrimaryKey, nil
+}
+
+func (t *types) sqlType(typ reflect.Type, size int) (string, error) {
+	if typ == timeType {
+		return t.time, nil
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return t.bool, nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return t.int16, nil
+	case reflect.Int32, reflect.Uint16:
+		return t.int32, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return t.int64, nil
+	case reflect.Float32:
+		return t.float32, nil
+	case reflect.Float64:
+		return t.float64, nil
+	case reflect.String:
+		if size <= 0 {
+			size = DefaultSize
+		}
+		return fmt.Sprintf(t.varchar, size), nil
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return t.bytes, nil
+		}
+	}
+	return "", ErrUnsupportedType
+}
+
+// CreateTable 生成实体的建表语句,第一条为CREATE TABLE,其余为创建索引的语句(MySQL和SQL Server的索引定义在建表语句中)
+//
+// 整数主键自增;租户列没有声明索引时自动创建索引;逻辑删除列没有声明默认值时默认为false
+func CreateTable(driver *dialect.Driver, entity *meta.Entity, opts ...Option) ([]string, error) {
+	t, err := lookup(driver)
+	if err != nil {
+		return nil, err
+	}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	var (
+		defs    []string
+		indexes []string
+		stmts   []string
+	)
+	for _, col := range entity.Columns {
+		if col.Ignore {
+			continue
+		}
+		def, err := t.columnDef(driver, col)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	for _, col := range entity.Columns {
+		if col.Ignore {
+			continue
+		}
+		switch {
+		case col.Unique:
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)",
+				driver.SQLNameFunc("uk_"+entity.TableName+"_"+col.ColumnName), driver.SQLNameFunc(col.ColumnName)))
+		case col.Index || col.IsTenantKey && !col.IsPrimaryKey:
+			name, column := driver.SQLNameFunc("idx_"+entity.TableName+"_"+col.ColumnName), driver.SQLNameFunc(col.ColumnName)
+			if t.inlineIndex {
+				defs = append(defs, fmt.Sprintf("INDEX %s (%s)", name, column))
+				continue
+			}
+			create := "CREATE INDEX "
+			if o.ifNotExists {
+				create += "IF NOT EXISTS "
+			}
+			indexes = append(indexes, fmt.Sprintf("%s%s ON %s (%s)", create, name, driver.SQLNameFunc(entity.TableName), column))
+		}
+	}
+	var sb strings.Builder
+	if o.ifNotExists {
+		if driver.Name == dialect.SQLServer.Name {
+			fmt.Fprintf(&sb, "IF OBJECT_ID(N%s, N'U') IS NULL\n", dialect.QuotedString(driver.SQLNameFunc(entity.TableName)))
+			sb.WriteString("CREATE TABLE ")
+		} else {
+			sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+		}
+	} else {
+		sb.WriteString("CREATE TABLE ")
+	}
+	sb.WriteString(driver.SQLNameFunc(entity.TableName))
+	sb.WriteString("\n(\n    ")
+	sb.WriteString(strings.Join(defs, ",\n    "))
+	sb.WriteString("\n)")
+	stmts = append(stmts, sb.String())
+	return append(stmts, indexes...), nil
+}
+
+// columnDef 列定义
+func (t *types) columnDef(driver *dialect.Driver, col *meta.Column) (string, error) {
+	sqlType, nullable, err := ColumnType(driver, col)
+	if err != nil {
+		return "", err
+	}
+	name := driver.SQLNameFunc(col.ColumnName)
+	if col.IsPrimaryKey {
+		if typ, _ := Nullable(col.Type); isInteger(typ) {
+			return name + " " + fmt.Sprintf(t.autoIncrement, sqlType), nil
+		}
+		return name + " " + sqlType + " NOT NULL PRIMARY KEY", nil
+	}
+	def := name + " " + sqlType
+	if nullable {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+	switch {
+	case col.Default != "":
+		def += " DEFAULT " + col.Default
+	case col.IsLogicDeleteKey:
+		def += " DEFAULT " + t.falseValue
+	}
+	return def, nil
+}
+
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
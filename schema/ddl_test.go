@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package schema
+
+import (
+	"database/sql"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/gnodux/sqlxx/meta"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDSN 测试数据库(MySQL)
+const testDSN = "xxtest:xxtest@tcp(localhost)/sqlxx?charset=utf8&parseTime=true"
+
+type account struct {
+	ID        int64
+	TenantID  int64
+	Email     string `dbx:"size:64,unique"`
+	Nickname  *string
+	Score     sql.NullFloat64 `dbx:"index"`
+	Level     int32           `dbx:"default:1"`
+	Avatar    []byte
+	IsDeleted bool
+	CreatedAt time.Time
+	Extra     map[string]any `dbx:"_"`
+}
+
+func TestCreateTable(t *testing.T) {
+	entity := meta.NewEntity(account{})
+	tests := []struct {
+		name   string
+		driver *dialect.Driver
+		opts   []Option
+		want   []string
+	}{
+		{"mysql", dialect.MySQL, []Option{IfNotExists()}, []string{"CREATE TABLE IF NOT EXISTS `account`\n(\n" +
+			"    `id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+			"    `tenant_id` BIGINT NOT NULL,\n" +
+			"    `email` VARCHAR(64) NOT NULL,\n" +
+			"    `nickname` VARCHAR(255) NULL,\n" +
+			"    `score` DOUBLE NULL,\n" +
+			"    `level` INT NOT NULL DEFAULT 1,\n" +
+			"    `avatar` BLOB NOT NULL,\n" +
+			"    `is_deleted` BOOLEAN NOT NULL DEFAULT 0,\n" +
+			"    `created_at` DATETIME NOT NULL,\n" +
+			"    INDEX `idx_account_tenant_id` (`tenant_id`),\n" +
+			"    CONSTRAINT `uk_account_email` UNIQUE (`email`),\n" +
+			"    INDEX `idx_account_score` (`score`)\n)"}},
+		{"postgres", dialect.Postgres, nil, []string{"CREATE TABLE \"account\"\n(\n" +
+			"    \"id\" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n" +
+			"    \"tenant_id\" BIGINT NOT NULL,\n" +
+			"    \"email\" VARCHAR(64) NOT NULL,\n" +
+			"    \"nickname\" VARCHAR(255) NULL,\n" +
+			"    \"score\" DOUBLE PRECISION NULL,\n" +
+			"    \"level\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"    \"avatar\" BYTEA NOT NULL,\n" +
+			"    \"is_deleted\" BOOLEAN NOT NULL DEFAULT FALSE,\n" +
+			"    \"created_at\" TIMESTAMP NOT NULL,\n" +
+			"    CONSTRAINT \"uk_account_email\" UNIQUE (\"email\")\n)",
+			"CREATE INDEX \"idx_account_tenant_id\" ON \"account\" (\"tenant_id\")",
+			"CREATE INDEX \"idx_account_score\" ON \"account\" (\"score\")"}},
+		{"sqlserver", dialect.SQLServer, []Option{IfNotExists()}, []string{"IF OBJECT_ID(N'[account]', N'U') IS NULL\nCREATE TABLE [account]\n(\n" +
+			"    [id] BIGINT IDENTITY(1,1) PRIMARY KEY,\n" +
+			"    [tenant_id] BIGINT NOT NULL,\n" +
+			"    [email] NVARCHAR(64) NOT NULL,\n" +
+			"    [nickname] NVARCHAR(255) NULL,\n" +
+			"    [score] FLOAT NULL,\n" +
+			"    [level] INT NOT NULL DEFAULT 1,\n" +
+			"    [avatar] VARBINARY(MAX) NOT NULL,\n" +
+			"    [is_deleted] BIT NOT NULL DEFAULT 0,\n" +
+			"    [created_at] DATETIME2 NOT NULL,\n" +
+			"    INDEX [idx_account_tenant_id] ([tenant_id]),\n" +
+			"    CONSTRAINT [uk_account_email] UNIQUE ([email]),\n" +
+			"    INDEX [idx_account_score] ([score])\n)"}},
+		{"sqlite", dialect.SQLite, []Option{IfNotExists()}, []string{"CREATE TABLE IF NOT EXISTS \"account\"\n(\n" +
+			"    \"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+			"    \"tenant_id\" INTEGER NOT NULL,\n" +
+			"    \"email\" VARCHAR(64) NOT NULL,\n" +
+			"    \"nickname\" VARCHAR(255) NULL,\n" +
+			"    \"score\" REAL NULL,\n" +
+			"    \"level\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"    \"avatar\" BLOB NOT NULL,\n" +
+			"    \"is_deleted\" BOOLEAN NOT NULL DEFAULT 0,\n" +
+			"    \"created_at\" DATETIME NOT NULL,\n" +
+			"    CONSTRAINT \"uk_account_email\" UNIQUE (\"email\")\n)",
+			"CREATE INDEX IF NOT EXISTS \"idx_account_tenant_id\" ON \"account\" (\"tenant_id\")",
+			"CREATE INDEX IF NOT EXISTS \"idx_account_score\" ON \"account\" (\"score\")"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateTable(tt.driver, entity, tt.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	t.Run("sqlserver quoted table", func(t *testing.T) {
+		quoted := meta.NewEntity(account{})
+		quoted.TableName = "o'neil"
+		got, err := CreateTable(dialect.SQLServer, quoted, IfNotExists())
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, got) {
+			assert.True(t, strings.HasPrefix(got[0], "IF OBJECT_ID(N'[o''neil]', N'U') IS NULL\nCREATE TABLE [o'neil]"), got[0])
+		}
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := CreateTable(&dialect.Driver{Name: "oracle"}, entity)
+		assert.ErrorIs(t, err, ErrUnsupportedDialect)
+		_, err = CreateTable(dialect.MySQL, meta.NewEntity(struct{ Data map[string]any }{}))
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	})
+}
+
+func TestCreateTable_Exec(t *testing.T) {
+	f := sqlxx.NewFactory("schema")
+	defer f.Shutdown()
+	db, err := f.Open(sqlxx.DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := meta.NewEntity(account{})
+	entity.TableName += "_" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+	defer db.Execxx("DROP TABLE IF EXISTS " + entity.TableName)
+	stmts, err := CreateTable(db.Driver(), entity, IfNotExists())
+	assert.NoError(t, err)
+	for _, stmt := range stmts {
+		_, err = db.Execxx(stmt)
+		assert.NoError(t, err)
+	}
+	_, err = db.Execxx("INSERT INTO "+entity.TableName+" (tenant_id, email, avatar, created_at) VALUES (?, ?, ?, ?)", 1, "a@b.c", []byte{}, time.Now())
+	assert.NoError(t, err)
+	var level int
+	assert.NoError(t, db.Get(&level, "SELECT level FROM "+entity.TableName+" WHERE email = ?", "a@b.c"))
+	assert.Equal(t, 1, level)
+}
@@ -0,0 +1,364 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package schema
+
+import (
+	"context"
+	"fmt"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/gnodux/sqlxx/meta"
+	"reflect"
+	"strings"
+)
+
+// DiffKind 结构差异类型
+type DiffKind int
+
+const (
+	// MissingColumn 实体字段在表中没有对应的列
+	MissingColumn DiffKind = iota + 1
+	// TypeMismatch 列类型与字段类型不兼容
+	TypeMismatch
+	// NullableMismatch 列允许为空,字段不能接收空值
+	NullableMismatch
+	// ExtraColumn 表中的列没有对应的实体字段
+	ExtraColumn
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case MissingColumn:
+		return "missing"
+	case TypeMismatch:
+		return "type mismatch"
+	case NullableMismatch:
+		return "nullable mismatch"
+	case ExtraColumn:
+		return "extra"
+	}
+	return "unknown"
+}
+
+// columnQueries 各数据库方言读取表结构的语句(参数为表名,没有列表示表不存在)
+var columnQueries = map[string]string{
+	dialect.MySQL.Name: `SELECT column_name AS column_name, column_type AS data_type, is_nullable AS is_nullable,
+       CASE WHEN column_key = 'PRI' THEN 1 ELSE 0 END AS primary_key
+FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = :table ORDER BY ordinal_position`,
+	dialect.Postgres.Name: `SELECT c.column_name AS column_name, c.data_type AS data_type, c.is_nullable AS is_nullable,
+       CASE WHEN EXISTS (SELECT 1 FROM information_schema.table_constraints t
+           JOIN information_schema.key_column_usage k ON k.constraint_name = t.constraint_name AND k.table_schema = t.table_schema
+           WHERE t.constraint_type = 'PRIMARY KEY' AND t.table_schema = c.table_schema AND t.table_name = c.table_name AND k.column_name = c.column_name)
+       THEN 1 ELSE 0 END AS primary_key
+FROM information_schema.columns c WHERE c.table_schema = current_schema() AND c.table_name = :table ORDER BY c.ordinal_position`,
+	dialect.SQLServer.Name: `SELECT c.column_name AS column_name, c.data_type AS data_type, c.is_nullable AS is_nullable,
+       CASE WHEN EXISTS (SELECT 1 FROM information_schema.table_constraints t
+           JOIN information_schema.key_column_usage k ON k.constraint_name = t.constraint_name AND k.table_schema = t.table_schema
+           WHERE t.constraint_type = 'PRIMARY KEY' AND t.table_schema = c.table_schema AND t.table_name = c.table_name AND k.column_name = c.column_name)
+       THEN 1 ELSE 0 END AS primary_key
+FROM information_schema.columns c WHERE c.table_schema = SCHEMA_NAME() AND c.table_name = :table ORDER BY c.ordinal_position`,
+	dialect.SQLite.Name: `SELECT name AS column_name, type AS data_type, CASE WHEN "notnull" = 1 OR pk > 0 THEN 'NO' ELSE 'YES' END AS is_nullable,
+       CASE WHEN pk > 0 THEN 1 ELSE 0 END AS primary_key
+FROM pragma_table_info(:table) ORDER BY cid`,
+}
+
+// tableQueries 各数据库方言读取表名的语句
+var tableQueries = map[string]string{
+	dialect.MySQL.Name: `SELECT table_name AS table_name FROM information_schema.tables
+WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name`,
+	dialect.Postgres.Name: `SELECT table_name AS table_name FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name`,
+	dialect.SQLServer.Name: `SELECT table_name AS table_name FROM information_schema.tables
+WHERE table_schema = SCHEMA_NAME() AND table_type = 'BASE TABLE' ORDER BY table_name`,
+	dialect.SQLite.Name: `SELECT name AS table_name FROM sqlite_master
+WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`,
+}
+
+// ColumnInfo 数据库中的列
+type ColumnInfo struct {
+	ColumnName string
+	DataType   string
+	IsNullable string
+	PrimaryKey bool
+}
+
+// Nullable 列是否允许为空
+func (c *ColumnInfo) Nullable() bool {
+	return strings.EqualFold(c.IsNullable, "YES")
+}
+
+// ColumnDiff 列差异
+type ColumnDiff struct {
+	Kind DiffKind
+	//Name 列名
+	Name string
+	//Column 实体的列(ExtraColumn为nil)
+	Column *meta.Column
+	//Info 数据库的列(MissingColumn为nil)
+	Info *ColumnInfo
+}
+
+func (d *ColumnDiff) String() string {
+	switch d.Kind {
+	case MissingColumn:
+		return fmt.Sprintf("%s: %s(%s)", d.Kind, d.Name, d.Column.Type)
+	case ExtraColumn:
+		return fmt.Sprintf("%s: %s(%s)", d.Kind, d.Name, d.Info.DataType)
+	}
+	return fmt.Sprintf("%s: %s(%s) <> %s(%s,nullable=%s)", d.Kind, d.Name, d.Column.Type, d.Name, d.Info.DataType, d.Info.IsNullable)
+}
+
+// TableDiff 实体与数据表的差异
+type TableDiff struct {
+	Entity *meta.Entity
+	//Missing 表不存在
+	Missing bool
+	Columns []*ColumnDiff
+}
+
+// Empty 实体与数据表一致
+func (t *TableDiff) Empty() bool {
+	return !t.Missing && len(t.Columns) == 0
+}
+
+func (t *TableDiff) String() string {
+	if t.Missing {
+		return t.Entity.TableName + ": table missing"
+	}
+	var sb strings.Builder
+	sb.WriteString(t.Entity.TableName)
+	for _, col := range t.Columns {
+		sb.WriteString("\n  ")
+		sb.WriteString(col.String())
+	}
+	return sb.String()
+}
+
+// Alter 生成使表结构与实体一致的语句:表不存在时建表,添加缺少的列,修改不兼容的列
+//
+// 多余的列不会删除;SQLite不支持修改列,只添加缺少的列。语句执行前需要人工确认(例如非空列添加到已有数据的表)
+//
+// 修改列不会改变主键约束(MySQL的 MODIFY COLUMN 去掉 PRIMARY KEY);PostgreSQL 修改类型时使用 USING 转换已有数据,
+// 无法直接转换的数据(例如非数字字符串转为整数)需要人工处理
+func (t *TableDiff) Alter(driver *dialect.Driver) ([]string, error) {
+	types, err := lookup(driver)
+	if err != nil {
+		return nil, err
+	}
+	if t.Missing {
+		return CreateTable(driver, t.Entity)
+	}
+	var stmts []string
+	table := "ALTER TABLE " + driver.SQLNameFunc(t.Entity.TableName)
+	for _, diff := range t.Columns {
+		if diff.Kind == ExtraColumn {
+			continue
+		}
+		def, err := types.columnDef(driver, diff.Column)
+		if err != nil {
+			return nil, err
+		}
+		if diff.Kind == MissingColumn {
+			if driver.Name == dialect.SQLServer.Name {
+				stmts = append(stmts, table+" ADD "+def)
+			} else {
+				stmts = append(stmts, table+" ADD COLUMN "+def)
+			}
+			continue
+		}
+		sqlType, nullable, err := ColumnType(driver, diff.Column)
+		if err != nil {
+			return nil, err
+		}
+		name := driver.SQLNameFunc(diff.Column.ColumnName)
+		switch driver.Name {
+		case dialect.MySQL.Name:
+			//主键已经存在,重复定义 PRIMARY KEY 会报错
+			stmts = append(stmts, table+" MODIFY COLUMN "+strings.TrimSuffix(def, " PRIMARY KEY"))
+		case dialect.Postgres.Name:
+			if diff.Kind == TypeMismatch {
+				stmts = append(stmts, fmt.Sprintf("%s ALTER COLUMN %s TYPE %s USING %s::%s", table, name, sqlType, name, sqlType))
+			}
+			if !nullable {
+				stmts = append(stmts, fmt.Sprintf("%s ALTER COLUMN %s SET NOT NULL", table, name))
+			}
+		case dialect.SQLServer.Name:
+			null := " NOT NULL"
+			if nullable {
+				null = " NULL"
+			}
+			stmts = append(stmts, fmt.Sprintf("%s ALTER COLUMN %s %s%s", table, name, sqlType, null))
+		}
+	}
+	return stmts, nil
+}
+
+// Inspector 比较实体元数据与数据库中的表结构
+type Inspector struct {
+	db *sqlxx.DB
+}
+
+// NewInspector 创建结构检查器
+func NewInspector(db *sqlxx.DB) *Inspector {
+	return &Inspector{db: db}
+}
+
+// Tables 读取当前数据库(schema)中的表名
+func (i *Inspector) Tables(ctx context.Context) ([]string, error) {
+	driver := i.db.Driver()
+	if _, err := lookup(driver); err != nil {
+		return nil, err
+	}
+	var tables []string
+	if err := i.db.NamedSelectxxContext(ctx, &tables, tableQueries[driver.Name], map[string]any{}); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Columns 读取数据表的列(表不存在时返回空)
+func (i *Inspector) Columns(ctx context.Context, table string) ([]*ColumnInfo, error) {
+	driver := i.db.Driver()
+	if _, err := lookup(driver); err != nil {
+		return nil, err
+	}
+	var columns []*ColumnInfo
+	if err := i.db.NamedSelectxxContext(ctx, &columns, columnQueries[driver.Name], map[string]any{"table": table}); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// Inspect 比较实体与数据表,返回存在差异的表。没有传入实体时检查数据库登记的所有实体(参考 sqlxx.DB.Entities)
+func (i *Inspector) Inspect(ctx context.Context, entities ...*meta.Entity) ([]*TableDiff, error) {
+	if len(entities) == 0 {
+		entities = i.db.Entities()
+	}
+	var diffs []*TableDiff
+	for _, entity := range entities {
+		diff, err := i.InspectEntity(ctx, entity)
+		if err != nil {
+			return nil, err
+		}
+		if !diff.Empty() {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+// InspectEntity 比较单个实体与数据表
+func (i *Inspector) InspectEntity(ctx context.Context, entity *meta.Entity) (*TableDiff, error) {
+	infos, err := i.Columns(ctx, entity.TableName)
+	if err != nil {
+		return nil, err
+	}
+	diff := &TableDiff{Entity: entity}
+	if len(infos) == 0 {
+		diff.Missing = true
+		return diff, nil
+	}
+	byName := make(map[string]*ColumnInfo, len(infos))
+	for _, info := range infos {
+		byName[strings.ToLower(info.ColumnName)] = info
+	}
+	known := map[string]bool{}
+	for _, col := range entity.Columns {
+		if col.Ignore {
+			continue
+		}
+		name := strings.ToLower(col.ColumnName)
+		known[name] = true
+		info, ok := byName[name]
+		switch {
+		case !ok:
+			diff.Columns = append(diff.Columns, &ColumnDiff{Kind: MissingColumn, Name: col.ColumnName, Column: col})
+		case !compatible(col.Type, info.DataType):
+			diff.Columns = append(diff.Columns, &ColumnDiff{Kind: TypeMismatch, Name: col.ColumnName, Column: col, Info: info})
+		case info.Nullable() && !isNullable(col.Type):
+			diff.Columns = append(diff.Columns, &ColumnDiff{Kind: NullableMismatch, Name: col.ColumnName, Column: col, Info: info})
+		}
+	}
+	for _, info := range infos {
+		if !known[strings.ToLower(info.ColumnName)] {
+			diff.Columns = append(diff.Columns, &ColumnDiff{Kind: ExtraColumn, Name: info.ColumnName, Info: info})
+		}
+	}
+	return diff, nil
+}
+
+// 类型分类,用于判断字段类型与列类型是否兼容
+const (
+	categoryUnknown = iota
+	categoryBool
+	categoryInteger
+	categoryFloat
+	categoryString
+	categoryBytes
+	categoryTime
+)
+
+// goCategory 字段类型的分类(实现了sql.Scanner等的自定义类型为未知)
+func goCategory(t reflect.Type) int {
+	t, _ = Nullable(t)
+	if t == timeType {
+		return categoryTime
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return categoryBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return categoryInteger
+	case reflect.Float32, reflect.Float64:
+		return categoryFloat
+	case reflect.String:
+		return categoryString
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return categoryBytes
+		}
+	}
+	return categoryUnknown
+}
+
+// sqlCategory 列类型的分类(参考SQLite的类型亲和规则)
+func sqlCategory(dataType string) int {
+	t := strings.ToUpper(dataType)
+	switch {
+	case strings.HasPrefix(t, "BOOL") || strings.HasPrefix(t, "BIT"):
+		return categoryBool
+	case strings.Contains(t, "INT"):
+		return categoryInteger
+	case strings.Contains(t, "CHAR") || strings.Contains(t, "TEXT") || strings.Contains(t, "CLOB") || strings.Contains(t, "UUID"):
+		return categoryString
+	case strings.Contains(t, "BLOB") || strings.Contains(t, "BINARY") || strings.Contains(t, "BYTEA"):
+		return categoryBytes
+	case strings.Contains(t, "REAL") || strings.Contains(t, "FLOA") || strings.Contains(t, "DOUB") ||
+		strings.Contains(t, "DEC") || strings.Contains(t, "NUMERIC") || strings.Contains(t, "MONEY"):
+		return categoryFloat
+	case strings.Contains(t, "DATE") || strings.Contains(t, "TIME"):
+		return categoryTime
+	}
+	return categoryUnknown
+}
+
+// compatible 字段类型与列类型是否兼容(无法判断时视为兼容,布尔值可以使用整数列)
+func compatible(t reflect.Type, dataType string) bool {
+	goCat, sqlCat := goCategory(t), sqlCategory(dataType)
+	if goCat == categoryUnknown || sqlCat == categoryUnknown || goCat == sqlCat {
+		return true
+	}
+	return goCat == categoryBool && sqlCat == categoryInteger
+}
+
+// isNullable 字段能否接收空值
+func isNullable(t reflect.Type) bool {
+	if _, nullable := Nullable(t); nullable {
+		return true
+	}
+	return goCategory(t) == categoryUnknown || t.Kind() == reflect.Slice
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"github.com/gnodux/sqlxx"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/gnodux/sqlxx/meta"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type drifted struct {
+	ID       int64
+	Name     string
+	Age      int
+	Nickname string
+	Email    *string
+}
+
+type driftedMapper struct {
+	sqlxx.BaseMapper[drifted]
+}
+
+func TestCompatible(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		dataType string
+		want     bool
+	}{
+		{"int", int64(0), "bigint(20)", true},
+		{"bool tinyint", false, "tinyint(1)", true},
+		{"bool bit", false, "bit", true},
+		{"string", "", "character varying", true},
+		{"string text", "", "TEXT", true},
+		{"time", time.Time{}, "timestamp without time zone", true},
+		{"null float", sql.NullFloat64{}, "double precision", true},
+		{"bytes", []byte{}, "varbinary", true},
+		{"string int", "", "int", false},
+		{"int varchar", 0, "varchar(64)", false},
+		{"time int", time.Time{}, "bigint", false},
+		{"unknown", map[string]any{}, "json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, compatible(reflect.TypeOf(tt.value), tt.dataType))
+		})
+	}
+}
+
+func TestTableDiff_Alter(t *testing.T) {
+	entity := meta.NewEntity(drifted{})
+	diff := &TableDiff{Entity: entity, Columns: []*ColumnDiff{
+		{Kind: TypeMismatch, Name: "id", Column: entity.Column("id"), Info: &ColumnInfo{ColumnName: "id", DataType: "int", IsNullable: "NO"}},
+		{Kind: MissingColumn, Name: "nickname", Column: entity.Column("nickname")},
+		{Kind: TypeMismatch, Name: "age", Column: entity.Column("age"), Info: &ColumnInfo{ColumnName: "age", DataType: "varchar", IsNullable: "NO"}},
+		{Kind: ExtraColumn, Name: "legacy", Info: &ColumnInfo{ColumnName: "legacy", DataType: "int", IsNullable: "YES"}},
+	}}
+	tests := []struct {
+		name   string
+		driver *dialect.Driver
+		want   []string
+	}{
+		{"mysql", dialect.MySQL, []string{
+			"ALTER TABLE `drifted` MODIFY COLUMN `id` BIGINT NOT NULL AUTO_INCREMENT",
+			"ALTER TABLE `drifted` ADD COLUMN `nickname` VARCHAR(255) NOT NULL",
+			"ALTER TABLE `drifted` MODIFY COLUMN `age` BIGINT NOT NULL"}},
+		{"postgres", dialect.Postgres, []string{
+			`ALTER TABLE "drifted" ALTER COLUMN "id" TYPE BIGINT USING "id"::BIGINT`,
+			`ALTER TABLE "drifted" ALTER COLUMN "id" SET NOT NULL`,
+			`ALTER TABLE "drifted" ADD COLUMN "nickname" VARCHAR(255) NOT NULL`,
+			`ALTER TABLE "drifted" ALTER COLUMN "age" TYPE BIGINT USING "age"::BIGINT`,
+			`ALTER TABLE "drifted" ALTER COLUMN "age" SET NOT NULL`}},
+		{"sqlserver", dialect.SQLServer, []string{
+			"ALTER TABLE [drifted] ALTER COLUMN [id] BIGINT NOT NULL",
+			"ALTER TABLE [drifted] ADD [nickname] NVARCHAR(255) NOT NULL",
+			"ALTER TABLE [drifted] ALTER COLUMN [age] BIGINT NOT NULL"}},
+		{"sqlite", dialect.SQLite, []string{`ALTER TABLE "drifted" ADD COLUMN "nickname" VARCHAR(255) NOT NULL`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diff.Alter(tt.driver)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInspector(t *testing.T) {
+	f := sqlxx.NewFactory("inspect")
+	defer f.Shutdown()
+	db, err := f.Open(sqlxx.DefaultName, "mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := &driftedMapper{}
+	assert.NoError(t, f.BoostMapper(mapper, sqlxx.DefaultName))
+	assert.Equal(t, []*meta.Entity{mapper.Meta()}, db.Entities())
+	entity := mapper.Meta()
+	ctx := context.Background()
+	inspector := NewInspector(db)
+
+	// 使用独立的表名,避免并发测试冲突
+	origin := entity.TableName
+	entity.TableName = "drifted_" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+	defer func() {
+		entity.TableName = origin
+	}()
+	defer db.Execxx("DROP TABLE IF EXISTS " + entity.TableName)
+
+	diffs, err := inspector.Inspect(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, diffs, 1) {
+		assert.True(t, diffs[0].Missing)
+	}
+
+	_, err = db.Execxx("CREATE TABLE " + entity.TableName + " (id BIGINT PRIMARY KEY AUTO_INCREMENT, name VARCHAR(64) NULL, age VARCHAR(8) NOT NULL, email VARCHAR(64) NULL, legacy INT NULL)")
+	assert.NoError(t, err)
+	tables, err := inspector.Tables(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, tables, entity.TableName)
+	infos, err := inspector.Columns(ctx, entity.TableName)
+	assert.NoError(t, err)
+	if assert.Len(t, infos, 5) {
+		assert.True(t, infos[0].PrimaryKey)
+		assert.False(t, infos[1].PrimaryKey)
+	}
+	diff, err := inspector.InspectEntity(ctx, entity)
+	assert.NoError(t, err)
+	var kinds []string
+	for _, col := range diff.Columns {
+		kinds = append(kinds, col.Name+" "+col.Kind.String())
+	}
+	assert.Equal(t, []string{"name nullable mismatch", "age type mismatch", "nickname missing", "legacy extra"}, kinds)
+
+	stmts, err := diff.Alter(db.Driver())
+	assert.NoError(t, err)
+	for _, stmt := range stmts {
+		_, err = db.Execxx(stmt)
+		assert.NoError(t, err, stmt)
+	}
+	diff, err = inspector.InspectEntity(ctx, entity)
+	assert.NoError(t, err)
+	if assert.Len(t, diff.Columns, 1) {
+		assert.Equal(t, ExtraColumn, diff.Columns[0].Kind)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"fmt"
+	"github.com/cookieY/sqlx"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const modulePath = "github.com/gnodux/sqlxx"
+
+// SlowQuery 慢查询记录
+type SlowQuery struct {
+	//DB 执行语句的数据库
+	DB *DB
+	//Query 渲染后的SQL
+	Query string
+	//Args 脱敏后的参数
+	Args any
+	//Template 模版名称
+	Template string
+	//Method mapper方法名称
+	Method string
+	//Caller 调用位置(sqlxx之外的第一个调用栈, file:line)
+	Caller string
+	//Duration 执行耗时
+	Duration time.Duration
+	//Err 执行错误
+	Err error
+	//Plan 执行计划(仅在开启Explain时)
+	Plan []map[string]any
+	//PlanErr 获取执行计划的错误
+	PlanErr error
+}
+
+func (s *SlowQuery) String() string {
+	sb := &strings.Builder{}
+	_, _ = fmt.Fprintf(sb, "slow query(%s): %s args:%v", s.Duration, s.Query, s.Args)
+	if s.Template != "" {
+		_, _ = fmt.Fprintf(sb, " template:%s", s.Template)
+	}
+	if s.Method != "" {
+		_, _ = fmt.Fprintf(sb, " method:%s", s.Method)
+	}
+	if s.Caller != "" {
+		_, _ = fmt.Fprintf(sb, " caller:%s", s.Caller)
+	}
+	if s.Err != nil {
+		_, _ = fmt.Fprintf(sb, " error:%s", s.Err)
+	}
+	if s.Plan != nil {
+		_, _ = fmt.Fprintf(sb, " plan:%v", s.Plan)
+	}
+	if s.PlanErr != nil {
+		_, _ = fmt.Fprintf(sb, " plan error:%s", s.PlanErr)
+	}
+	return sb.String()
+}
+
+// SlowQueryConfig 慢查询配置
+type SlowQueryConfig struct {
+	//Threshold 慢查询阈值,执行时间大于等于该值的语句会被记录
+	Threshold time.Duration
+	//Explain 是否获取执行计划(语法由dialect.Driver.Explain决定,不支持的数据库将忽略)
+	Explain bool
+	//ExplainTimeout 获取执行计划的超时时间,默认5秒
+	ExplainTimeout time.Duration
+	//Redactor 参数脱敏函数,默认对敏感列脱敏(RedactSensitive,与语句日志一致)
+	Redactor Redactor
+	//Handler 慢查询处理函数,默认使用日志输出(Warn)
+	Handler func(ctx context.Context, query *SlowQuery)
+}
+
+type slowQueryInterceptor struct {
+	cfg SlowQueryConfig
+}
+
+// NewSlowQueryInterceptor 创建慢查询拦截器
+func NewSlowQueryInterceptor(cfg SlowQueryConfig) Interceptor {
+	if cfg.Redactor == nil {
+		cfg.Redactor = RedactSensitive
+	}
+	if cfg.ExplainTimeout <= 0 {
+		cfg.ExplainTimeout = 5 * time.Second
+	}
+	if cfg.Handler == nil {
+		cfg.Handler = func(_ context.Context, query *SlowQuery) {
+			logger := log
+			if query.DB != nil {
+				logger = query.DB.Logger()
+			}
+			logger.Warn(query)
+		}
+	}
+	return &slowQueryInterceptor{cfg: cfg}
+}
+
+// WithSlowQuery 打开数据库时开启慢查询检测
+func WithSlowQuery(cfg SlowQueryConfig) Option {
+	return func(d *DB) {
+		d.Use(NewSlowQueryInterceptor(cfg))
+	}
+}
+
+// UseSlowQuery 开启慢查询检测
+func (d *DB) UseSlowQuery(cfg SlowQueryConfig) {
+	d.Use(NewSlowQueryInterceptor(cfg))
+}
+
+func (s *slowQueryInterceptor) Before(ctx context.Context, _ *Statement) (context.Context, error) {
+	return ctx, nil
+}
+
+func (s *slowQueryInterceptor) After(ctx context.Context, stmt *Statement) {
+	if stmt.Duration < s.cfg.Threshold {
+		return
+	}
+	query := &SlowQuery{
+		DB:       stmt.DB,
+		Query:    stmt.Query,
+		Args:     RedactStatement(stmt, s.cfg.Redactor),
+		Template: stmt.Template,
+		Method:   stmt.Method,
+		Caller:   callSite(),
+		Duration: stmt.Duration,
+		Err:      stmt.Err,
+	}
+	if s.cfg.Explain && stmt.DB != nil && stmt.Op != OpPrepare {
+		query.Plan, query.PlanErr = s.explain(stmt)
+	}
+	s.cfg.Handler(ctx, query)
+}
+
+// explainSavepoint 事务中获取执行计划时使用的保存点
+const explainSavepoint = "sqlxx_explain"
+
+// explain 获取执行计划(不经过拦截器)
+//
+// 语句在事务中执行时使用同一个事务(可以看到事务中未提交的表,也不会额外占用连接),
+// 并在保存点中执行,避免EXPLAIN失败时影响事务(例如PostgreSQL中的错误会中止事务);否则使用连接池执行
+func (s *slowQueryInterceptor) explain(stmt *Statement) (plan []map[string]any, err error) {
+	driver := stmt.DB.driver
+	if driver == nil || driver.Explain == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ExplainTimeout)
+	defer cancel()
+	var ext sqlx.ExtContext = stmt.DB.DB
+	if stmt.Tx != nil && stmt.Tx.Tx != nil {
+		tx := stmt.Tx.Tx
+		ext = tx
+		if driver.Savepoint != "" {
+			if _, err = tx.ExecContext(ctx, driver.SavepointSQL(explainSavepoint)); err != nil {
+				return nil, err
+			}
+			defer func() {
+				if err != nil {
+					_, _ = tx.ExecContext(ctx, driver.RollbackToSQL(explainSavepoint))
+				} else if release := driver.ReleaseSavepointSQL(explainSavepoint); release != "" {
+					_, err = tx.ExecContext(ctx, release)
+				}
+			}()
+		}
+	}
+	query := driver.Explain + stmt.Query
+	var rows *sqlx.Rows
+	switch args := stmt.Args.(type) {
+	case nil:
+		rows, err = ext.QueryxContext(ctx, query)
+	case []any:
+		rows, err = ext.QueryxContext(ctx, query, args...)
+	default:
+		rows, err = sqlx.NamedQueryContext(ctx, ext, query, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	for rows.Next() {
+		row := map[string]any{}
+		if err = rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		plan = append(plan, row)
+	}
+	return plan, rows.Err()
+}
+
+// callSite 获取sqlxx之外的第一个调用位置
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, modulePath+".") || strings.HasPrefix(frame.Function, modulePath+"/")
+		if (!internal || strings.HasSuffix(frame.File, "_test.go")) &&
+			!strings.HasPrefix(frame.Function, "reflect.") && !strings.HasPrefix(frame.Function, "runtime.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
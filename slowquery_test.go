@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/cookieY/sqlx"
+	"github.com/gnodux/sqlxx/dialect"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     any
+		redactor Redactor
+		want     any
+	}{
+		{"nil", nil, RedactAll, nil},
+		{"positional", []any{1, "secret"}, RedactAll, []any{Redacted, Redacted}},
+		{"none", []any{1, "secret"}, RedactNone, []any{1, "secret"}},
+		{"named", map[string]any{"name": "gnodux", "password": "secret"}, RedactNames("Password"),
+			map[string]any{"name": "gnodux", "password": Redacted}},
+		{"struct", &User{Name: "gnodux", Password: "secret", TenantID: 1}, RedactNames("Password"),
+			map[string]any{"name": "gnodux", "password": Redacted, "tenant_id": int64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RedactArgs(tt.args, tt.redactor))
+		})
+	}
+}
+
+type RedactAccount struct {
+	Id       int64
+	Name     string
+	Password string `dbx:"sensitive"`
+}
+
+func TestRedactStatement(t *testing.T) {
+	RegisterSensitive(reflect.TypeOf(RedactAccount{}))
+	tests := []struct {
+		name     string
+		query    string
+		args     any
+		redactor Redactor
+		want     any
+	}{
+		{"positional", "SELECT * FROM `redact_account` WHERE `name` = ?", []any{"a"}, RedactSensitive, []any{Redacted}},
+		{"positional without sensitive table", "SELECT * FROM `config` WHERE `name` = ?", []any{"a"}, RedactSensitive, []any{"a"}},
+		{"named", "UPDATE `redact_account` SET `password` = :password WHERE `name` = :name",
+			map[string]any{"name": "a", "password": "secret"}, RedactSensitive, map[string]any{"name": "a", "password": Redacted}},
+		{"same column in other table", "UPDATE `config` SET `password` = :password",
+			map[string]any{"password": "secret"}, RedactSensitive, map[string]any{"password": "secret"}},
+		{"struct", "INSERT INTO `account_log` (`name`, `password`) VALUES (:name, :password)",
+			RedactAccount{Name: "a", Password: "secret"}, RedactSensitive, map[string]any{"name": "a", "password": Redacted}},
+		{"other redactor", "SELECT * FROM `redact_account` WHERE `name` = ?", []any{"a"}, RedactNone, []any{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RedactStatement(&Statement{Query: tt.query, Args: tt.args}, tt.redactor))
+		})
+	}
+}
+
+func TestSlowQuery(t *testing.T) {
+	RegisterSensitive(reflect.TypeOf(auditedUser{}))
+	f := NewFactory("slow")
+	var slows []*SlowQuery
+	db, err := f.Open(DefaultName, "mysql", testDSN,
+		WithSlowQuery(SlowQueryConfig{
+			Threshold: time.Nanosecond,
+			Explain:   true,
+			Handler: func(ctx context.Context, query *SlowQuery) {
+				slows = append(slows, query)
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	var users []User
+	assert.NoError(t, db.Selectxx(&users, "SELECT * FROM `user` WHERE `name` = ?", "user_1"))
+	assert.NoError(t, db.Selectxx(&users, "SELECT * FROM `user` LIMIT 10"))
+	if assert.Len(t, slows, 2) {
+		//默认按照敏感列脱敏:user表包含敏感列(auditedUser.Password),位置参数全部脱敏
+		assert.Equal(t, []any{Redacted}, slows[0].Args)
+		assert.True(t, strings.Contains(slows[0].Caller, "slowquery_test.go"), slows[0].Caller)
+	}
+}
+
+// explainDriver 返回固定执行计划的数据库驱动(测试用的MySQL服务端不能返回可解析的EXPLAIN结果)
+type explainDriver struct{}
+
+func init() {
+	sql.Register("sqlxx_explain", explainDriver{})
+}
+
+func (explainDriver) Open(string) (driver.Conn, error) { return explainConn{}, nil }
+
+type explainConn struct{}
+
+func (explainConn) Prepare(query string) (driver.Stmt, error) { return explainStmt(query), nil }
+func (explainConn) Close() error                              { return nil }
+func (explainConn) Begin() (driver.Tx, error)                 { return explainTx{}, nil }
+
+type explainTx struct{}
+
+func (explainTx) Commit() error   { return nil }
+func (explainTx) Rollback() error { return nil }
+
+type explainStmt string
+
+func (explainStmt) Close() error  { return nil }
+func (explainStmt) NumInput() int { return -1 }
+func (explainStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (s explainStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.HasPrefix(string(s), "EXPLAIN ") {
+		return &explainRows{columns: []string{"id", "table", "type"}, rows: [][]driver.Value{{int64(1), "user", "ALL"}}}, nil
+	}
+	return &explainRows{columns: []string{"id"}}, nil
+}
+
+type explainRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *explainRows) Columns() []string { return r.columns }
+func (r *explainRows) Close() error      { return nil }
+func (r *explainRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSlowQuery_Explain(t *testing.T) {
+	d := *dialect.MySQL
+	d.Name = "sqlxx_explain"
+	var slows []*SlowQuery
+	db, err := OpenWith(NewFactory("explain"), &d, "", WithSlowQuery(SlowQueryConfig{
+		Threshold: time.Nanosecond,
+		Explain:   true,
+		Handler: func(ctx context.Context, query *SlowQuery) {
+			slows = append(slows, query)
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var ids []int64
+	assert.NoError(t, db.Selectxx(&ids, "SELECT `id` FROM `user` WHERE `name` = ?", "user_1"))
+	if assert.Len(t, slows, 1) {
+		assert.NoError(t, slows[0].PlanErr)
+		assert.Equal(t, []map[string]any{{"id": int64(1), "table": "user", "type": "ALL"}}, slows[0].Plan)
+	}
+	//事务中使用同一个事务获取执行计划(连接池只有一个连接时也不会阻塞)
+	slows = nil
+	db.SetMaxOpenConns(1)
+	start := time.Now()
+	assert.NoError(t, db.Batch(context.Background(), nil, func(tx *Tx) error {
+		return tx.NamedSelect(&ids, "SELECT `id` FROM `user` WHERE `name` = :name", map[string]any{"name": "user_1"})
+	}))
+	assert.Less(t, time.Since(start), time.Second)
+	if assert.Len(t, slows, 1) {
+		assert.NoError(t, slows[0].PlanErr)
+		assert.NotEmpty(t, slows[0].Plan)
+	}
+	//预编译语句不获取执行计划
+	slows = nil
+	assert.NoError(t, db.RunPrepared("SELECT `id` FROM `user`", nil, func(stmt *sqlx.Stmt) error {
+		return nil
+	}))
+	if assert.Len(t, slows, 1) {
+		assert.Nil(t, slows[0].Plan)
+		assert.NoError(t, slows[0].PlanErr)
+	}
+}
@@ -76,6 +76,11 @@ func allColumns(driver *dialect.Driver, cols []*Column) string {
 	for _, c := range cols {
 		sb.WriteString(pre)
 		sb.WriteString(driver.SQLNameFunc(c.ColumnName))
+		if c.Nested() {
+			//嵌入结构体的列使用字段路径作为别名
+			sb.WriteString(driver.KeywordWithSpace("AS"))
+			sb.WriteString(driver.SQLNameFunc(c.Path))
+		}
 		pre = ","
 	}
 	return sb.String()
@@ -90,7 +95,7 @@ func args(driver *dialect.Driver, cols []*Column) string {
 		}
 		sb.WriteString(pre)
 		sb.WriteString(driver.NamedPrefix)
-		sb.WriteString(c.ColumnName)
+		sb.WriteString(c.ArgName())
 		pre = ","
 	}
 	return sb.String()
@@ -106,7 +111,7 @@ func sets(cols []*Column, driver *dialect.Driver) string {
 		sb.WriteString(pre)
 		sb.WriteString(driver.SQLNameFunc(c.ColumnName))
 		sb.WriteString("=" + driver.NamedPrefix)
-		sb.WriteString(c.ColumnName)
+		sb.WriteString(c.ArgName())
 		pre = ","
 	}
 	return sb.String()
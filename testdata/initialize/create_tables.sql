@@ -46,3 +46,14 @@ CREATE TABLE IF NOT EXISTS `transaction`
     `status`          enum (' Draft ',' Done ',' Cancel ') NOT NULL COMMENT ' 交易状态 ',
     `is_deleted` BOOLEAN DEFAULT FALSE
 ) COMMENT ' 交易表 ';
+CREATE TABLE IF NOT EXISTS `sqlxx_audit`
+(
+    `id`           BIGINT PRIMARY KEY AUTO_INCREMENT NOT NULL,
+    `entity_table` VARCHAR(128)                      NOT NULL,
+    `operation`    VARCHAR(16)                       NOT NULL,
+    `entity_id`    VARCHAR(64)                       NOT NULL,
+    `tenant_id`    VARCHAR(64),
+    `actor`        VARCHAR(128),
+    `diff`         TEXT                              NOT NULL,
+    `created_at`   DATETIME                          NOT NULL
+) COMMENT '变更审计表';
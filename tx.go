@@ -6,21 +6,92 @@
 package sqlxx
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"github.com/cookieY/sqlx"
 	"github.com/gnodux/sqlxx/expr"
+	"sync/atomic"
 )
 
 // Tx transaction wrapper
 type Tx struct {
 	*sqlx.Tx
+	id  uint64
 	db  *DB
 	tpl string
+	ctx context.Context
+	//root 最外层事务(加入已有事务或创建保存点时不为空)
+	root *Tx
+	//savepoint 保存点名称(PropagationNested)
+	savepoint  string
+	savepoints int
+	done       atomic.Bool
+	//callbacks 事务结束后执行的回调(加入已有事务时与外层事务共享)
+	callbacks *txCallbacks
+	//status 事务(或保存点)的状态(加入已有事务时与外层事务共享)
+	status *txStatus
+}
+
+// txStatus 事务(或保存点)的状态
+type txStatus struct {
+	//cause 加入该事务的函数返回的错误,不为空时事务只能回滚(参考 ErrRollbackOnly)
+	cause error
+}
+
+// RollbackOnly 事务是否只能回滚(加入该事务的函数返回了错误)
+func (t *Tx) RollbackOnly() bool {
+	return t.status != nil && t.status.cause != nil
+}
+
+// setRollbackOnly 标记事务只能回滚,保留第一个错误
+func (t *Tx) setRollbackOnly(cause error) {
+	if t.status != nil && t.status.cause == nil {
+		t.status.cause = cause
+	}
+}
+
+// rollbackOnlyErr 事务只能回滚时返回 ErrRollbackOnly(包装导致回滚的错误)
+func (t *Tx) rollbackOnlyErr() error {
+	if !t.RollbackOnly() {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrRollbackOnly, t.status.cause)
+}
+
+var txSeq uint64
+
+// ID 事务编号(进程内唯一),用于日志关联同一事务中的语句
+func (t *Tx) ID() uint64 {
+	return t.id
 }
 
 func (t *Tx) Tpl() string {
 	return t.tpl
 }
+
+// Savepoint 当前保存点名称(非保存点事务为空)
+func (t *Tx) Savepoint() string {
+	return t.savepoint
+}
+
+// physical 获取实际的数据库事务(最外层事务)
+func (t *Tx) physical() *Tx {
+	if t.root != nil {
+		return t.root
+	}
+	return t
+}
+
+// Context 获取事务的上下文(包含当前事务,参考 WithTx)
+//
+// 使用该上下文调用mapper方法或 DB.BatchWith 时会根据传播行为复用当前事务
+func (t *Tx) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
 func (t *Tx) Parse(tplName string, args any) (string, error) {
 	if t.db == nil {
 		return "", ErrNilDB
@@ -33,37 +104,14 @@ func (t *Tx) SelectExpr(dest interface{}, exp expr.Expr) error {
 	if t == nil {
 		return ErrNilDB
 	}
-	buff := expr.NewTracedBuffer(t.db.driver)
-	if t.db.driver.SupportNamed {
-		query, namedArgs, err := buff.BuildNamed(exp)
-		if err != nil {
-			return err
-		}
-		return t.NamedSelect(dest, query, namedArgs)
-	} else {
-		query, args, err := buff.Build(exp)
-		if err != nil {
-			return err
-		}
-		return t.Select(dest, query, args...)
-	}
+	return t.db.selectExpr(t.Context(), t, dest, exp)
 }
 
 func (t *Tx) NamedSelect(dest interface{}, sql string, arg any) (err error) {
 	if t == nil {
 		return ErrNilDB
 	}
-	var named *sqlx.NamedStmt
-	named, err = t.PrepareNamed(sql)
-	if err != nil {
-		return err
-	}
-	defer func(named *sqlx.NamedStmt) {
-		err = named.Close()
-
-	}(named)
-	log.Debug("named select:", named.QueryString, arg)
-	return named.Select(dest, arg)
+	return t.db.doNamedSelect(t.Context(), t, dest, "", sql, arg)
 }
 
 // ExecExpr 使用表达式进行执行
@@ -71,50 +119,17 @@ func (t *Tx) ExecExpr(exp expr.Expr) (sql.Result, error) {
 	if t == nil {
 		return nil, ErrNilDB
 	}
-	buff := expr.NewTracedBuffer(t.db.driver)
-	if t.db.driver.SupportNamed {
-		query, namedArgs, err := buff.BuildNamed(exp)
-		if err != nil {
-			return nil, err
-		}
-		return t.NamedExec(query, namedArgs)
-	} else {
-		query, args, err := buff.Build(exp)
-		if err != nil {
-			return nil, err
-		}
-		return t.Exec(query, args...)
-	}
+	return t.db.execExpr(t.Context(), t, exp)
 }
 
 func (t *Tx) GetExpr(dest interface{}, exp expr.Expr) error {
 	if t == nil {
 		return ErrNilDB
 	}
-	buff := expr.NewTracedBuffer(t.db.driver)
-	if t.db.driver.SupportNamed {
-		query, namedArgs, err := buff.BuildNamed(exp)
-		if err != nil {
-			return err
-		}
-		return t.NamedGet(dest, query, namedArgs)
-	} else {
-		query, args, err := buff.Build(exp)
-		if err != nil {
-			return err
-		}
-		return t.Get(dest, query, args...)
-	}
+	return t.db.getExpr(t.Context(), t, dest, exp)
 }
 func (t *Tx) NamedGet(dest interface{}, query string, arg interface{}) error {
-	stmt, err := t.PrepareNamed(query)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = stmt.Close()
-	}()
-	return stmt.Get(dest, arg)
+	return t.db.doNamedGet(t.Context(), t, dest, "", query, arg)
 }
 
 // ParseAndPrepareNamed use tplName to parse and prepare named statement
@@ -123,22 +138,16 @@ func (t *Tx) ParseAndPrepareNamed(tplName string, arg any) (*sqlx.NamedStmt, err
 	if err != nil {
 		return nil, err
 	}
-	return t.PrepareNamed(query)
+	return t.db.doPrepareNamed(t.Context(), t, tplName, query, arg)
 }
 
 // RunPrepareNamedxx use tplName to prepare named statement
 func (t *Tx) RunPrepareNamedxx(sqlOrTpl string, arg any, fn func(*sqlx.NamedStmt) error) (err error) {
-	var stmt *sqlx.NamedStmt
-	if stmt, err = t.ParseAndPrepareNamed(sqlOrTpl, arg); err != nil {
+	var query string
+	if query, err = t.Parse(sqlOrTpl, arg); err != nil {
 		return
 	}
-	defer func() {
-		stErr := stmt.Close()
-		if stErr != nil {
-			err = stErr
-		}
-	}()
-	return fn(stmt)
+	return t.db.runPrepareNamed(t.Context(), t, sqlOrTpl, query, arg, fn)
 }
 
 // RunCurrentPrepareNamed use current tpl to prepare named statement
@@ -151,32 +160,24 @@ func (t *Tx) ParseAndPrepare(sqlOrTpl string, arg any) (*sqlx.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return t.Preparex(query)
+	return t.db.doPrepare(t.Context(), t, sqlOrTpl, query, arg)
 }
 func (t *Tx) RunPreparedxx(sqlOrTpl string, arg any, fn func(*sqlx.Stmt) error) (err error) {
-	var stmt *sqlx.Stmt
-	if stmt, err = t.ParseAndPrepare(sqlOrTpl, arg); err != nil {
+	var query string
+	if query, err = t.Parse(sqlOrTpl, arg); err != nil {
 		return
 	}
-	defer func() {
-		stErr := stmt.Close()
-		if stErr != nil {
-			err = stErr
-		}
-	}()
-	return fn(stmt)
+	return t.db.runPrepared(t.Context(), t, sqlOrTpl, query, arg, fn)
 }
 func (t *Tx) RunCurrentPrepared(arg any, fn func(*sqlx.Stmt) error) (err error) {
 	return t.RunPreparedxx(t.tpl, arg, fn)
 }
 
 func (t *Tx) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
-	log.Debug("prepare named query:", query)
-	return t.Tx.PrepareNamed(query)
+	return t.db.doPrepareNamed(t.Context(), t, "", query, nil)
 }
 func (t *Tx) Preparex(query string) (*sqlx.Stmt, error) {
-	log.Debug("prepare query:", query)
-	return t.Tx.Preparex(query)
+	return t.db.doPrepare(t.Context(), t, "", query, nil)
 }
 
 // NamedExecxx  use tpl to query named statement
@@ -185,8 +186,7 @@ func (t *Tx) NamedExecxx(sqlOrTpl string, arg interface{}) (sql.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Debug("named exec tpl:", query, arg)
-	return t.NamedExec(query, arg)
+	return t.db.doNamedExec(t.Context(), t, sqlOrTpl, query, arg)
 }
 
 func (t *Tx) Execxx(sqlOrTpl string, args ...interface{}) (sql.Result, error) {
@@ -194,8 +194,7 @@ func (t *Tx) Execxx(sqlOrTpl string, args ...interface{}) (sql.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Debug("exec query:", query, args)
-	return t.Exec(query, args...)
+	return t.db.doExec(t.Context(), t, sqlOrTpl, query, args)
 }
 
 // ExecCurrent use current tpl to exec
@@ -213,14 +212,17 @@ func (t *Tx) Getxx(dest any, tpl string, args ...any) error {
 	if err != nil {
 		return err
 	}
-	log.Debug("get query:", query, args)
-	return t.Get(dest, query, args...)
+	return t.db.doGet(t.Context(), t, dest, tpl, query, args)
 }
 
 func NewTxWith(tx *sqlx.Tx, d *DB, tpl string) *Tx {
 	return &Tx{
 		Tx:  tx,
+		id:  atomic.AddUint64(&txSeq, 1),
 		db:  d,
 		tpl: tpl,
+
+		callbacks: &txCallbacks{},
+		status:    &txStatus{},
 	}
 }
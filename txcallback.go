@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+// txCallbacks 事务结束后执行的回调
+type txCallbacks struct {
+	onCommit   []func()
+	onRollback []func(err error)
+	onComplete []func(err error)
+}
+
+// merge 合并回调(保存点释放后,回调由外层事务执行)
+func (c *txCallbacks) merge(other *txCallbacks) {
+	c.onCommit = append(c.onCommit, other.onCommit...)
+	c.onRollback = append(c.onRollback, other.onRollback...)
+	c.onComplete = append(c.onComplete, other.onComplete...)
+}
+
+// run 根据事务结果执行回调,err为空表示已提交
+func (c *txCallbacks) run(err error) {
+	if err == nil {
+		for _, fn := range c.onCommit {
+			fn()
+		}
+	} else {
+		for _, fn := range c.onRollback {
+			fn(err)
+		}
+	}
+	for _, fn := range c.onComplete {
+		fn(err)
+	}
+}
+
+// OnCommit 注册事务提交后执行的回调(如发布领域事件、清理缓存)
+//
+// 回调由 Batchxx/BatchWith 在事务结束后按注册顺序执行；加入已有事务时在最外层事务提交后执行，
+// 保存点回滚时，保存点中注册的提交回调会被丢弃
+func (t *Tx) OnCommit(fn func()) {
+	t.callbacks.onCommit = append(t.callbacks.onCommit, fn)
+}
+
+// OnRollback 注册事务回滚后执行的回调,err为导致回滚的错误
+//
+// 保存点中注册的回调在回滚到保存点后立即执行
+func (t *Tx) OnRollback(fn func(err error)) {
+	t.callbacks.onRollback = append(t.callbacks.onRollback, fn)
+}
+
+// OnComplete 注册事务结束后执行的回调(无论提交或回滚),err为空表示已提交
+func (t *Tx) OnComplete(fn func(err error)) {
+	t.callbacks.onComplete = append(t.callbacks.onComplete, fn)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// committedUser 提交后执行AfterUpdate钩子的实体
+type committedUser struct {
+	updated int
+}
+
+func (u *committedUser) AfterUpdate() error {
+	u.updated++
+	return nil
+}
+
+func (u *committedUser) AfterCommit() bool {
+	return true
+}
+
+func TestTx_Callbacks(t *testing.T) {
+	f := NewFactory("callbacks")
+	//failRelease 为true时释放保存点失败
+	var failRelease bool
+	errRelease := errors.New("release failed")
+	db, err := f.Open(DefaultName, "mysql", testDSN,
+		WithInterceptors(&InterceptorFuncs{BeforeFunc: func(ctx context.Context, stmt *Statement) (context.Context, error) {
+			if failRelease && strings.HasPrefix(stmt.Query, "RELEASE SAVEPOINT") {
+				return ctx, errRelease
+			}
+			return ctx, nil
+		}}, (&txRecorder{}).interceptor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+	errFailed := errors.New("failed")
+
+	t.Run("commit", func(t *testing.T) {
+		var events []string
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			tx.OnCommit(func() { events = append(events, "commit") })
+			tx.OnRollback(func(err error) { events = append(events, "rollback") })
+			tx.OnComplete(func(err error) {
+				assert.NoError(t, err)
+				events = append(events, "complete")
+			})
+			assert.Empty(t, events)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"commit", "complete"}, events)
+	})
+	t.Run("rollback", func(t *testing.T) {
+		var events []string
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			tx.OnCommit(func() { events = append(events, "commit") })
+			tx.OnRollback(func(err error) {
+				assert.ErrorIs(t, err, errFailed)
+				events = append(events, "rollback")
+			})
+			tx.OnComplete(func(err error) { events = append(events, "complete") })
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, []string{"rollback", "complete"}, events)
+	})
+	t.Run("joined", func(t *testing.T) {
+		var events []string
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			err := db.BatchWith(tx.Context(), TxDefinition{}, "", func(inner *Tx) error {
+				inner.OnCommit(func() { events = append(events, "inner") })
+				return nil
+			})
+			assert.Empty(t, events)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"inner"}, events)
+	})
+	t.Run("savepoint", func(t *testing.T) {
+		var events []string
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			_ = db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationNested}, "", func(inner *Tx) error {
+				inner.OnCommit(func() { events = append(events, "discarded") })
+				inner.OnRollback(func(err error) { events = append(events, "rollback to savepoint") })
+				return errFailed
+			})
+			assert.Equal(t, []string{"rollback to savepoint"}, events)
+			return db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationNested}, "", func(inner *Tx) error {
+				inner.OnCommit(func() { events = append(events, "released") })
+				return nil
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"rollback to savepoint", "released"}, events)
+	})
+	t.Run("release failed", func(t *testing.T) {
+		failRelease = true
+		defer func() { failRelease = false }()
+		var events []string
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			releaseErr := db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationNested}, "", func(inner *Tx) error {
+				inner.OnCommit(func() { events = append(events, "discarded") })
+				inner.OnRollback(func(err error) {
+					assert.ErrorIs(t, err, errRelease)
+					events = append(events, "rollback to savepoint")
+				})
+				return nil
+			})
+			assert.ErrorIs(t, releaseErr, errRelease)
+			assert.Equal(t, []string{"rollback to savepoint"}, events)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"rollback to savepoint"}, events)
+	})
+	t.Run("joined rollback only", func(t *testing.T) {
+		var events []string
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			tx.OnRollback(func(err error) {
+				assert.ErrorIs(t, err, ErrRollbackOnly)
+				events = append(events, "rollback")
+			})
+			_ = db.BatchWith(tx.Context(), TxDefinition{}, "", func(inner *Tx) error {
+				inner.OnCommit(func() { events = append(events, "commit") })
+				return errFailed
+			})
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrRollbackOnly)
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, []string{"rollback"}, events)
+	})
+	t.Run("panic", func(t *testing.T) {
+		var events []string
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = db.Batch(context.Background(), nil, func(tx *Tx) error {
+				tx.OnCommit(func() { events = append(events, "commit") })
+				tx.OnRollback(func(err error) {
+					assert.ErrorIs(t, err, ErrTxPanic)
+					assert.ErrorContains(t, err, "boom")
+					events = append(events, "rollback")
+				})
+				tx.OnComplete(func(err error) { events = append(events, "complete") })
+				_ = db.BatchWith(tx.Context(), TxDefinition{Propagation: PropagationNested}, "", func(inner *Tx) error {
+					inner.OnRollback(func(err error) {
+						assert.ErrorIs(t, err, ErrTxPanic)
+						events = append(events, "rollback to savepoint")
+					})
+					panic("boom")
+				})
+				return nil
+			})
+		})
+		assert.Equal(t, []string{"rollback to savepoint", "rollback", "complete"}, events)
+	})
+	t.Run("after commit hook", func(t *testing.T) {
+		user := &committedUser{}
+		err := db.Batch(context.Background(), nil, func(tx *Tx) error {
+			assert.NoError(t, EvalAfterHooksTx(tx, HookUpdate, user))
+			assert.Equal(t, 0, user.updated)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, user.updated)
+
+		_ = db.Batch(context.Background(), nil, func(tx *Tx) error {
+			assert.NoError(t, EvalAfterHookTx(tx, HookUpdate, user))
+			return errFailed
+		})
+		assert.Equal(t, 1, user.updated)
+
+		assert.NoError(t, EvalAfterHookTx(nil, HookUpdate, user))
+		assert.Equal(t, 2, user.updated)
+	})
+}
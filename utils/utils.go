@@ -7,6 +7,7 @@ package utils
 
 import (
 	"reflect"
+	"strings"
 	"unicode"
 )
 
@@ -46,6 +47,11 @@ func ToMap(v any, excludes ...string) map[string]any {
 				for k, v := range ToMap(f.Interface()) {
 					result[k] = v
 				}
+			} else if isEmbedded(ft) {
+				//展开的结构体字段(dbx:"embedded"或dbx:"prefix:xxx"),key为字段路径,例如 Address.Street
+				for k, v := range ToMap(f.Interface()) {
+					result[ft.Name+"."+k] = v
+				}
 			} else {
 				result[ft.Name] = f.Interface()
 			}
@@ -55,6 +61,23 @@ func ToMap(v any, excludes ...string) map[string]any {
 	return result
 }
 
+// isEmbedded 字段是否为展开的结构体字段(参考 meta.MarkEmbedded/meta.MarkPrefix)
+func isEmbedded(f reflect.StructField) bool {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, tag := range strings.Split(f.Tag.Get("dbx"), ",") {
+		if tag == "embedded" || strings.HasPrefix(tag, "prefix:") {
+			return true
+		}
+	}
+	return false
+}
+
 func Search[T any](lst []T, fn func(T) bool) (result []T) {
 	for _, itm := range lst {
 		if fn(itm) {
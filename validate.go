@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrTemplateNotFound mapper绑定的模版不存在
+var ErrTemplateNotFound = errors.New("template not found")
+
+// binding mapper函数与模版的绑定关系(用于启动校验)
+type binding struct {
+	db        *DB
+	method    string
+	templates []string
+	named     bool
+	//result 查询结果类型,命名参数的模版优先使用结果的零值渲染
+	result reflect.Type
+	tx     bool
+}
+
+// bindingKey 绑定关系的键,同一数据库的同一个mapper函数重复绑定时替换原有的绑定
+type bindingKey struct {
+	db     *DB
+	method string
+}
+
+// BoostOption BoostMapper 的可选配置
+type BoostOption func(*boostOptions)
+
+type boostOptions struct {
+	strict   bool
+	validate []ValidateOption
+}
+
+// Strict 严格模式:绑定完成后立即校验mapper的所有模版(参考 Factory.Validate)
+func Strict(opts ...ValidateOption) BoostOption {
+	return func(o *boostOptions) {
+		o.strict = true
+		o.validate = append(o.validate, opts...)
+	}
+}
+
+// ValidateOption 模版校验的可选配置
+type ValidateOption func(*validateOptions)
+
+type validateOptions struct {
+	prepare bool
+}
+
+// WithPrepare 校验时在数据库中预编译(PREPARE)渲染后的SQL,检查语法和表、列是否存在
+func WithPrepare() ValidateOption {
+	return func(o *validateOptions) {
+		o.prepare = true
+	}
+}
+
+// bind 记录mapper的绑定关系(Shutdown 时清空)
+func (m *Factory) bind(bindings ...*binding) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.bindings == nil {
+		m.bindings = map[bindingKey]*binding{}
+	}
+	for _, b := range bindings {
+		m.bindings[bindingKey{db: b.db, method: b.method}] = b
+	}
+}
+
+// Validate 校验所有已绑定mapper的模版:模版必须存在,并且可以使用零值参数渲染;
+// 使用 WithPrepare 时同时在数据库中预编译渲染后的SQL。适合在启动时调用,尽早发现模版错误
+func (m *Factory) Validate(ctx context.Context, opts ...ValidateOption) error {
+	m.lock.RLock()
+	bindings := make([]*binding, 0, len(m.bindings))
+	for _, b := range m.bindings {
+		bindings = append(bindings, b)
+	}
+	m.lock.RUnlock()
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].method < bindings[j].method
+	})
+	return validateBindings(ctx, bindings, opts)
+}
+
+// validateBindings 校验绑定关系,返回所有错误
+func validateBindings(ctx context.Context, bindings []*binding, opts []ValidateOption) error {
+	o := &validateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	var errs []error
+	for _, b := range bindings {
+		if b.tx {
+			continue
+		}
+		if err := b.validate(ctx, o); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.method, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate 校验单个绑定:查找模版、渲染、预编译
+func (b *binding) validate(ctx context.Context, o *validateOptions) error {
+	tpl := ""
+	for _, name := range b.templates {
+		if b.db.template.Lookup(name) != nil {
+			tpl = name
+			break
+		}
+	}
+	if tpl == "" {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, strings.Join(b.templates, ","))
+	}
+	var (
+		query string
+		err   error
+	)
+	for _, arg := range b.zeroArgs() {
+		if query, err = b.db.ParseSQL(tpl, arg); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !o.prepare {
+		return nil
+	}
+	if b.named {
+		stmt, err := b.db.PrepareNamedContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		return stmt.Close()
+	}
+	stmt, err := b.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	return stmt.Close()
+}
+
+// zeroArgs 渲染模版使用的零值参数:位置参数为空切片;命名参数依次尝试结果结构体的零值和空map
+func (b *binding) zeroArgs() []any {
+	if !b.named {
+		return []any{[]any{}}
+	}
+	var args []any
+	if t := b.result; t != nil {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			args = append(args, reflect.New(t).Interface())
+		}
+	}
+	return append(args, map[string]any{})
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2023.
+ * all right reserved by gnodux<gnodux@gmail.com>
+ */
+
+package sqlxx
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+type validMapper struct {
+	GetById    GetFunc[User]         `sql:"examples/get_user_by_id.sql"`
+	ListUserBy NamedSelectFunc[User] `sql:"examples/select_user_where.sql"`
+	CountUser  GetFunc[int]          `sql:"select count(1) from user"`
+	BatchAdd   TxFunc
+}
+
+type missingMapper struct {
+	Add NamedExecFunc `sql:"examples/not_exists.sql"`
+	//没有tag,并且不存在对应名称的模版
+	ListByRole SelectFunc[User]
+}
+
+type renderMapper struct {
+	//位置参数的模版不能引用参数字段
+	Remove ExecFunc `sql:"delete from user where id={{.Id}}"`
+}
+
+type prepareMapper struct {
+	CountMissing GetFunc[int] `sql:"select count(1) from sqlxx_validate_not_exists"`
+}
+
+func TestFactory_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapper  any
+		opts    []ValidateOption
+		wantErr []string
+	}{
+		{name: "valid", mapper: &validMapper{}},
+		{name: "valid with prepare", mapper: &validMapper{}, opts: []ValidateOption{WithPrepare()}},
+		{name: "missing template", mapper: &missingMapper{}, wantErr: []string{
+			"missingMapper.Add: template not found: examples/not_exists.sql",
+			"missingMapper.ListByRole: template not found",
+		}},
+		{name: "render", mapper: &renderMapper{}, wantErr: []string{"renderMapper.Remove:"}},
+		{name: "without prepare", mapper: &prepareMapper{}},
+		{name: "prepare", mapper: &prepareMapper{}, opts: []ValidateOption{WithPrepare()}, wantErr: []string{"prepareMapper.CountMissing:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFactory("validate")
+			f.SetTemplateFS(os.DirFS("./testdata"), "examples/*.sql")
+			_, err := f.Open(DefaultName, "mysql", testDSN)
+			assert.NoError(t, err)
+			defer f.Shutdown()
+			assert.NoError(t, f.BoostMapper(tt.mapper, DefaultName))
+			err = f.Validate(context.Background(), tt.opts...)
+			strictErr := f.BoostMapper(tt.mapper, DefaultName, Strict(tt.opts...))
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				assert.NoError(t, strictErr)
+				return
+			}
+			for _, want := range tt.wantErr {
+				assert.ErrorContains(t, err, want)
+				assert.ErrorContains(t, strictErr, want)
+			}
+		})
+	}
+}
+
+func TestFactory_Bindings(t *testing.T) {
+	f := NewFactory("bindings")
+	f.SetTemplateFS(os.DirFS("./testdata"), "examples/*.sql")
+	_, err := f.Open(DefaultName, "mysql", testDSN)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, f.BoostMapper(&validMapper{}, DefaultName))
+	}
+	//重复绑定同一个mapper时替换原有的绑定
+	assert.Len(t, f.bindings, 4)
+	assert.NoError(t, f.Validate(context.Background()))
+	assert.NoError(t, f.Shutdown())
+	assert.Empty(t, f.bindings)
+}
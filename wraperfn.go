@@ -8,7 +8,6 @@ package sqlxx
 import (
 	"context"
 	"database/sql"
-	"github.com/cookieY/sqlx"
 )
 
 const (
@@ -36,6 +35,38 @@ type NamedExecFunc func(arg any) (sql.Result, error)
 // TxFunc Tx 函数类型, 用于执行事务
 type TxFunc func(func(*Tx) error) error
 
+// SelectContextFunc 使用上下文的 SelectFunc,上下文中存在事务时在事务中执行(参考 WithTx)
+type SelectContextFunc[T any] func(ctx context.Context, args ...any) ([]T, error)
+
+// NamedSelectContextFunc 使用上下文的 NamedSelectFunc
+type NamedSelectContextFunc[T any] func(ctx context.Context, arg any) ([]T, error)
+
+// GetContextFunc 使用上下文的 GetFunc
+type GetContextFunc[T any] func(ctx context.Context, args ...any) (T, error)
+
+// NamedGetContextFunc 使用上下文的 NamedGetFunc
+type NamedGetContextFunc[T any] func(ctx context.Context, arg any) (T, error)
+
+// ExecContextFunc 使用上下文的 ExecFunc
+type ExecContextFunc func(ctx context.Context, args ...any) (sql.Result, error)
+
+// NamedExecContextFunc 使用上下文的 NamedExecFunc
+type NamedExecContextFunc func(ctx context.Context, arg any) (sql.Result, error)
+
+// TxContextFunc 使用上下文的 TxFunc,根据传播行为复用上下文中的事务(参考 DB.BatchWith)
+type TxContextFunc func(ctx context.Context, fn func(*Tx) error) error
+
+// methodContext 调用方上下文中记录mapper方法名称
+func methodContext(ctx context.Context, method string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if method == "" {
+		return ctx
+	}
+	return WithMethod(ctx, method)
+}
+
 // NewSelectFuncWith 创建一个 SelectFunc
 // m: Factory 数据库管理器
 // db: 数据库名称
@@ -59,12 +90,30 @@ func NewSelectFunc[T any](db, tpl string) SelectFunc[T] {
 	return NewSelectFuncWith[T](StdFactory, db, tpl)
 }
 
-// NewTxFuncWith 创建一个 TxFunc
+// NewTxFuncWith 创建一个 TxFunc(使用数据库默认的重试策略,参考 WithRetry)
 // db: 数据库名称
 // tpl: SQL模版或者inline SQL
 func NewTxFuncWith(db *DB, tpl string, opts *sql.TxOptions) TxFunc {
+	return newTxFunc(context.Background(), db, tpl, TxDefinition{Options: opts})
+}
+
+func newTxFunc(ctx context.Context, db *DB, tpl string, def TxDefinition) TxFunc {
 	return func(fn func(tx *Tx) error) error {
-		return db.Batchxx(context.Background(), opts, tpl, fn)
+		return db.BatchWith(ctx, def, tpl, fn)
+	}
+}
+
+// NewTxContextFuncWith 创建一个 TxContextFunc
+// db: 数据库
+// tpl: SQL模版或者inline SQL
+// def: 事务定义(隔离级别、只读、传播行为、重试策略)
+func NewTxContextFuncWith(db *DB, tpl string, def TxDefinition) TxContextFunc {
+	return newTxContextFunc("", db, tpl, def)
+}
+
+func newTxContextFunc(method string, db *DB, tpl string, def TxDefinition) TxContextFunc {
+	return func(ctx context.Context, fn func(tx *Tx) error) error {
+		return db.BatchWith(methodContext(ctx, method), def, tpl, fn)
 	}
 }
 
@@ -105,14 +154,11 @@ func NewNamedGetFuncWith[T any](m *Factory, db, tpl string) NamedGetFunc[T] {
 		if d, err = m.Get(db); err != nil {
 			return
 		}
-		var n *sqlx.NamedStmt
-		if n, err = d.PrepareNamedxx(tpl, arg); err != nil {
+		var query string
+		if query, err = d.ParseSQL(tpl, arg); err != nil {
 			return v, err
 		}
-		defer func(n *sqlx.NamedStmt) {
-			err = n.Close()
-		}(n)
-		err = n.Get(&v, arg)
+		err = d.doNamedGet(context.Background(), nil, &v, tpl, query, arg)
 		return v, err
 	}
 }
@@ -121,8 +167,29 @@ func NewNamedGetFuncWith[T any](m *Factory, db, tpl string) NamedGetFunc[T] {
 // db:*DB 数据库管理器
 // tpl: SQL模版或者inline SQL
 func NewNamedExecFuncWith(db *DB, tpl string) NamedExecFunc {
+	return newNamedExecFunc(context.Background(), db, tpl)
+}
+
+func newNamedExecFunc(ctx context.Context, db *DB, tpl string) NamedExecFunc {
 	return func(arg any) (sql.Result, error) {
-		return db.NamedExecxx(tpl, arg)
+		query, err := db.ParseSQL(tpl, arg)
+		if err != nil {
+			return nil, err
+		}
+		return db.doNamedExec(ctx, nil, tpl, query, arg)
+	}
+}
+
+// NewNamedExecContextFuncWith 创建一个 NamedExecContextFunc
+// db:*DB 数据库
+// tpl: SQL模版或者inline SQL
+func NewNamedExecContextFuncWith(db *DB, tpl string) NamedExecContextFunc {
+	return newNamedExecContextFunc("", db, tpl)
+}
+
+func newNamedExecContextFunc(method string, db *DB, tpl string) NamedExecContextFunc {
+	return func(ctx context.Context, arg any) (sql.Result, error) {
+		return newNamedExecFunc(methodContext(ctx, method), db, tpl)(arg)
 	}
 }
 
@@ -130,7 +197,28 @@ func NewNamedExecFuncWith(db *DB, tpl string) NamedExecFunc {
 // db:*DB 数据库
 // tpl: SQL模版或者inline SQL
 func NewExecFuncWith(db *DB, tpl string) ExecFunc {
+	return newExecFunc(context.Background(), db, tpl)
+}
+
+func newExecFunc(ctx context.Context, db *DB, tpl string) ExecFunc {
 	return func(args ...any) (sql.Result, error) {
-		return db.Execxx(tpl, args...)
+		query, err := db.ParseSQL(tpl, args)
+		if err != nil {
+			return nil, err
+		}
+		return db.doExec(ctx, nil, tpl, query, args)
+	}
+}
+
+// NewExecContextFuncWith 创建一个 ExecContextFunc
+// db:*DB 数据库
+// tpl: SQL模版或者inline SQL
+func NewExecContextFuncWith(db *DB, tpl string) ExecContextFunc {
+	return newExecContextFunc("", db, tpl)
+}
+
+func newExecContextFunc(method string, db *DB, tpl string) ExecContextFunc {
+	return func(ctx context.Context, args ...any) (sql.Result, error) {
+		return newExecFunc(methodContext(ctx, method), db, tpl)(args...)
 	}
 }